@@ -0,0 +1,231 @@
+package agecache
+
+import (
+	"fmt"
+	"time"
+)
+
+// AdmissionPolicy decides whether a new item may displace an existing one
+// once the cache is full
+type AdmissionPolicy int
+
+const (
+	// AlwaysAdmit stores every new item, evicting to make room as needed
+	AlwaysAdmit AdmissionPolicy = iota
+
+	// TinyLFUAdmission estimates how often keys are used, including keys not
+	// currently in the cache, with a count-min sketch guarded by a doorkeeper
+	// bloom filter. Once the cache is full, a new item is only stored if its
+	// key is estimated to be used more frequently than the key that would be
+	// evicted to make room for it. Estimates are halved periodically, so that
+	// the frequencies of past workloads decay.
+	TinyLFUAdmission
+)
+
+// RejectionReason enumerates why an item could not be stored by TrySet.
+type RejectionReason int
+
+const (
+	// AdmissionRejected reports that the admission policy preferred the item
+	// which would have been evicted to make room
+	AdmissionRejected RejectionReason = iota + 1
+
+	// CostExceeded reports that the cost of the item alone exceeds MaxCost
+	CostExceeded
+
+	// Bypassed reports that the key must not be cached, per Config.Bypass
+	Bypassed
+)
+
+func (reason RejectionReason) String() string {
+	switch reason {
+	case AdmissionRejected:
+		return "rejected by admission policy"
+	case CostExceeded:
+		return "cost exceeds max cost"
+	case Bypassed:
+		return "bypassed"
+	default:
+		return "rejected"
+	}
+}
+
+// RejectedError is returned by TrySet when an item could not be stored.
+type RejectedError struct {
+	Reason RejectionReason
+	// Fraction of the capacity in use, or of the MaxCost for CostExceeded
+	Occupancy float64
+	// Advisory delay before retrying, such as until the item which would be
+	// evicted expires. Zero when retrying is not expected to succeed
+	RetryAfter time.Duration
+}
+
+func (err *RejectedError) Error() string {
+	message := fmt.Sprintf("agecache: item %s (%.0f%% occupancy)", err.Reason, err.Occupancy*100)
+	if err.RetryAfter > 0 {
+		message += fmt.Sprintf(", retry after %s", err.RetryAfter)
+	}
+	return message
+}
+
+const (
+	// Number of rows of counters in the count-min sketch
+	sketchDepth = 4
+	// Number of counters per row, and of doorkeeper bits per recorded key of
+	// a sample, per unit of capacity
+	sketchWidth = 4
+	doorWidth   = 4
+	// Maximum value of a sketch counter
+	sketchMax = 15
+	// Number of recorded keys per unit of capacity after which estimates
+	// are halved
+	sketchSample = 10
+)
+
+// tinyLFU implements the TinyLFUAdmission policy. The first use of a key
+// within a sample only sets its doorkeeper bits, so that keys used once never
+// reach the sketch.
+type tinyLFU[K comparable] struct {
+	rows      [sketchDepth][]uint8
+	door      []uint64
+	mask      uint64 // Masks indices of the rows
+	doorMask  uint64 // Masks indices of the doorkeeper bits
+	additions int
+	sample    int
+}
+
+func newAdmission[K comparable](policy AdmissionPolicy, capacity int) *tinyLFU[K] {
+	if policy != TinyLFUAdmission {
+		return nil
+	}
+
+	width := pow2(sketchWidth * capacity)
+	bits := pow2(doorWidth * sketchSample * capacity)
+
+	filter := &tinyLFU[K]{
+		door:     make([]uint64, (bits+63)/64),
+		mask:     uint64(width - 1),
+		doorMask: uint64(bits - 1),
+		sample:   sketchSample * capacity,
+	}
+	for i := range filter.rows {
+		filter.rows[i] = make([]uint8, width)
+	}
+	return filter
+}
+
+// record notes a use of key.
+func (filter *tinyLFU[K]) record(key K) {
+	hash := hashKey(key)
+
+	if !filter.admitted(hash) {
+		filter.admit(hash)
+	} else {
+		for i := range filter.rows {
+			counter := &filter.rows[i][sketchIndex(hash, i, filter.mask)]
+			if *counter < sketchMax {
+				*counter++
+			}
+		}
+	}
+
+	filter.additions++
+	if filter.additions >= filter.sample {
+		filter.reset()
+	}
+}
+
+// save copies the counters of the sketch.
+func (filter *tinyLFU[K]) save() *saveSketch {
+	sketch := &saveSketch{
+		Door:      append([]uint64(nil), filter.door...),
+		Additions: filter.additions,
+	}
+	for i := range filter.rows {
+		sketch.Rows[i] = append([]uint8(nil), filter.rows[i]...)
+	}
+	return sketch
+}
+
+// load replaces the counters of the sketch with saved counters, returning
+// false if they were saved by a sketch of other dimensions.
+func (filter *tinyLFU[K]) load(sketch *saveSketch) bool {
+	if len(sketch.Door) != len(filter.door) {
+		return false
+	}
+	for i := range filter.rows {
+		if len(sketch.Rows[i]) != len(filter.rows[i]) {
+			return false
+		}
+	}
+
+	for i := range filter.rows {
+		copy(filter.rows[i], sketch.Rows[i])
+	}
+	copy(filter.door, sketch.Door)
+	filter.additions = sketch.Additions
+	return true
+}
+
+// allow returns whether key should be stored in place of victim.
+func (filter *tinyLFU[K]) allow(key, victim K) bool {
+	return filter.estimate(hashKey(key)) > filter.estimate(hashKey(victim))
+}
+
+func (filter *tinyLFU[K]) estimate(hash uint64) int {
+	estimate := sketchMax
+	for i := range filter.rows {
+		if counter := int(filter.rows[i][sketchIndex(hash, i, filter.mask)]); counter < estimate {
+			estimate = counter
+		}
+	}
+	if filter.admitted(hash) {
+		estimate++
+	}
+	return estimate
+}
+
+// reset halves every counter and clears the doorkeeper.
+func (filter *tinyLFU[K]) reset() {
+	for i := range filter.rows {
+		for j := range filter.rows[i] {
+			filter.rows[i][j] >>= 1
+		}
+	}
+	for i := range filter.door {
+		filter.door[i] = 0
+	}
+	filter.additions = 0
+}
+
+func (filter *tinyLFU[K]) admitted(hash uint64) bool {
+	for i := 0; i < 2; i++ {
+		bit := sketchIndex(hash, sketchDepth+i, filter.doorMask)
+		if filter.door[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (filter *tinyLFU[K]) admit(hash uint64) {
+	for i := 0; i < 2; i++ {
+		bit := sketchIndex(hash, sketchDepth+i, filter.doorMask)
+		filter.door[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// sketchIndex returns the ith index of hash, deriving independent indices
+// from the two halves of the hash.
+func sketchIndex(hash uint64, i int, mask uint64) uint64 {
+	return (hash + uint64(i)*(hash>>32|1)) & mask
+}
+
+// pow2 returns the smallest power of two of at least n, and at least 64.
+func pow2(n int) int {
+	p := 64
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
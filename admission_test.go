@@ -0,0 +1,110 @@
+package agecache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTinyLFUAdmission(t *testing.T) {
+	cache := New(Config[int, int]{Capacity: 10, Admission: TinyLFUAdmission})
+
+	for i := 0; i < 10; i++ {
+		cache.Set(i, i)
+		cache.Get(i)
+		cache.Get(i)
+	}
+
+	// Keys used once cannot displace frequently used keys
+	for i := 10; i < 100; i++ {
+		assert.False(t, cache.Set(i, i))
+	}
+	assert.Equal(t, 10, cache.Len())
+	for i := 0; i < 10; i++ {
+		assert.True(t, cache.Has(i))
+	}
+
+	// A key requested repeatedly is eventually admitted
+	for i := 0; i < 5; i++ {
+		cache.Get(100)
+	}
+	assert.True(t, cache.Set(100, 100))
+	assert.True(t, cache.Has(100))
+	assert.False(t, cache.Has(0))
+}
+
+func TestTinyLFUReset(t *testing.T) {
+	filter := newAdmission[string](TinyLFUAdmission, 1)
+	assert.Nil(t, newAdmission[string](AlwaysAdmit, 1))
+
+	for i := 0; i < 9; i++ {
+		filter.record("a")
+	}
+	assert.Equal(t, 9, filter.estimate(hashKey("a")))
+
+	// The tenth use reaches the sample size, halving the estimates
+	filter.record("a")
+	assert.Equal(t, 0, filter.additions)
+	assert.Equal(t, 4, filter.estimate(hashKey("a")))
+	assert.Equal(t, 0, filter.estimate(hashKey("b")))
+
+	assert.True(t, filter.allow("a", "b"))
+	assert.False(t, filter.allow("b", "a"))
+}
+
+func TestTrySetRejected(t *testing.T) {
+	cache := New(Config[int, int]{
+		Capacity:  2,
+		MaxAge:    time.Minute,
+		Admission: TinyLFUAdmission,
+	})
+
+	assert.NoError(t, cache.TrySet(1, 1))
+	assert.NoError(t, cache.TrySet(2, 2))
+	cache.Get(1)
+	cache.Get(2)
+
+	err := cache.TrySet(3, 3)
+	var rejected *RejectedError
+	assert.True(t, errors.As(err, &rejected))
+	assert.Equal(t, AdmissionRejected, rejected.Reason)
+	assert.Equal(t, 1.0, rejected.Occupancy)
+	assert.True(t, rejected.RetryAfter > 59*time.Second)
+	assert.Contains(t, err.Error(), "rejected by admission policy (100% occupancy), retry after")
+
+	// Replacing an item is never rejected
+	assert.NoError(t, cache.TrySet(1, 4))
+}
+
+func TestTrySetCostExceeded(t *testing.T) {
+	var callbacks []string
+	cache := New(Config[string, string]{
+		Capacity: 10,
+		MaxCost:  4,
+		Cost: func(key string, value string) int64 {
+			return int64(len(value))
+		},
+		OnSet: func(key string, value string, replaced bool) {
+			callbacks = append(callbacks, "set "+key)
+		},
+		OnRemoval: func(key string, value string, reason RemovalReason) {
+			callbacks = append(callbacks, "removal "+key)
+		},
+	})
+
+	assert.NoError(t, cache.TrySet("a", "aa"))
+
+	err := cache.TrySet("b", "bbbbb")
+	assert.Equal(t, &RejectedError{Reason: CostExceeded, Occupancy: 0.5}, err)
+	assert.Equal(t, "agecache: item cost exceeds max cost (50% occupancy)", err.Error())
+
+	// Rejected before being stored, leaving the cache untouched
+	assert.Error(t, cache.TrySet("a", "aaaaa"))
+	value, ok := cache.Peek("a")
+	assert.True(t, ok)
+	assert.Equal(t, "aa", value)
+	assert.Equal(t, []string{"set a"}, callbacks)
+	assert.Zero(t, cache.Stats().Evictions)
+}
@@ -0,0 +1,82 @@
+package agecache
+
+// SetWithAlt updates a key:value pair in the cache as with Set, registering alt
+// as an alternate key of the item, such as a slug alongside a numeric ID. The
+// item may then be retrieved with GetByAlt, and its alternate key leaves the
+// cache with it. The alternate key is kept by subsequent Sets of the item, and
+// replaced by SetWithAlt. An alternate key previously registered for another
+// item is moved to this one.
+func (cache *Cache[K, V]) SetWithAlt(key, alt K, value V) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	evict := cache.set(key, value, cache.getTimestamp(), 0)
+	if entry, ok := cache.items[key]; ok {
+		cache.alias(entry, alt)
+	}
+
+	return evict
+}
+
+// GetByAlt returns the value stored at the item whose alternate key is alt, as
+// with Get. The boolean value reports whether the value was found.
+func (cache *Cache[K, V]) GetByAlt(alt K) (value V, found bool) {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	key, ok := cache.alts[alt]
+	if !ok {
+		cache.gets++
+		cache.misses++
+		cache.emit(EventMiss, alt, value)
+		return value, false
+	}
+
+	return cache.get(key)
+}
+
+// RemoveByAlt removes the item whose alternate key is alt, as with Remove,
+// returning a bool indicating whether it existed.
+func (cache *Cache[K, V]) RemoveByAlt(alt K) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	key, ok := cache.alts[alt]
+	if !ok {
+		return false
+	}
+
+	return cache.remove(key)
+}
+
+// alias registers alt as the alternate key of the entry, replacing its
+// previous alternate key and taking alt from any other entry.
+func (cache *Cache[K, V]) alias(entry *cacheEntry[K, V], alt K) {
+	cache.unalias(entry)
+	if key, ok := cache.alts[alt]; ok {
+		if other, ok := cache.items[key]; ok {
+			cache.unalias(other)
+		}
+	}
+
+	if cache.alts == nil {
+		cache.alts = make(map[K]K)
+	}
+	cache.alts[alt] = entry.key
+	entry.alt = alt
+	entry.hasAlt = true
+}
+
+// unalias drops the alternate key of the entry, if any.
+func (cache *Cache[K, V]) unalias(entry *cacheEntry[K, V]) {
+	if !entry.hasAlt {
+		return
+	}
+
+	if cache.alts[entry.alt] == entry.key {
+		delete(cache.alts, entry.alt)
+	}
+	var zero K
+	entry.alt = zero
+	entry.hasAlt = false
+}
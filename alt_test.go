@@ -0,0 +1,62 @@
+package agecache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWithAlt(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 2})
+
+	cache.SetWithAlt("42", "answer", 1)
+	val, ok := cache.GetByAlt("answer")
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+
+	// Sets of the item keep its alternate key
+	cache.Set("42", 2)
+	val, ok = cache.GetByAlt("answer")
+	assert.True(t, ok)
+	assert.Equal(t, 2, val)
+
+	_, ok = cache.GetByAlt("missing")
+	assert.False(t, ok)
+	stats := cache.Stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+
+	// Both keys leave the cache together
+	assert.True(t, cache.Remove("42"))
+	_, ok = cache.GetByAlt("answer")
+	assert.False(t, ok)
+
+	cache.SetWithAlt("42", "answer", 3)
+	assert.True(t, cache.RemoveByAlt("answer"))
+	assert.False(t, cache.Has("42"))
+	assert.False(t, cache.RemoveByAlt("answer"))
+	assert.Empty(t, cache.alts)
+}
+
+func TestSetWithAltMoves(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 2})
+
+	cache.SetWithAlt("1", "a", 1)
+	cache.SetWithAlt("1", "b", 1)
+	_, ok := cache.GetByAlt("a")
+	assert.False(t, ok, "alternate keys are replaced")
+
+	cache.SetWithAlt("2", "b", 2)
+	val, _ := cache.GetByAlt("b")
+	assert.Equal(t, 2, val, "alternate keys move between items")
+
+	// Evicting the item which lost its alternate key leaves it in place
+	cache.Set("3", 3)
+	assert.False(t, cache.Has("1"))
+	val, _ = cache.GetByAlt("b")
+	assert.Equal(t, 2, val)
+
+	cache.Clear()
+	_, ok = cache.GetByAlt("b")
+	assert.False(t, ok)
+}
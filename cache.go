@@ -3,6 +3,7 @@ package agecache
 
 import (
 	"container/list"
+	"context"
 	"errors"
 	"math/rand"
 	"sync"
@@ -18,34 +19,84 @@ import (
 // 		stats.WithPrefix("mycache").Observe(s)
 //
 type Stats struct {
-	Capacity  int64 `metric:"capacity" type:"gauge"`    // Gauge, maximum capacity for the cache
-	Count     int64 `metric:"count" type:"gauge"`       // Gauge, number of items in the cache
-	Sets      int64 `metric:"sets" type:"counter"`      // Counter, number of sets
-	Gets      int64 `metric:"gets" type:"counter"`      // Counter, number of gets
-	Hits      int64 `metric:"hits" type:"counter"`      // Counter, number of cache hits from Get operations
-	Misses    int64 `metric:"misses" type:"counter"`    // Counter, number of cache misses from Get operations
-	Evictions int64 `metric:"evictions" type:"counter"` // Counter, number of evictions
+	Capacity    int64 `metric:"capacity" type:"gauge" json:"capacity"`         // Gauge, maximum capacity for the cache
+	Count       int64 `metric:"count" type:"gauge" json:"count"`               // Gauge, number of items in the cache
+	Sets        int64 `metric:"sets" type:"counter" json:"sets"`               // Counter, number of sets
+	Gets        int64 `metric:"gets" type:"counter" json:"gets"`               // Counter, number of gets
+	Hits        int64 `metric:"hits" type:"counter" json:"hits"`               // Counter, number of cache hits from Get operations
+	Misses      int64 `metric:"misses" type:"counter" json:"misses"`           // Counter, number of cache misses from Get operations
+	Evictions   int64 `metric:"evictions" type:"counter" json:"evictions"`     // Counter, number of evictions
+	Cost        int64 `metric:"cost" type:"gauge" json:"cost"`                 // Gauge, total cost of the items in the cache
+	Corruptions int64 `metric:"corruptions" type:"counter" json:"corruptions"` // Counter, number of items failing checksum validation
+	Expirations int64 `metric:"expirations" type:"counter" json:"expirations"` // Counter, number of items removed for having expired
+	Thrashes    int64 `metric:"thrashes" type:"counter" json:"thrashes"`       // Counter, number of items evicted or expired unread within the ThrashWindow
+	Cardinality int64 `metric:"cardinality" type:"gauge" json:"cardinality"`   // Gauge, estimated number of distinct keys requested within the CardinalityWindow
 }
 
 // Delta returns a Stats object such that all counters are calculated as the
 // difference since the previous.
 func (stats Stats) Delta(previous Stats) Stats {
 	return Stats{
-		Capacity:  stats.Capacity,
-		Count:     stats.Count,
-		Sets:      stats.Sets - previous.Sets,
-		Gets:      stats.Gets - previous.Gets,
-		Hits:      stats.Hits - previous.Hits,
-		Misses:    stats.Misses - previous.Misses,
-		Evictions: stats.Evictions - previous.Evictions,
+		Capacity:    stats.Capacity,
+		Count:       stats.Count,
+		Sets:        stats.Sets - previous.Sets,
+		Gets:        stats.Gets - previous.Gets,
+		Hits:        stats.Hits - previous.Hits,
+		Misses:      stats.Misses - previous.Misses,
+		Evictions:   stats.Evictions - previous.Evictions,
+		Cost:        stats.Cost,
+		Corruptions: stats.Corruptions - previous.Corruptions,
+		Expirations: stats.Expirations - previous.Expirations,
+		Thrashes:    stats.Thrashes - previous.Thrashes,
+		Cardinality: stats.Cardinality,
 	}
 }
 
-// RandGenerator represents a random number generator.
+// HitRatio returns the fraction of Get operations which were hits, or zero if
+// there were none.
+func (stats Stats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// Utilization returns the fraction of the cache's capacity in use.
+func (stats Stats) Utilization() float64 {
+	if stats.Capacity == 0 {
+		return 0
+	}
+	return float64(stats.Count) / float64(stats.Capacity)
+}
+
+// Returned to callers waiting on a loader which panicked
+var errLoaderPanicked = errors.New("loader panicked")
+
+// RandGenerator represents a random number generator. A generator supplied
+// with Config.Rand is invoked with the cache's lock held, and must be safe for
+// concurrent use if it is shared between caches.
 type RandGenerator interface {
 	Int63n(n int64) int64
 }
 
+// lockedRand is the default RandGenerator, seeded from the time and safe for
+// concurrent use.
+type lockedRand struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	return &lockedRand{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (r *lockedRand) Int63n(n int64) int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.rand.Int63n(n)
+}
+
 // ExpirationType enumerates expiration types.
 type ExpirationType int
 
@@ -60,10 +111,88 @@ const (
 	ActiveExpiration
 )
 
-// Config configures the cache.
+// ExpirationPolicy enumerates how the age of an item is measured.
+type ExpirationPolicy int
+
+const (
+	// AbsoluteExpiration measures the age of an item from when it was
+	// last Set.
+	AbsoluteExpiration ExpirationPolicy = iota
+
+	// SlidingExpiration measures the age of an item from when it was
+	// last Set or successfully retrieved with `.Get()`, so that frequently
+	// read items stay alive.
+	SlidingExpiration
+)
+
+// RemovalReason enumerates why an item left the cache, as reported to
+// OnRemoval.
+type RemovalReason int
+
+const (
+	// Evicted reports an item evicted by the eviction policy to make room
+	Evicted RemovalReason = iota
+
+	// Expired reports an item which exceeded its max age
+	Expired
+
+	// Replaced reports the previous value of an item which was Set again
+	Replaced
+
+	// Removed reports an item explicitly removed with Remove
+	Removed
+
+	// Cleared reports an item removed by Clear
+	Cleared
+
+	// Invalidated reports an item removed because an item it depends on, per
+	// SetWithDeps, left the cache or was replaced, or because its tag was
+	// invalidated with InvalidateTag
+	Invalidated
+)
+
+func (reason RemovalReason) String() string {
+	switch reason {
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Replaced:
+		return "replaced"
+	case Removed:
+		return "removed"
+	case Cleared:
+		return "cleared"
+	case Invalidated:
+		return "invalidated"
+	default:
+		return "unknown"
+	}
+}
+
+// Config configures the cache. Callbacks are invoked once the cache's lock
+// has been released, and so may safely call back into the cache, e.g. to
+// re-insert a value.
 type Config[K comparable, V any] struct {
 	// Maximum number of items in the cache
 	Capacity int
+	// Optional maximum total cost of the items in the cache, as computed by
+	// Cost. The oldest items are evicted until the total cost fits, so an
+	// item which alone costs more than MaxCost is evicted immediately. If
+	// zero, or if Cost is nil, only Capacity is enforced.
+	MaxCost int64
+	// Optional function computing the cost of an item, e.g. its size in
+	// bytes. Invoked whenever an item is stored.
+	Cost func(key K, value V) int64
+	// Optional approximate limit, in bytes, of the memory used by the items
+	// in the cache, as estimated by the Sizer of their values plus their keys
+	// and bookkeeping. The oldest items are evicted until the estimate fits,
+	// as with a MaxCost, and Stats.Cost reports the estimate. May not be
+	// combined with MaxCost or Cost
+	MaxMemory int64
+	// For MaxMemory, the estimator of the size of values, such as a
+	// StringSizer or BytesSizer. Defaults to a ReflectSizer
+	Sizer Sizer[V]
 	// Optional max duration before an item expires. Must be greater than or
 	// equal to MinAge. If zero, expiration is disabled.
 	MaxAge time.Duration
@@ -73,65 +202,483 @@ type Config[K comparable, V any] struct {
 	MinAge time.Duration
 	// Type of key expiration: Passive or Active
 	ExpirationType ExpirationType
+	// How the age of an item is measured: Absolute or Sliding
+	ExpirationPolicy ExpirationPolicy
 	// For active expiration, how often to iterate over the keyspace. Defaults
 	// to the MaxAge
 	ExpirationInterval time.Duration
-	// Optional callback invoked when an item is evicted due to the LRU policy
+	// How expiring items are ordered for removal: HeapQueue or
+	// TimingWheelQueue. Defaults to HeapQueue
+	ExpirationQueue ExpirationQueue
+	// For TimingWheelQueue, the width of the slots of the wheel. Defaults to
+	// 100ms
+	WheelTick time.Duration
+	// Policy selecting which item to evict once the cache is full. Defaults
+	// to LRUEviction
+	Policy EvictionPolicy
+	// Optional function constructing a custom eviction policy, overriding
+	// Policy. It is invoked once by New, and once per shard by NewSharded
+	CustomPolicy func() Policy[K]
+	// For SLRUEviction, the fraction of the capacity reserved for the
+	// protected segment. Must be between 0 and 1. Defaults to 0.8
+	ProtectedRatio float64
+	// Policy deciding whether new items are stored once the cache is full.
+	// Defaults to AlwaysAdmit
+	Admission AdmissionPolicy
+	// Optional callback invoked when an item is evicted due to the eviction
+	// policy
 	OnEviction func(key K, value V)
 	// Optional callback invoked when an item expired
 	OnExpiration func(key K, value V)
+	// Optional callback invoked when an item is explicitly removed via Remove
+	OnRemove func(key K, value V)
+	// Optional callback invoked whenever an item leaves the cache, including
+	// when its value is replaced, with the reason it was removed
+	OnRemoval func(key K, value V, reason RemovalReason)
+	// Optional callback invoked when an item is stored, reporting whether it
+	// replaced a previous value
+	OnSet func(key K, value V, replaced bool)
+	// Optional callback invoked when Get finds an unexpired item
+	OnHit func(key K, value V)
+	// Optional callback invoked with groups of evicted items, in addition to
+	// OnEviction. Evictions are coalesced by EvictionGroup, keeping the last
+	// value of keys evicted more than once, and delivered once per operation
+	// or EvictionWindow
+	OnEvictionGroup func(group string, evicted map[K]V)
+	// Optional function mapping keys to their group for OnEvictionGroup, such
+	// as a prefix or tenant. Defaults to a single group named ""
+	EvictionGroup func(key K) string
+	// How long to coalesce evictions for OnEvictionGroup. Defaults to zero,
+	// coalescing the evictions of each operation, such as a Resize
+	EvictionWindow time.Duration
+	// For SerializableCache, whether snapshots store a checksum of each value,
+	// validated on Restore. Corrupted items are skipped and counted in
+	// Stats.Corruptions
+	Checksums bool
+	// For SerializableCache, stages applied in order to each encoded value
+	// written to a snapshot, such as a GzipTransformer, and in reverse order
+	// on Restore
+	Transformers []Transformer
+	// Optional fraction of stores, from 0 to 1, which record the call site of
+	// the Set as the origin of the item, listed by Export and Report. Defaults
+	// to zero, recording only the origins given to SetWithOrigin
+	OriginSampleRate float64
+	// Optional age below which an item evicted or expired without having been
+	// hit is counted in Stats.Thrashes, as its store was wasted. Defaults to
+	// zero, disabling thrash detection
+	ThrashWindow time.Duration
+	// For ThrashWindow, the number of consecutive wasted stores of a key after
+	// which OnThrash is invoked. Defaults to 3
+	ThrashThreshold int
+	// Optional callback invoked with keys whose stores are repeatedly wasted,
+	// and which may be better left uncached
+	OnThrash func(key K, value V)
+	// Optional predicate reporting keys which must not be cached. Storing a
+	// bypassed key has no effect, and Get reports it as a miss, so that
+	// GetOrLoad always invokes the loader. It is invoked with the cache's
+	// lock held, and so must not call back into the cache. Items stored
+	// before their key was bypassed are left in place
+	Bypass func(key K) bool
+	// Whether to index string keys as paths separated by the PathSeparator,
+	// so that InvalidateSubtree takes time proportional to the number of items
+	// it removes, and RemovePrefix to the number of items sharing the
+	// complete segments of its prefix. Panics if K is not a string type
+	Hierarchical bool
+	// Separator of the segments of keys, for Hierarchical and
+	// InvalidateSubtree, such as ":" for keys like "tenant:resource:id".
+	// Defaults to "/"
+	PathSeparator string
+	// Optional path of a file which New loads the cache from, if it exists,
+	// and which the cache is saved to with SaveTo every PersistInterval and on
+	// Close. The file is replaced atomically. For NewSharded and NewSegmented,
+	// each shard or segment uses its own file, suffixed with its index or name
+	PersistPath string
+	// For PersistPath, how often to save the cache. Defaults to zero, saving
+	// only on Close
+	PersistInterval time.Duration
+	// Optional callback invoked with the errors of loading the PersistPath, and
+	// of saving it every PersistInterval
+	OnPersistError func(err error)
+	// Optional function returning items which New stores with SetAll, in one
+	// locked pass, to warm the cache. Preloaded items take precedence over
+	// those loaded from the PersistPath
+	Preload func() map[K]V
+	// Optional maximum by which the jitter band of items stored without a TTL
+	// may be widened below MinAge, when too many of them are scheduled to
+	// expire within the same second. Such items may then live for as little as
+	// MinAge minus AdaptiveJitter, which must be less than MinAge, or than
+	// MaxAge without a MinAge. Defaults to zero, disabling adaptive jitter
+	AdaptiveJitter time.Duration
+	// For AdaptiveJitter, the number of items scheduled to expire within the
+	// same second above which the jitter band is widened. Defaults to 100
+	JitterThreshold int
+	// Optional source of the time, used to timestamp and expire items, to
+	// tick active expiration, PersistInterval and stats reporters, and to
+	// time the EvictionWindow. Defaults to the system clock. See ManualClock
+	Clock Clock
+	// Optional source of the random jitter and sampling of the cache, such as
+	// a rand.Rand with a fixed seed for deterministic tests and simulations.
+	// Defaults to a generator seeded from the time
+	Rand RandGenerator
+	// Optional duration past their max age for which expired items are kept,
+	// so that GetStale may serve them while Revalidate replaces them in the
+	// background. Get reports such items as misses, without deleting them.
+	// Defaults to zero, deleting items once they expire
+	StaleWhileRevalidate time.Duration
+	// Optional age after which a hit on an item which has not yet expired
+	// passes its key to Revalidate in the background, so that hot items are
+	// replaced before they expire rather than missed. Defaults to zero,
+	// disabling refresh-ahead
+	RefreshAfter time.Duration
+	// For StaleWhileRevalidate and RefreshAfter, the loader invoked in the
+	// background with keys whose items GetStale served stale, or which were
+	// hit past their RefreshAfter. Its value is stored if it returns a nil
+	// error. Calls for the same key are shared with those of GetOrLoad. Their
+	// context is cancelled once the cache is closed
+	Revalidate func(ctx context.Context, key K) (V, error)
+	// Optional callback invoked with the key and error of each call of
+	// Revalidate which failed, other than by the cache being closed
+	OnRevalidateError func(key K, err error)
+	// Optional duration after their insertion during which items are passed
+	// over when selecting an item to evict, so that a cache running at
+	// capacity does not evict the items it has just stored to make room for
+	// the next. If every item was inserted within the window, the oldest is
+	// evicted regardless. Defaults to zero
+	InsertionWindow time.Duration
+	// Optional duration for which GetOrLoad caches the errors returned by its
+	// loader, such as one reporting that the key does not exist, returning
+	// them to later calls for the key without invoking the loader. Errors of
+	// the caller's ctx are not cached, and storing an item at the key forgets
+	// its error. At most Capacity errors are cached. Defaults to zero
+	NegativeTTL time.Duration
+	// Optional duration over which Stats.Cardinality estimates the number of
+	// distinct keys requested with Get, whether or not they were found, with a
+	// HyperLogLog of 4KiB. A cardinality well above the Capacity suggests that
+	// a larger cache could improve the hit ratio, and one below it that misses
+	// are due to expiration. Covers the requests of the last one to two
+	// windows. Defaults to zero, disabling the estimate
+	CardinalityWindow time.Duration
+	// Optional bounds within which the capacity is adjusted every
+	// CapacityInterval, starting from the Capacity. The capacity grows by a
+	// tenth while items are evicted and requests miss, and shrinks by a tenth
+	// while as much of it is left unused, never evicting to do so. Enabled by
+	// a positive MaxCapacity, in which case MinCapacity defaults to 1. Resize
+	// lowers the upper bound to the capacity it sets. For NewSharded, the
+	// bounds are split across shards as with the Capacity
+	MinCapacity int
+	MaxCapacity int
+	// For a MaxCapacity, how often the capacity is adjusted. Defaults to one
+	// minute
+	CapacityInterval time.Duration
+	// Whether to record when each item was created, last updated and last
+	// accessed, as reported by the Times of Entry, EntryInfo and the eviction
+	// and expiration Events of Subscribe. Costs 72 bytes per item. Defaults to
+	// false
+	TrackTimes bool
+	// Optional number of goroutines invoking callbacks asynchronously, so that
+	// slow callbacks do not delay the operation which triggered them.
+	// Callbacks may then run concurrently, and out of order. Defaults to zero,
+	// invoking callbacks in the goroutine of the operation
+	CallbackWorkers int
+	// For CallbackWorkers, the maximum number of operations whose callbacks
+	// are queued. Once full, callbacks are invoked in the goroutine of the
+	// operation. Defaults to 1024
+	CallbackQueue int
+	// Optional sink to which the Stats are reported every MetricsInterval,
+	// and once more when the cache is closed, along with the durations of
+	// loads. See MetricsSink
+	Metrics MetricsSink
+	// For Metrics, how often the Stats are reported. Defaults to 10 seconds
+	MetricsInterval time.Duration
+
+	// Set by WithJitter, from which NewCache derives the MinAge once every
+	// option is applied
+	jitter time.Duration
+}
+
+// Entry is a copy of an item in the cache, as returned by Export.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+	// When the item was last Set, shifted back by any jitter applied
+	Timestamp time.Time
+	// The ttl the item was stored with via SetWithTTL, or zero if it
+	// expires according to the cache's MaxAge
+	TTL time.Duration
+	// Sequence number assigned when the item was inserted, increasing with
+	// each insertion. Breaks ties between items with equal timestamps.
+	Seq uint64
+	// Where the item was last Set, if recorded. See Config.OriginSampleRate
+	Origin string
+	// Remaining lifetime of the item when it was copied, or zero if it does
+	// not expire or has expired
+	Remaining time.Duration
+	// Number of Get hits on the item since it was last Set
+	Hits int64
+	// When the item was created, updated and accessed, per Config.TrackTimes
+	Times EntryTimes
 }
 
-// Entry pointed to by each list.Element
+// EntryTimes records when an item was created, last updated and last
+// accessed, when tracked per Config.TrackTimes. Each is zero if untracked.
+type EntryTimes struct {
+	// When the item was inserted, by the first Set since it was last absent
+	Created time.Time
+	// When the item was last Set
+	Updated time.Time
+	// When the item was last found by Get or Touch, or zero if it was not
+	// since it was created
+	Accessed time.Time
+}
+
+// EntryInfo describes the age of an item in the cache, as returned by
+// OrderedEntriesInfo.
+type EntryInfo[K comparable] struct {
+	Key K
+	// Time since the item was last Set, including any jitter applied
+	Age time.Duration
+	// Remaining lifetime of the item, or zero if it does not expire or has
+	// expired
+	Remaining time.Duration
+	// When the item was created, updated and accessed, per Config.TrackTimes
+	Times EntryTimes
+}
+
+// ImportOptions configures how ImportFrom translates items between caches.
+type ImportOptions struct {
+	// Store imported items with this cache's MaxAge and jitter, starting from
+	// the time of import, rather than preserving their remaining lifetime
+	ResetAge bool
+	// Keep items already present in this cache rather than overwriting them
+	SkipExisting bool
+}
+
+// Entry stored for each item in the cache. The value is held inline rather
+// than boxed in an interface, so small values such as ints and small structs
+// need no allocation of their own.
 type cacheEntry[K comparable, V any] struct {
 	key       K
 	value     V
 	timestamp time.Time
+	ttl       time.Duration // Overrides maxAge when positive
+	seq       uint64        // Insertion sequence number
+	cost      int64
+	cohort    Cohort // Zero unless last stored by SetAll
+	hits      int64  // Number of hits since last stored
+	origin    string // Empty unless recorded when last stored
+	deps      []K    // Keys the item depends on, per SetWithDeps
+	alt       K      // Alternate key, per SetWithAlt
+	hasAlt    bool
+	tags      []string    // Per SetWithTags
+	inserted  time.Time   // Zero unless using an InsertionWindow
+	times     *EntryTimes // Nil unless TrackTimes
+
+	// Bookkeeping of the expiry index
+	deadline time.Time
+	expiry   int // Index in the HeapQueue, or -1 if not queued
+	timer    *list.Element
+	slot     *list.List // Slot of the TimingWheelQueue holding timer
+
+	// Bookkeeping of the eviction policy
+	element *list.Element
+	node    *list.Element
+	segment uint8
+}
+
+// Maximum number of items expected per scan bucket
+const scanBucketSize = 64
+
+// Cursor is a position within a Scan of the keys in the cache.
+type Cursor uint64
+
+// Cohort identifies the items inserted by a single call to SetAll.
+type Cohort uint64
+
+// Callback invocation queued while the mutex is held
+type notification[K comparable, V any] struct {
+	callback func(key K, value V)
+	removal  func(key K, value V, reason RemovalReason)
+	hook     func(key K, value V, reason RemovalReason)
+	set      func(key K, value V, replaced bool)
+	key      K
+	value    V
+	reason   RemovalReason
+	replaced bool
+
+	// Listeners registered at the time of an eviction or expiration
+	listeners []*Listener[K, V]
+
+	// Event queued for the subscribers at the time of the operation
+	subscribers []*subscriber[K, V]
+	event       EventType
+	at          time.Time
+	times       EntryTimes
 }
 
-// Cache implements a thread-safe fixed-capacity LRU cache.
+// In-flight call to a loader passed to GetOrLoad
+type load[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Cache implements a thread-safe fixed-capacity cache, evicting items
+// according to its EvictionPolicy, LRU by default.
 type Cache[K comparable, V any] struct {
 	// Fields defined by configuration
 	capacity           int
+	maxCost            int64
+	cost               func(key K, value V) int64
 	minAge             time.Duration
 	maxAge             time.Duration
 	expirationType     ExpirationType
+	expirationPolicy   ExpirationPolicy
 	expirationInterval time.Duration
+	ageScale           float64
 	onEviction         func(key K, value V)
 	onExpiration       func(key K, value V)
+	onRemove           func(key K, value V)
+	onRemoval          func(key K, value V, reason RemovalReason)
+	removalHook        func(key K, value V, reason RemovalReason) // Invoked after onRemoval, which cannot replace it
+	onSet              func(key K, value V, replaced bool)
+	onHit              func(key K, value V)
+	checksums          bool
+	transforms         *pipeline // Guarded by mutex, unlike the stages
+	originSampleRate   float64
+	thrashWindow       time.Duration
+	thrashThreshold    int
+	thrashing          map[K]int // Consecutive wasted stores, by key
+	onThrash           func(key K, value V)
+	bypass             func(key K) bool
+	subscribers        []*subscriber[K, V]
+	listeners          []*Listener[K, V]
+	dependents         map[K]map[K]struct{}      // Keys of the items depending on each key
+	alts               map[K]K                   // Keys of the items by their alternate key
+	tagged             map[string]map[K]struct{} // Keys of the items by tag, per SetWithTags
+	paths              *pathIndex[K]             // Nil unless Hierarchical
+	pathSeparator      string
+	persistPath        string
+	persistMutex       sync.Mutex    // Serializes saves to the persistPath
+	persisted          chan struct{} // Closed once periodic saves stop
+	onPersistError     func(err error)
+	adaptiveJitter     time.Duration
+	jitterThreshold    int
+	extraJitter        time.Duration // Added to the jitter band by adapt
+	expiring           map[int64]int // Stores by the second they expire in
+	pruned             int64         // Second in which expiring was pruned
+	onEvictionGroup    func(group string, evicted map[K]V)
+	evictionGroup      func(key K) string
+	evictionWindow     time.Duration
+	clock              Clock
+	staleWindow        time.Duration
+	refreshAfter       time.Duration
+	insertionWindow    time.Duration
+	negativeTTL        time.Duration
+	negatives          map[K]negative // Errors cached for the negativeTTL
+	requested          *cardinality   // Nil unless using a CardinalityWindow
+	minCapacity        int
+	maxCapacity        int
+	resizedCapacity    int // Set by Resize, bounding the growth below maxCapacity
+	metrics            MetricsSink
+	metricsInterval    time.Duration
+	trackTimes         bool
+	revalidate         func(ctx context.Context, key K) (V, error)
+	revalidateCtx      context.Context // Cancelled by Close
+	stopRevalidate     context.CancelFunc
+	onRevalidateError  func(key K, err error)
 
 	// Cache statistics
+	totalCost int64
 	sets      int64
 	gets      int64
 	hits      int64
 	misses    int64
 	evictions int64
-
-	items        map[K]*list.Element
-	evictionList *list.List
-	mutex        sync.RWMutex
-	rand         RandGenerator
+	corrupted int64
+	expiries  int64
+	thrashes  int64
+
+	items   map[K]*cacheEntry[K, V]
+	policy  policy[K, V]
+	filter  *tinyLFU[K] // Nil unless using TinyLFUAdmission
+	mutex   sync.RWMutex
+	rand    RandGenerator
+	pending []notification[K, V]
+	buckets []map[K]struct{}
+	expiry  expiryIndex[K, V]
+	seq     uint64
+	cohorts map[Cohort]map[K]struct{}
+	cohort  Cohort
+
+	loads     map[K]*load[V]
+	loadMutex sync.Mutex
+
+	keyLocks     map[K]*keyLock
+	keyLockMutex sync.Mutex
+
+	coalesced map[string]map[K]V // Evictions awaiting OnEvictionGroup
+	flush     Timer
+
+	dispatcher *dispatcher // Nil unless using CallbackWorkers
+
+	done      chan struct{} // Closed by Close to stop active expiration
+	closeOnce sync.Once
 }
 
-// New constructs an LRU Cache with the given Config object. config.Capacity
+// New constructs a Cache with the given Config object. config.Capacity
 // must be a positive int, and config.MaxAge a zero or positive duration. A
 // duration of zero disables item expiration. Panics given an invalid
-// config.Capacity or config.MaxAge.
+// config.Capacity or config.MaxAge. A cache using ActiveExpiration should be
+// closed with Close once it is no longer needed.
 func New[K comparable, V any](config Config[K, V]) *Cache[K, V] {
-	if config.Capacity <= 0 {
-		panic("Must supply a positive config.Capacity")
+	cache, err := NewWithError(config)
+	if err != nil {
+		panic(err.Error())
+	}
+	return cache
+}
+
+// NewWithError constructs a Cache with the given Config object as with New,
+// returning a *ConfigError rather than panicking given an invalid
+// configuration, such as one read from a file.
+func NewWithError[K comparable, V any](config Config[K, V]) (*Cache[K, V], error) {
+	cache, err := newCache(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache.expirationType == ActiveExpiration && cache.expirationInterval > 0 {
+		ticker := cache.clock.NewTicker(cache.expirationInterval)
+		go func() {
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.Chan():
+					cache.deleteExpired()
+				case <-cache.done:
+					return
+				}
+			}
+		}()
 	}
 
-	if config.MaxAge < 0 {
-		panic("Must supply a zero or positive config.MaxAge")
+	if cache.metrics != nil {
+		reportMetrics(cache.metrics, cache.metricsInterval, cache.clock, cache.Stats, cache.done)
 	}
 
-	if config.MinAge < 0 {
-		panic("Must supply a zero or positive config.MinAge")
+	return cache, nil
+}
+
+// newCache constructs a Cache as with NewWithError, but without starting
+// active expiration or the reporting of metrics, so that a ShardedCache may
+// schedule them across shards.
+func newCache[K comparable, V any](config Config[K, V]) (*Cache[K, V], error) {
+	if err := validate(config); err != nil {
+		return nil, err
 	}
 
-	if config.MinAge > 0 && config.MinAge > config.MaxAge {
-		panic("config.MinAge must be less than or equal to config.MaxAge")
+	if config.Clock == nil {
+		config.Clock = systemClock{}
 	}
 
 	minAge := config.MinAge
@@ -144,25 +691,147 @@ func New[K comparable, V any](config Config[K, V]) *Cache[K, V] {
 		interval = config.MaxAge
 	}
 
-	seed := rand.NewSource(time.Now().UnixNano())
+	thrashThreshold := config.ThrashThreshold
+	if thrashThreshold == 0 {
+		thrashThreshold = defaultThrashThreshold
+	}
+
+	jitterThreshold := config.JitterThreshold
+	if jitterThreshold == 0 {
+		jitterThreshold = defaultJitterThreshold
+	}
+
+	maxCost, cost := config.MaxCost, config.Cost
+	if config.MaxMemory > 0 {
+		sizer := config.Sizer
+		if sizer == nil {
+			sizer = ReflectSizer[V]{}
+		}
+		maxCost, cost = config.MaxMemory, memoryCost[K](sizer)
+	}
+
+	minCapacity := config.MinCapacity
+	if minCapacity == 0 {
+		minCapacity = 1
+	}
+
+	capacityInterval := config.CapacityInterval
+	if capacityInterval == 0 {
+		capacityInterval = defaultCapacityInterval
+	}
+
+	metricsInterval := config.MetricsInterval
+	if metricsInterval == 0 {
+		metricsInterval = defaultMetricsInterval
+	}
+
+	pathSeparator := config.PathSeparator
+	if pathSeparator == "" {
+		pathSeparator = defaultPathSeparator
+	}
+
+	generator := config.Rand
+	if generator == nil {
+		generator = newLockedRand()
+	}
 
 	cache := &Cache[K, V]{
 		capacity:           config.Capacity,
+		maxCost:            maxCost,
+		cost:               cost,
 		maxAge:             config.MaxAge,
 		minAge:             minAge,
 		expirationType:     config.ExpirationType,
+		expirationPolicy:   config.ExpirationPolicy,
 		expirationInterval: interval,
+		ageScale:           1,
 		onEviction:         config.OnEviction,
 		onExpiration:       config.OnExpiration,
-		items:              make(map[K]*list.Element),
-		evictionList:       list.New(),
-		rand:               rand.New(seed),
+		onRemove:           config.OnRemove,
+		onRemoval:          config.OnRemoval,
+		onSet:              config.OnSet,
+		onHit:              config.OnHit,
+		checksums:          config.Checksums,
+		transforms:         newPipeline(config.Transformers),
+		originSampleRate:   config.OriginSampleRate,
+		thrashWindow:       config.ThrashWindow,
+		thrashThreshold:    thrashThreshold,
+		onThrash:           config.OnThrash,
+		bypass:             config.Bypass,
+		persistPath:        config.PersistPath,
+		onPersistError:     config.OnPersistError,
+		adaptiveJitter:     config.AdaptiveJitter,
+		jitterThreshold:    jitterThreshold,
+		expiring:           make(map[int64]int),
+		onEvictionGroup:    config.OnEvictionGroup,
+		evictionGroup:      config.EvictionGroup,
+		evictionWindow:     config.EvictionWindow,
+		items:              make(map[K]*cacheEntry[K, V]),
+		policy:             newPolicy(config),
+		filter:             newAdmission[K](config.Admission, config.Capacity),
+		rand:               generator,
+		loads:              make(map[K]*load[V]),
+		keyLocks:           make(map[K]*keyLock),
+		buckets:            make([]map[K]struct{}, scanBuckets(config.Capacity)),
+		cohorts:            make(map[Cohort]map[K]struct{}),
+		done:               make(chan struct{}),
+		expiry:             newExpiryIndex(config),
+		clock:              config.Clock,
+		staleWindow:        config.StaleWhileRevalidate,
+		refreshAfter:       config.RefreshAfter,
+		insertionWindow:    config.InsertionWindow,
+		negativeTTL:        config.NegativeTTL,
+		requested:          newCardinality(config.CardinalityWindow, config.Clock.Now()),
+		minCapacity:        minCapacity,
+		maxCapacity:        config.MaxCapacity,
+		metrics:            config.Metrics,
+		metricsInterval:    metricsInterval,
+		trackTimes:         config.TrackTimes,
+		pathSeparator:      pathSeparator,
+		revalidate:         config.Revalidate,
+		onRevalidateError:  config.OnRevalidateError,
+		dispatcher:         newDispatcher(config.CallbackWorkers, config.CallbackQueue),
+	}
+
+	if config.Hierarchical {
+		cache.paths = &pathIndex[K]{separator: cache.pathSeparator}
+	}
+
+	if config.Revalidate != nil {
+		cache.revalidateCtx, cache.stopRevalidate = context.WithCancel(context.Background())
+	}
+
+	if config.PersistPath != "" {
+		if err := cache.load(); err != nil {
+			cache.persistError(err)
+		}
+		if config.PersistInterval > 0 {
+			cache.persistEvery(config.PersistInterval)
+		}
+	}
+
+	if config.Preload != nil {
+		cache.SetAll(config.Preload())
+	}
+
+	if config.MaxCapacity > 0 {
+		cache.tuneEvery(capacityInterval)
 	}
 
-	if config.ExpirationType == ActiveExpiration && interval > 0 {
+	return cache, nil
+}
+
+// NewWithContext constructs a Cache with the given Config object, as with
+// New. Cancelling the context closes the cache, stopping active expiration.
+func NewWithContext[K comparable, V any](ctx context.Context, config Config[K, V]) *Cache[K, V] {
+	cache := New(config)
+
+	if done := ctx.Done(); done != nil {
 		go func() {
-			for range time.Tick(interval) {
-				cache.deleteExpired()
+			select {
+			case <-done:
+				cache.Close()
+			case <-cache.done:
 			}
 		}()
 	}
@@ -171,29 +840,228 @@ func New[K comparable, V any](config Config[K, V]) *Cache[K, V] {
 }
 
 // Set updates a key:value pair in the cache. Returns true if an eviction
-// occurred, and subsequently invokes the OnEviction callback.
+// occurred, and subsequently invokes the OnEviction callback. When using
+// TinyLFUAdmission, a new key may not be stored once the cache is full.
 func (cache *Cache[K, V]) Set(key K, value V) bool {
 	cache.mutex.Lock()
-	defer cache.mutex.Unlock()
+	defer cache.unlock()
+
+	return cache.set(key, value, cache.getTimestamp(), 0)
+}
+
+// TrySet updates a key:value pair in the cache, as with Set, returning a
+// *RejectedError if the item could not be stored, either because it was
+// refused by TinyLFUAdmission or because its cost alone exceeds MaxCost. An
+// item exceeding the MaxCost leaves the cache untouched, including any item
+// already stored at key, and invokes no callback.
+func (cache *Cache[K, V]) TrySet(key K, value V) error {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	if cache.bypassed(key) {
+		return &RejectedError{
+			Reason:    Bypassed,
+			Occupancy: float64(len(cache.items)) / float64(cache.capacity),
+		}
+	}
+
+	// Checked before storing, so that no callback is invoked for the item
+	if cache.maxCost > 0 && cache.cost != nil && cache.cost(key, value) > cache.maxCost {
+		return &RejectedError{
+			Reason:    CostExceeded,
+			Occupancy: float64(cache.totalCost) / float64(cache.maxCost),
+		}
+	}
+
+	cache.set(key, value, cache.getTimestamp(), 0)
+	if _, ok := cache.items[key]; ok {
+		return nil
+	}
+
+	err := &RejectedError{
+		Reason:    AdmissionRejected,
+		Occupancy: float64(len(cache.items)) / float64(cache.capacity),
+	}
+	if victim := cache.victim(); victim != nil && cache.lifetime(victim) > 0 {
+		if retry := victim.deadline.Sub(cache.clock.Now()); retry > 0 {
+			err.RetryAfter = retry
+		}
+	}
+	return err
+}
+
+// SetWithTTL updates a key:value pair in the cache, expiring it once ttl has
+// elapsed rather than after the cache's MaxAge. Jitter is not applied to the
+// ttl. A zero or negative ttl uses the cache's MaxAge, as with Set. Returns
+// true if an eviction occurred, and subsequently invokes the OnEviction
+// callback.
+//
+// For ActiveExpiration with a MaxAge of zero, an ExpirationInterval must be
+// configured for the entry to be actively expired.
+func (cache *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	if ttl <= 0 {
+		return cache.set(key, value, cache.getTimestamp(), 0)
+	}
+	return cache.set(key, value, cache.clock.Now(), ttl)
+}
+
+// SetWithOrigin updates a key:value pair in the cache as with Set, recording
+// origin as where the item came from, such as the name of the caller. The
+// origin is listed by Export and Report.
+func (cache *Cache[K, V]) SetWithOrigin(key K, value V, origin string) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	evict := cache.set(key, value, cache.getTimestamp(), 0)
+	if entry, ok := cache.items[key]; ok {
+		entry.origin = origin
+	}
+
+	return evict
+}
+
+func (cache *Cache[K, V]) set(key K, value V, timestamp time.Time, ttl time.Duration) bool {
+	if cache.bypassed(key) {
+		return false
+	}
 
 	cache.sets++
-	timestamp := cache.getTimestamp()
+	if cache.filter != nil {
+		cache.filter.record(key)
+	}
+	delete(cache.negatives, key)
+
+	var cost int64
+	if cache.cost != nil {
+		cost = cache.cost(key, value)
+	}
 
-	if element, ok := cache.items[key]; ok {
-		cache.evictionList.MoveToFront(element)
-		entry := element.Value.(*cacheEntry[K, V])
+	if entry, ok := cache.items[key]; ok {
+		// Unlinked first, so that invalidation cannot cycle back to the entry
+		cache.unlink(entry)
+		cache.notify(nil, entry, Replaced)
+		cache.policy.access(entry)
+		cache.untag(entry)
+		cache.unlabel(entry)
 		entry.value = value
 		entry.timestamp = timestamp
-		return false
+		entry.ttl = ttl
+		entry.hits = 0
+		entry.origin = cache.origin()
+		if entry.times != nil {
+			entry.times.Updated = cache.clock.Now()
+		}
+		cache.schedule(entry)
+		cache.totalCost += cost - entry.cost
+		entry.cost = cost
+		cache.notifySet(entry, true)
+		return cache.evictToFit()
+	}
+
+	if cache.filter != nil && len(cache.items) >= cache.capacity {
+		if victim := cache.victim(); victim != nil && !cache.filter.allow(key, victim.key) {
+			return false
+		}
+	}
+
+	entry := &cacheEntry[K, V]{key: key, value: value, timestamp: timestamp, ttl: ttl, seq: cache.seq, cost: cost, origin: cache.origin(), expiry: -1}
+	if cache.insertionWindow > 0 {
+		entry.inserted = cache.clock.Now()
+	}
+	if cache.trackTimes {
+		now := cache.clock.Now()
+		entry.times = &EntryTimes{Created: now, Updated: now}
+	}
+	cache.policy.add(entry)
+	cache.schedule(entry)
+	cache.items[key] = entry
+	cache.bucket(entry)[key] = struct{}{}
+	if cache.paths != nil {
+		cache.paths.insert(key)
+	}
+	cache.seq++
+	cache.totalCost += cost
+	cache.notifySet(entry, false)
+
+	return cache.evictToFit()
+}
+
+// SetMulti updates all of the key:value pairs in the cache, taking the lock
+// only once. Unlike SetAll, the items are not tagged with a Cohort. Returns
+// true if an eviction occurred, and subsequently invokes the OnEviction
+// callback.
+func (cache *Cache[K, V]) SetMulti(items map[K]V) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	evict := false
+	for key, value := range items {
+		if cache.set(key, value, cache.getTimestamp(), 0) {
+			evict = true
+		}
+	}
+
+	return evict
+}
+
+// SetAll updates all of the key:value pairs in the cache, taking the lock only
+// once. The items are tagged with a new Cohort, which is returned, so that they
+// may later be removed together with ExpireCohort. An item leaves the cohort
+// once it is replaced by a subsequent Set. Returns true if an eviction
+// occurred, and subsequently invokes the OnEviction callback.
+func (cache *Cache[K, V]) SetAll(items map[K]V) (Cohort, bool) {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	cache.cohort++
+	cohort := cache.cohort
+
+	evict := false
+	for key, value := range items {
+		if cache.set(key, value, cache.getTimestamp(), 0) {
+			evict = true
+		}
+		if entry, ok := cache.items[key]; ok {
+			cache.tag(entry, cohort)
+		}
 	}
 
-	entry := &cacheEntry[K, V]{key, value, timestamp}
-	element := cache.evictionList.PushFront(entry)
-	cache.items[key] = element
+	return cohort, evict
+}
+
+// ExpireCohort removes all items still tagged with the given cohort, invoking
+// the OnExpiration callback for each of them. This allows a bad bulk load to be
+// rolled back without touching the rest of the cache. Returns the number of
+// items removed.
+func (cache *Cache[K, V]) ExpireCohort(cohort Cohort) int {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	removed := 0
+	for key := range cache.cohorts[cohort] {
+		entry := cache.items[key]
+		cache.deleteEntry(entry)
+		cache.expiries++
+		cache.notify(cache.onExpiration, entry, Expired)
+		removed++
+	}
 
-	evict := cache.evictionList.Len() > cache.capacity
-	if evict {
-		cache.evictOldest()
+	return removed
+}
+
+// evictToFit evicts the oldest items until both the capacity and max cost are
+// satisfied, returning whether any item was evicted.
+func (cache *Cache[K, V]) evictToFit() bool {
+	evict := false
+	for len(cache.items) > cache.capacity ||
+		(cache.maxCost > 0 && cache.totalCost > cache.maxCost) {
+		if !cache.evictOldest() {
+			break
+		}
+		evict = true
 	}
 	return evict
 }
@@ -203,31 +1071,182 @@ func (cache *Cache[K, V]) Set(key K, value V) bool {
 // had expired on access
 func (cache *Cache[K, V]) Get(key K) (value V, found bool) {
 	cache.mutex.Lock()
-	defer cache.mutex.Unlock()
+	defer cache.unlock()
+
+	return cache.get(key)
+}
+
+// GetMulti returns the values stored at each of the keys which were found,
+// taking the lock only once. As with Get, the OnExpiration callback is invoked
+// for values which had expired on access.
+func (cache *Cache[K, V]) GetMulti(keys []K) map[K]V {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := cache.get(key); ok {
+			values[key] = value
+		}
+	}
 
+	return values
+}
+
+func (cache *Cache[K, V]) get(key K) (value V, found bool) {
 	cache.gets++
+	if cache.requested != nil {
+		cache.requested.record(hashKey(key), cache.clock.Now())
+	}
+	if cache.bypassed(key) {
+		cache.misses++
+		cache.emit(EventMiss, key, value)
+		return value, false
+	}
+
+	if cache.filter != nil {
+		cache.filter.record(key)
+	}
 
-	if element, ok := cache.items[key]; ok {
-		entry := element.Value.(*cacheEntry[K, V])
-		if cache.maxAge == 0 || time.Since(entry.timestamp) <= cache.maxAge {
-			cache.evictionList.MoveToFront(element)
+	if entry, ok := cache.items[key]; ok {
+		if !cache.expired(entry) {
+			cache.policy.access(entry)
 			cache.hits++
+			entry.hits++
+			cache.accessed(entry)
+			delete(cache.thrashing, key)
+			cache.notifyHit(entry)
+			if cache.expirationPolicy == SlidingExpiration {
+				cache.refresh(entry)
+			}
+			cache.refreshAhead(entry)
 			return entry.value, true
 		}
 
+		// Kept for GetStale
+		if cache.stale(entry) {
+			cache.misses++
+			cache.emit(EventMiss, key, value)
+			return value, false
+		}
+
 		// Entry expired
-		cache.deleteElement(element)
+		cache.deleteEntry(entry)
 		cache.misses++
-		if cache.onExpiration != nil {
-			cache.onExpiration(entry.key, entry.value)
-		}
+		cache.expiries++
+		cache.wasted(entry)
+		cache.notify(cache.onExpiration, entry, Expired)
+		cache.emit(EventMiss, key, value)
 		return value, false
 	}
 
 	cache.misses++
+	cache.emit(EventMiss, key, value)
 	return value, false
 }
 
+// GetOrLoad returns the value stored at `key`, invoking loader to produce and
+// store it on a miss. Concurrent calls for the same key share a single
+// invocation of loader, with the other callers blocking until it returns or
+// their ctx is done. The loader receives the ctx of the caller which started
+// the load. Values are only stored when loader returns a nil error.
+func (cache *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error)) (value V, err error) {
+	if value, ok, err := cache.getOrNegative(key); ok || err != nil {
+		return value, err
+	}
+
+	cache.loadMutex.Lock()
+	if call, ok := cache.loads[key]; ok {
+		cache.loadMutex.Unlock()
+
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return value, ctx.Err()
+		}
+	}
+
+	call := &load[V]{done: make(chan struct{}), err: errLoaderPanicked}
+	cache.loads[key] = call
+	cache.loadMutex.Unlock()
+
+	defer func() {
+		cache.loadMutex.Lock()
+		delete(cache.loads, key)
+		cache.loadMutex.Unlock()
+		close(call.done)
+	}()
+
+	start := cache.clock.Now()
+	call.value, call.err = loader(ctx, key)
+	cache.observeLoad(start)
+	if call.err == nil {
+		cache.Set(key, call.value)
+	} else if cache.negativeTTL > 0 && ctx.Err() == nil {
+		cache.setNegative(key, call.err)
+	}
+
+	return call.value, call.err
+}
+
+// Touch resets the age of the item at `key` and marks it as the most recently
+// accessed, without replacing its value. Returns a bool indicating whether the
+// item was found. The OnExpiration callback is invoked if the item had expired
+// on access.
+func (cache *Cache[K, V]) Touch(key K) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	entry, ok := cache.items[key]
+	if !ok {
+		return false
+	}
+
+	if cache.expired(entry) {
+		cache.deleteEntry(entry)
+		cache.expiries++
+		cache.wasted(entry)
+		cache.notify(cache.onExpiration, entry, Expired)
+		return false
+	}
+
+	cache.policy.access(entry)
+	cache.refresh(entry)
+	cache.accessed(entry)
+	return true
+}
+
+// Promote marks the item at `key` as the most recently accessed, as a hint
+// that it should be kept, without resetting its age or counting as a hit.
+// Returns a bool indicating whether the item was found.
+func (cache *Cache[K, V]) Promote(key K) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	entry, ok := cache.items[key]
+	if ok {
+		cache.policy.access(entry)
+	}
+	return ok
+}
+
+// Demote marks the item at `key` as the next to evict, as a hint that it is
+// unlikely to be requested again, without resetting its age. With
+// ARCEviction, the item becomes the least recently used of those accessed
+// once, and with a CustomPolicy the hint is ignored unless the policy
+// implements Demote. Returns a bool indicating whether the item was found.
+func (cache *Cache[K, V]) Demote(key K) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	entry, ok := cache.items[key]
+	if ok {
+		cache.policy.sink(entry)
+	}
+	return ok
+}
+
 // Has returns whether the `key` is in the cache without updating
 // how recently it was accessed or deleting it for having expired.
 func (cache *Cache[K, V]) Has(key K) bool {
@@ -245,21 +1264,148 @@ func (cache *Cache[K, V]) Peek(key K) (value V, found bool) {
 	cache.mutex.RLock()
 	defer cache.mutex.RUnlock()
 
-	if element, ok := cache.items[key]; ok {
-		return element.Value.(*cacheEntry[K, V]).value, true
+	if entry, ok := cache.items[key]; ok {
+		return entry.value, true
 	}
 
 	return value, false
 }
 
+// TTL returns the remaining lifetime of the item at `key`, and a boolean
+// reporting whether it was found and has not expired. A zero duration is
+// returned for items which do not expire. Does not update how recently the
+// item was accessed or delete it for having expired.
+func (cache *Cache[K, V]) TTL(key K) (ttl time.Duration, found bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	entry, ok := cache.items[key]
+	if !ok {
+		return 0, false
+	}
+
+	return cache.remaining(entry)
+}
+
+// PeekEntry returns a copy of the item at `key`, including its metadata, and
+// a boolean specifying whether it was found, without updating how recently it
+// was accessed or deleting it for having expired.
+func (cache *Cache[K, V]) PeekEntry(key K) (Entry[K, V], bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	if entry, ok := cache.items[key]; ok {
+		return cache.export(entry), true
+	}
+
+	return Entry[K, V]{}, false
+}
+
+// remaining returns the remaining lifetime of the entry, and whether it has
+// not expired. A zero duration is returned for entries which do not expire.
+func (cache *Cache[K, V]) remaining(entry *cacheEntry[K, V]) (time.Duration, bool) {
+	maxAge := cache.lifetime(entry)
+	if maxAge == 0 {
+		return 0, true
+	}
+
+	remaining := maxAge - cache.clock.Now().Sub(entry.timestamp)
+	if remaining < 0 {
+		return 0, false
+	}
+
+	return remaining, true
+}
+
+// export copies the entry with the mutex held.
+func (cache *Cache[K, V]) export(entry *cacheEntry[K, V]) Entry[K, V] {
+	remaining, _ := cache.remaining(entry)
+	return Entry[K, V]{
+		Key:       entry.key,
+		Value:     entry.value,
+		Timestamp: entry.timestamp,
+		TTL:       entry.ttl,
+		Seq:       entry.seq,
+		Origin:    entry.origin,
+		Remaining: remaining,
+		Hits:      entry.hits,
+		Times:     entry.trackedTimes(),
+	}
+}
+
 // Remove removes the provided key from the cache, returning a bool indicating
-// whether it existed.
+// whether it existed. The OnRemove callback is invoked if it existed.
 func (cache *Cache[K, V]) Remove(key K) bool {
 	cache.mutex.Lock()
-	defer cache.mutex.Unlock()
+	defer cache.unlock()
+
+	return cache.remove(key)
+}
 
-	if element, ok := cache.items[key]; ok {
-		cache.deleteElement(element)
+// Pop atomically removes the item at `key` and returns its value. The boolean
+// value reports whether the value was found. The OnRemove callback is invoked
+// if it was found, and the OnExpiration callback if it had expired.
+func (cache *Cache[K, V]) Pop(key K) (value V, found bool) {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	entry, ok := cache.items[key]
+	if !ok {
+		return value, false
+	}
+
+	cache.deleteEntry(entry)
+	if cache.expired(entry) {
+		cache.expiries++
+		cache.wasted(entry)
+		cache.notify(cache.onExpiration, entry, Expired)
+		return value, false
+	}
+
+	cache.notify(cache.onRemove, entry, Removed)
+	return entry.value, true
+}
+
+// RemoveMulti removes each of the provided keys from the cache, taking the
+// lock only once. Returns the number of keys which existed, invoking the
+// OnRemove callback for each of them.
+func (cache *Cache[K, V]) RemoveMulti(keys []K) int {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	removed := 0
+	for _, key := range keys {
+		if cache.remove(key) {
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// RemoveFunc removes every item for which pred returns true, taking the lock
+// only once and invoking the OnRemove callback for each of them. pred is
+// invoked with every item while the cache's lock is held, and so must not
+// call back into the cache. Returns the number of items removed, not
+// including their dependents.
+func (cache *Cache[K, V]) RemoveFunc(pred func(key K, value V) bool) int {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	var keys []K
+	for key, entry := range cache.items {
+		if pred(key, entry.value) {
+			keys = append(keys, key)
+		}
+	}
+
+	return cache.removeKeys(keys)
+}
+
+func (cache *Cache[K, V]) remove(key K) bool {
+	if entry, ok := cache.items[key]; ok {
+		cache.deleteEntry(entry)
+		cache.notify(cache.onRemove, entry, Removed)
 		return true
 	}
 
@@ -269,30 +1415,60 @@ func (cache *Cache[K, V]) Remove(key K) bool {
 // EvictOldest removes the oldest item from the cache, while also invoking any
 // eviction callback. A bool is returned indicating whether or not an item was
 // removed
+//
+// Throughout the cache, the oldest item is the least recently set or accessed
+// one, as tracked by the order of access rather than by timestamps. Items set
+// within the same instant are therefore ordered deterministically, by the
+// order in which they were set.
 func (cache *Cache[K, V]) EvictOldest() bool {
 	cache.mutex.Lock()
-	defer cache.mutex.Unlock()
+	defer cache.unlock()
 
 	return cache.evictOldest()
 }
 
+// RemoveOldest removes the oldest item from the cache as with EvictOldest,
+// returning its key and value. The boolean value reports whether an item was
+// removed.
+func (cache *Cache[K, V]) RemoveOldest() (key K, value V, found bool) {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	entry := cache.victim()
+	if entry == nil {
+		return key, value, false
+	}
+
+	cache.evictOldest()
+	return entry.key, entry.value, true
+}
+
 // Len returns the number of items in the cache.
 func (cache *Cache[K, V]) Len() int {
 	cache.mutex.RLock()
 	defer cache.mutex.RUnlock()
 
-	return cache.evictionList.Len()
+	return len(cache.items)
 }
 
-// Clear empties the cache.
-func (cache *Cache[K, V]) Clear() {
+// Clear empties the cache, invoking the OnRemoval callback for each item.
+// Returns the number of items removed.
+func (cache *Cache[K, V]) Clear() int {
 	cache.mutex.Lock()
-	defer cache.mutex.Unlock()
-
-	for _, val := range cache.items {
-		cache.deleteElement(val)
+	defer cache.unlock()
+
+	n := len(cache.items)
+	cache.dependents = nil
+	cache.alts = nil
+	cache.negatives = nil
+	cache.tagged = nil
+	for _, entry := range cache.items {
+		cache.deleteEntry(entry)
+		cache.notify(nil, entry, Cleared)
 	}
-	cache.evictionList.Init()
+	cache.policy.clear()
+
+	return n
 }
 
 // Keys returns all keys in the cache.
@@ -311,36 +1487,195 @@ func (cache *Cache[K, V]) Keys() []K {
 	return keys
 }
 
-// OrderedKeys returns all keys in the cache, ordered from oldest to newest.
-func (cache *Cache[K, V]) OrderedKeys() []K {
+// Items returns a point-in-time copy of all key:value pairs in the cache,
+// without updating how recently they were accessed or deleting those which
+// have expired.
+func (cache *Cache[K, V]) Items() map[K]V {
 	cache.mutex.RLock()
 	defer cache.mutex.RUnlock()
 
-	keys := make([]K, len(cache.items))
-	i := 0
+	items := make(map[K]V, len(cache.items))
+	for key, entry := range cache.items {
+		items[key] = entry.value
+	}
+
+	return items
+}
+
+// Values returns a point-in-time copy of all values in the cache, ordered from
+// oldest to newest as with OrderedKeys.
+func (cache *Cache[K, V]) Values() []V {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	values := make([]V, 0, len(cache.items))
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		values = append(values, entry.value)
+		return true
+	})
+
+	return values
+}
 
-	for element := cache.evictionList.Back(); element != nil; element = element.Prev() {
-		keys[i] = element.Value.(*cacheEntry[K, V]).key
+// Range invokes fn for each item in the cache, ordered from oldest to newest
+// as with OrderedKeys, until fn returns false. The read lock is held
+// throughout, so that fn sees a consistent view of the cache without it being
+// copied, and so fn must not modify the cache. Does not update how recently
+// items were accessed or delete those which have expired.
+func (cache *Cache[K, V]) Range(fn func(key K, value V) bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		return fn(entry.key, entry.value)
+	})
+}
+
+// Scan returns a batch of keys in the cache starting from the given cursor,
+// along with the cursor from which to continue. A scan starts with a zero
+// cursor and is complete once a zero cursor is returned. Keys are visited in
+// buckets, with the lock held for a single bucket at a time, until at least
+// count keys are gathered or the scan completes. Keys present for the full
+// duration of a scan are returned exactly once. Keys added or removed during
+// a scan may or may not be returned. A count less than one is treated as one.
+func (cache *Cache[K, V]) Scan(cursor Cursor, count int) (keys []K, next Cursor) {
+	if count < 1 {
+		count = 1
+	}
+
+	i := int(cursor)
+
+	for i < len(cache.buckets) && len(keys) < count {
+		cache.mutex.RLock()
+		for key := range cache.buckets[i] {
+			keys = append(keys, key)
+		}
+		cache.mutex.RUnlock()
 		i++
 	}
 
+	if i >= len(cache.buckets) {
+		return keys, 0
+	}
+
+	return keys, Cursor(i)
+}
+
+// OrderedKeys returns all keys in the cache, ordered from oldest to newest,
+// i.e. in the order they would be evicted.
+func (cache *Cache[K, V]) OrderedKeys() []K {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	keys := make([]K, 0, len(cache.items))
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		keys = append(keys, entry.key)
+		return true
+	})
+
 	return keys
 }
 
+// OrderedEntriesInfo returns the key, age and remaining lifetime of all items
+// in the cache, ordered from oldest to newest as with OrderedKeys, without
+// updating how recently they were accessed or deleting those which have
+// expired. All items are described at the same instant.
+func (cache *Cache[K, V]) OrderedEntriesInfo() []EntryInfo[K] {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	now := cache.clock.Now()
+	infos := make([]EntryInfo[K], 0, len(cache.items))
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		info := EntryInfo[K]{Key: entry.key, Age: now.Sub(entry.timestamp), Times: entry.trackedTimes()}
+		if maxAge := cache.lifetime(entry); maxAge > 0 && info.Age < maxAge {
+			info.Remaining = maxAge - info.Age
+		}
+		infos = append(infos, info)
+		return true
+	})
+
+	return infos
+}
+
+// Export returns a copy of all items in the cache, ordered from oldest to
+// newest as with OrderedKeys, without updating how recently they were accessed
+// or deleting those which have expired.
+func (cache *Cache[K, V]) Export() []Entry[K, V] {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	entries := make([]Entry[K, V], 0, len(cache.items))
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		entries = append(entries, cache.export(entry))
+		return true
+	})
+
+	return entries
+}
+
+// ImportFrom copies all unexpired items from other into the cache, from
+// oldest to newest so that their recency is preserved. Unless
+// opts.ResetAge is set, each item keeps the lifetime it had remaining in
+// other. When other holds more items than the cache's capacity, only the
+// newest are imported. Returns the number of items imported.
+func (cache *Cache[K, V]) ImportFrom(other *Cache[K, V], opts ImportOptions) int {
+	other.mutex.RLock()
+	entries := make([]cacheEntry[K, V], 0, len(other.items))
+	other.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		if !other.expired(entry) {
+			entries = append(entries, cacheEntry[K, V]{
+				key:       entry.key,
+				value:     entry.value,
+				timestamp: entry.timestamp,
+				ttl:       other.lifetime(entry),
+			})
+		}
+		return true
+	})
+	other.mutex.RUnlock()
+
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	if len(entries) > cache.capacity {
+		entries = entries[len(entries)-cache.capacity:]
+	}
+
+	imported := 0
+	for _, entry := range entries {
+		if _, ok := cache.items[entry.key]; ok && opts.SkipExisting {
+			continue
+		}
+
+		if opts.ResetAge {
+			cache.set(entry.key, entry.value, cache.getTimestamp(), 0)
+		} else {
+			cache.set(entry.key, entry.value, entry.timestamp, entry.ttl)
+		}
+		imported++
+	}
+
+	return imported
+}
+
 // SetMaxAge updates the max age for items in the cache. A duration of zero
 // disables expiration. A negative duration, or one that is less than minAge,
 // results in an error.
 func (cache *Cache[K, V]) SetMaxAge(maxAge time.Duration) error {
 	if maxAge < 0 {
 		return errors.New("Must supply a zero or positive maxAge")
-	} else if maxAge < cache.minAge {
-		return errors.New("Must supply a maxAge greater than or equal to minAge")
 	}
 
 	cache.mutex.Lock()
 	defer cache.mutex.Unlock()
 
+	if maxAge < cache.minAge {
+		return errors.New("Must supply a maxAge greater than or equal to minAge")
+	}
+
 	cache.maxAge = maxAge
+	cache.reschedule()
 
 	return nil
 }
@@ -351,13 +1686,15 @@ func (cache *Cache[K, V]) SetMaxAge(maxAge time.Duration) error {
 func (cache *Cache[K, V]) SetMinAge(minAge time.Duration) error {
 	if minAge < 0 {
 		return errors.New("Must supply a zero or positive minAge")
-	} else if minAge > cache.maxAge {
-		return errors.New("Must supply a minAge lesser than or equal to maxAge")
 	}
 
 	cache.mutex.Lock()
 	defer cache.mutex.Unlock()
 
+	if minAge > cache.maxAge {
+		return errors.New("Must supply a minAge lesser than or equal to maxAge")
+	}
+
 	if minAge == 0 {
 		cache.minAge = cache.maxAge
 	} else {
@@ -367,6 +1704,33 @@ func (cache *Cache[K, V]) SetMinAge(minAge time.Duration) error {
 	return nil
 }
 
+// ScaleMaxAge scales the effective max age of all items in the cache by the
+// given factor, without changing the configured max age. For example, a
+// factor of 0.5 treats items as expired at half of their age. This allows
+// the stalest items to be dropped first when the cache must shrink. A factor
+// of 1 restores the configured max age. A factor outside of (0, 1] results
+// in an error.
+func (cache *Cache[K, V]) ScaleMaxAge(factor float64) error {
+	if factor <= 0 || factor > 1 {
+		return errors.New("Must supply a factor greater than 0 and at most 1")
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.ageScale = factor
+	cache.reschedule()
+
+	return nil
+}
+
+// RemoveExpired removes all expired items from the cache, invoking the
+// OnExpiration callback for each of them. Returns the number of items
+// removed.
+func (cache *Cache[K, V]) RemoveExpired() int {
+	return cache.deleteExpired()
+}
+
 // OnEviction sets the eviction callback.
 func (cache *Cache[K, V]) OnEviction(callback func(key K, value V)) {
 	cache.mutex.Lock()
@@ -383,93 +1747,533 @@ func (cache *Cache[K, V]) OnExpiration(callback func(key K, value V)) {
 	cache.onExpiration = callback
 }
 
+// OnRemove sets the removal callback.
+func (cache *Cache[K, V]) OnRemove(callback func(key K, value V)) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.onRemove = callback
+}
+
+// OnRemoval sets the callback invoked whenever an item leaves the cache.
+func (cache *Cache[K, V]) OnRemoval(callback func(key K, value V, reason RemovalReason)) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.onRemoval = callback
+}
+
+// OnSet sets the callback invoked when an item is stored.
+func (cache *Cache[K, V]) OnSet(callback func(key K, value V, replaced bool)) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.onSet = callback
+}
+
+// OnHit sets the callback invoked when Get finds an item.
+func (cache *Cache[K, V]) OnHit(callback func(key K, value V)) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.onHit = callback
+}
+
+// SetBypass updates the predicate reporting keys which must not be cached,
+// such as a deny list updated at runtime. A nil predicate caches all keys.
+// See Config.Bypass.
+func (cache *Cache[K, V]) SetBypass(bypass func(key K) bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.bypass = bypass
+}
+
+// OnThrash sets the callback invoked with keys whose stores are repeatedly
+// wasted. See Config.ThrashWindow.
+func (cache *Cache[K, V]) OnThrash(callback func(key K, value V)) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.onThrash = callback
+}
+
 // Stats returns cache stats.
 func (cache *Cache[K, V]) Stats() Stats {
 	cache.mutex.RLock()
 	defer cache.mutex.RUnlock()
 
+	return cache.stats()
+}
+
+// stats returns cache stats with the mutex held.
+func (cache *Cache[K, V]) stats() Stats {
 	return Stats{
-		Capacity:  int64(cache.capacity),
-		Count:     int64(cache.evictionList.Len()),
-		Sets:      cache.sets,
-		Gets:      cache.gets,
-		Hits:      cache.hits,
-		Misses:    cache.misses,
-		Evictions: cache.evictions,
+		Capacity:    int64(cache.capacity),
+		Count:       int64(len(cache.items)),
+		Sets:        cache.sets,
+		Gets:        cache.gets,
+		Hits:        cache.hits,
+		Misses:      cache.misses,
+		Evictions:   cache.evictions,
+		Cost:        cache.totalCost,
+		Corruptions: cache.corrupted,
+		Expirations: cache.expiries,
+		Thrashes:    cache.thrashes,
+		Cardinality: cache.cardinality(),
+	}
+}
+
+// StartStatsReporter invokes report every interval with the current Stats and
+// their Delta since the previous report, until the returned stop function is
+// called or the cache is closed. The first delta is since the reporter was
+// started. Panics given a non-positive interval.
+func (cache *Cache[K, V]) StartStatsReporter(interval time.Duration, report func(stats, delta Stats)) (stop func()) {
+	return startStatsReporter(interval, cache.clock, cache.Stats, cache.done, report)
+}
+
+// startStatsReporter reports the stats returned by stats every interval of
+// clock, until stopped or done is closed.
+func startStatsReporter(interval time.Duration, clock Clock, stats func() Stats, done <-chan struct{}, report func(stats, delta Stats)) (stop func()) {
+	if interval <= 0 {
+		panic("Must supply a positive interval to StartStatsReporter")
+	}
+
+	stopped := make(chan struct{})
+	var once sync.Once
+
+	ticker := clock.NewTicker(interval)
+	previous := stats()
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.Chan():
+				current := stats()
+				report(current, current.Delta(previous))
+				previous = current
+			case <-stopped:
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(stopped)
+		})
 	}
 }
 
 // Resize the cache to hold at most n entries. If n is smaller than the current
-// size, entries are evicted to fit the new size. It errors if n <= 0.
+// size, entries are evicted to fit the new size. With a MaxCapacity, the
+// capacity is not grown past n either, so that a capacity assigned from
+// outside, as by a Coordinator, holds; resizing to the MaxCapacity or beyond
+// restores the full range. It errors if n <= 0.
 func (cache *Cache[K, V]) Resize(n int) error {
 	if n <= 0 {
 		return errors.New("must supply a positive capacity to Resize")
 	}
 
 	cache.mutex.Lock()
-	defer cache.mutex.Unlock()
-	c := cache.capacity
+	defer cache.unlock()
+	cache.resizedCapacity = n
+	cache.resize(n)
+
+	return nil
+}
+
+// resize updates the capacity of the cache with the mutex held, evicting
+// entries to fit.
+func (cache *Cache[K, V]) resize(n int) {
 	cache.capacity = n
+	if policy, ok := cache.policy.(resizablePolicy); ok {
+		policy.resize(n)
+	}
+	cache.evictToFit()
+}
 
-	for i := 0; i < c-n; i++ {
-		successful := cache.evictOldest()
-		if !successful {
-			break
-		}
+// MaxCost returns the maximum total cost of the items in the cache, or zero if
+// cost-based eviction is disabled. With a MaxMemory, it is the memory limit.
+func (cache *Cache[K, V]) MaxCost() int64 {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	return cache.maxCost
+}
+
+// SetMaxCost updates the maximum total cost of the items in the cache,
+// evicting items to fit if necessary. A max cost of zero disables cost-based
+// eviction. A negative max cost results in an error.
+func (cache *Cache[K, V]) SetMaxCost(maxCost int64) error {
+	if maxCost < 0 {
+		return errors.New("Must supply a zero or positive maxCost")
 	}
 
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	cache.maxCost = maxCost
+	cache.evictToFit()
+
 	return nil
 }
 
-func (cache *Cache[K, V]) deleteExpired() {
-	keys := cache.Keys()
+// Sync delivers any evictions awaiting OnEvictionGroup, and waits for the
+// CallbackWorkers to invoke all callbacks queued before it was called, so that
+// their effects are observed once it returns. It must not be called from a
+// callback when using CallbackWorkers.
+//
+// Only callbacks are deferred. Items are stored and removed synchronously
+// under the cache's lock, so a Get following a Set in the same goroutine
+// always observes the write, unless the item was since evicted, expired or
+// replaced, or its key is bypassed.
+func (cache *Cache[K, V]) Sync() {
+	cache.flushEvictions()
+	if cache.dispatcher != nil {
+		cache.dispatcher.wait()
+	}
+}
+
+// Close stops the goroutine expiring items for ActiveExpiration, delivers any
+// evictions awaiting OnEvictionGroup, and waits for the CallbackWorkers to
+// invoke all queued callbacks before stopping them. The cache remains usable,
+// with expiration enforced passively and callbacks invoked synchronously. It
+// must not be called from a callback when using CallbackWorkers. With
+// Config.PersistPath, the cache is saved, and the error of saving it is
+// returned. Calling Close more than once has no effect.
+func (cache *Cache[K, V]) Close() error {
+	var err error
+	cache.closeOnce.Do(func() {
+		close(cache.done)
+		if cache.stopRevalidate != nil {
+			cache.stopRevalidate()
+		}
+		if cache.persisted != nil {
+			<-cache.persisted
+		}
+		if cache.persistPath != "" {
+			err = cache.Persist()
+		}
+	})
+	cache.flushEvictions()
+	if cache.dispatcher != nil {
+		cache.dispatcher.close()
+	}
+	return err
+}
+
+// deleteExpired removes expired entries in order of their deadline, taking
+// the lock once per entry.
+func (cache *Cache[K, V]) deleteExpired() int {
+	removed := 0
 
-	for i := range keys {
+	for {
 		cache.mutex.Lock()
 
-		if element, ok := cache.items[keys[i]]; ok {
-			entry := element.Value.(*cacheEntry[K, V])
-			if cache.maxAge > 0 && time.Since(entry.timestamp) > cache.maxAge {
-				cache.deleteElement(element)
-				if cache.onExpiration != nil {
-					cache.onExpiration(entry.key, entry.value)
-				}
-			}
+		entry := cache.expiry.next(cache.clock.Now().Add(-cache.staleWindow))
+		if entry == nil {
+			cache.unlock()
+			return removed
 		}
 
-		cache.mutex.Unlock()
+		cache.deleteEntry(entry)
+		removed++
+		cache.expiries++
+		cache.wasted(entry)
+		cache.notify(cache.onExpiration, entry, Expired)
+
+		cache.unlock()
 	}
 }
 
+func (cache *Cache[K, V]) expired(entry *cacheEntry[K, V]) bool {
+	maxAge := cache.lifetime(entry)
+	return maxAge > 0 && cache.clock.Now().Sub(entry.timestamp) > maxAge
+}
+
+// lifetime returns the effective max age of the entry, or zero if it does
+// not expire.
+func (cache *Cache[K, V]) lifetime(entry *cacheEntry[K, V]) time.Duration {
+	maxAge := cache.maxAge
+	if entry.ttl > 0 {
+		maxAge = entry.ttl
+	}
+
+	if cache.ageScale < 1 {
+		maxAge = time.Duration(float64(maxAge) * cache.ageScale)
+	}
+
+	return maxAge
+}
+
 func (cache *Cache[K, V]) evictOldest() bool {
-	element := cache.evictionList.Back()
-	if element == nil {
+	entry := cache.victim()
+	if entry == nil {
 		return false
 	}
 
 	cache.evictions++
-	entry := cache.deleteElement(element)
-	if cache.onEviction != nil {
-		cache.onEviction(entry.key, entry.value)
+	cache.wasted(entry)
+	cache.policy.evict(entry)
+	cache.unindex(entry)
+	cache.notify(cache.onEviction, entry, Evicted)
+	if cache.onEvictionGroup != nil {
+		cache.coalesce(entry)
 	}
 	return true
 }
 
-func (cache *Cache[K, V]) deleteElement(element *list.Element) *cacheEntry[K, V] {
-	cache.evictionList.Remove(element)
-	entry := element.Value.(*cacheEntry[K, V])
+// victim returns the entry to evict next, passing over those inserted within
+// the InsertionWindow unless every entry was. Takes time proportional to the
+// number of entries passed over.
+func (cache *Cache[K, V]) victim() *cacheEntry[K, V] {
+	victim := cache.policy.victim()
+	if cache.insertionWindow == 0 || victim == nil {
+		return victim
+	}
+
+	now := cache.clock.Now()
+	if now.Sub(victim.inserted) >= cache.insertionWindow {
+		return victim
+	}
+
+	var settled *cacheEntry[K, V]
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		if now.Sub(entry.inserted) >= cache.insertionWindow {
+			settled = entry
+			return false
+		}
+		return true
+	})
+	if settled == nil {
+		return victim
+	}
+	return settled
+}
+
+// notify queues the callback, and OnRemoval with the reason, to be invoked
+// with the entry once the mutex is released, so that callbacks may safely call
+// back into the cache. As the entry has left the cache or been replaced, the
+// items depending on it are invalidated.
+func (cache *Cache[K, V]) notify(callback func(key K, value V), entry *cacheEntry[K, V], reason RemovalReason) {
+	switch reason {
+	case Evicted:
+		cache.emitEntry(EventEviction, entry)
+	case Expired:
+		cache.emitEntry(EventExpiration, entry)
+	}
+
+	var listeners []*Listener[K, V]
+	if reason == Evicted || reason == Expired {
+		listeners = cache.listeners
+	}
+
+	if callback != nil || cache.onRemoval != nil || cache.removalHook != nil || len(listeners) > 0 {
+		cache.pending = append(cache.pending, notification[K, V]{
+			callback:  callback,
+			removal:   cache.onRemoval,
+			hook:      cache.removalHook,
+			key:       entry.key,
+			value:     entry.value,
+			reason:    reason,
+			listeners: listeners,
+		})
+	}
+
+	cache.invalidate(entry.key)
+}
+
+// notifyHit queues OnHit to be invoked with the entry found by Get.
+func (cache *Cache[K, V]) notifyHit(entry *cacheEntry[K, V]) {
+	cache.emit(EventHit, entry.key, entry.value)
+	if cache.onHit != nil {
+		cache.pending = append(cache.pending, notification[K, V]{
+			callback: cache.onHit,
+			key:      entry.key,
+			value:    entry.value,
+		})
+	}
+}
+
+// notifySet queues OnSet to be invoked with the stored entry.
+func (cache *Cache[K, V]) notifySet(entry *cacheEntry[K, V], replaced bool) {
+	cache.emit(EventSet, entry.key, entry.value)
+	if cache.onSet != nil {
+		cache.pending = append(cache.pending, notification[K, V]{
+			set:      cache.onSet,
+			key:      entry.key,
+			value:    entry.value,
+			replaced: replaced,
+		})
+	}
+}
+
+// unlock releases the mutex and then invokes any callbacks queued while it
+// was held, or dispatches them to the CallbackWorkers.
+func (cache *Cache[K, V]) unlock() {
+	pending := cache.pending
+	cache.pending = nil
+
+	var coalesced map[string]map[K]V
+	if cache.evictionWindow == 0 {
+		coalesced = cache.coalesced
+		cache.coalesced = nil
+	}
+	cache.mutex.Unlock()
+
+	if len(pending) == 0 && len(coalesced) == 0 {
+		return
+	}
+
+	batch := func() {
+		for _, n := range pending {
+			if n.callback != nil {
+				n.callback(n.key, n.value)
+			}
+			if n.removal != nil {
+				n.removal(n.key, n.value, n.reason)
+			}
+			if n.hook != nil {
+				n.hook(n.key, n.value, n.reason)
+			}
+			if n.set != nil {
+				n.set(n.key, n.value, n.replaced)
+			}
+			notifyListeners(n.listeners, n.key, n.value, n.reason)
+			for _, s := range n.subscribers {
+				s.fn(Event[K, V]{Type: n.event, Key: n.key, Value: n.value, Time: n.at, Times: n.times})
+			}
+		}
+		for group, evicted := range coalesced {
+			cache.onEvictionGroup(group, evicted)
+		}
+	}
+
+	if cache.dispatcher == nil || !cache.dispatcher.dispatch(batch) {
+		batch()
+	}
+}
+
+func (cache *Cache[K, V]) deleteEntry(entry *cacheEntry[K, V]) {
+	cache.policy.remove(entry)
+	cache.unindex(entry)
+}
+
+// unindex drops the entry from the cache once the policy has stopped
+// tracking it.
+func (cache *Cache[K, V]) unindex(entry *cacheEntry[K, V]) {
 	delete(cache.items, entry.key)
-	return entry
+	delete(cache.bucket(entry), entry.key)
+	if cache.paths != nil {
+		cache.paths.remove(entry.key)
+	}
+	cache.totalCost -= entry.cost
+	cache.untag(entry)
+	cache.unlabel(entry)
+	cache.unlink(entry)
+	cache.unalias(entry)
+	cache.expiry.unschedule(entry)
+}
+
+// tag adds the entry to the cohort.
+func (cache *Cache[K, V]) tag(entry *cacheEntry[K, V], cohort Cohort) {
+	keys, ok := cache.cohorts[cohort]
+	if !ok {
+		keys = make(map[K]struct{})
+		cache.cohorts[cohort] = keys
+	}
+
+	keys[entry.key] = struct{}{}
+	entry.cohort = cohort
+}
+
+// untag removes the entry from its cohort, if any.
+func (cache *Cache[K, V]) untag(entry *cacheEntry[K, V]) {
+	if entry.cohort == 0 {
+		return
+	}
+
+	keys := cache.cohorts[entry.cohort]
+	delete(keys, entry.key)
+	if len(keys) == 0 {
+		delete(cache.cohorts, entry.cohort)
+	}
+	entry.cohort = 0
+}
+
+// bucket returns the scan bucket holding the entry's key.
+func (cache *Cache[K, V]) bucket(entry *cacheEntry[K, V]) map[K]struct{} {
+	i := entry.seq % uint64(len(cache.buckets))
+	if cache.buckets[i] == nil {
+		cache.buckets[i] = make(map[K]struct{})
+	}
+	return cache.buckets[i]
+}
+
+// accessed records the access of the entry, if tracking its times.
+func (cache *Cache[K, V]) accessed(entry *cacheEntry[K, V]) {
+	if entry.times != nil {
+		entry.times.Accessed = cache.clock.Now()
+	}
+}
+
+// trackedTimes returns a copy of the times of the entry, or zero times if they
+// are not tracked.
+func (entry *cacheEntry[K, V]) trackedTimes() EntryTimes {
+	if entry.times == nil {
+		return EntryTimes{}
+	}
+	return *entry.times
+}
+
+// refresh resets the age of the entry, preserving any per-entry ttl.
+func (cache *Cache[K, V]) refresh(entry *cacheEntry[K, V]) {
+	if entry.ttl > 0 {
+		entry.timestamp = cache.clock.Now()
+	} else {
+		entry.timestamp = cache.getTimestamp()
+	}
+	cache.schedule(entry)
+}
+
+// scanBuckets returns the number of scan buckets for a cache of the given
+// capacity.
+func scanBuckets(capacity int) int {
+	n := capacity / scanBucketSize
+	if n < 1 {
+		return 1
+	} else if n > 1<<16 {
+		return 1 << 16
+	}
+	return n
+}
+
+// bypassed returns whether the key must not be cached.
+func (cache *Cache[K, V]) bypassed(key K) bool {
+	return cache.bypass != nil && cache.bypass(key)
 }
 
 func (cache *Cache[K, V]) getTimestamp() time.Time {
-	timestamp := time.Now()
-	if cache.minAge == cache.maxAge {
-		return timestamp
+	timestamp := cache.clock.Now()
+
+	jitter := cache.maxAge - cache.minAge + cache.extraJitter
+	if jitter > cache.maxAge {
+		// The min age was lowered below the extra jitter by SetMinAge, which
+		// must not leave items stored already expired
+		jitter = cache.maxAge
+	}
+	if jitter > 0 {
+		randVal := cache.rand.Int63n(jitter.Nanoseconds())
+		timestamp = timestamp.Add(time.Duration(-randVal))
 	}
 
-	jitter := cache.maxAge - cache.minAge
-	randVal := cache.rand.Int63n(jitter.Nanoseconds())
+	if cache.adaptiveJitter > 0 {
+		cache.adapt(timestamp)
+	}
 
-	return timestamp.Add(time.Duration(-randVal))
+	return timestamp
 }
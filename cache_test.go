@@ -1,7 +1,13 @@
 package agecache
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,6 +20,12 @@ func TestInvalidCapacity(t *testing.T) {
 	})
 }
 
+func TestInvalidMaxCost(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, MaxCost: -1})
+	})
+}
+
 func TestInvalidMaxAge(t *testing.T) {
 	assert.Panics(t, func() {
 		New(Config[string, int]{Capacity: 1, MaxAge: -1 * time.Hour})
@@ -83,6 +95,110 @@ func TestEviction(t *testing.T) {
 	assert.Equal(t, 1, v)
 }
 
+func TestInsertionWindow(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 3, InsertionWindow: time.Second, Clock: clock})
+
+	cache.Set("a", 1)
+	clock.Advance(2 * time.Second)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Get("a")
+
+	// The least recently used items were just inserted, so are passed over
+	assert.True(t, cache.Set("d", 4))
+	assert.Equal(t, []string{"b", "c", "d"}, cache.OrderedKeys())
+
+	// Once every item was just inserted, the oldest is evicted
+	assert.True(t, cache.Set("e", 5))
+	assert.Equal(t, []string{"c", "d", "e"}, cache.OrderedKeys())
+}
+
+func TestReentrantCallbacks(t *testing.T) {
+	var cache *Cache[string, int]
+	cache = New(Config[string, int]{
+		Capacity: 1,
+		MaxAge:   time.Millisecond,
+		OnEviction: func(key string, value int) {
+			cache.Len()
+		},
+		OnExpiration: func(key string, value int) {
+			cache.Set(key, value+1)
+		},
+	})
+
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	assert.False(t, cache.Has("foo"))
+
+	<-time.After(time.Millisecond * 2)
+	_, ok := cache.Get("bar")
+	assert.False(t, ok)
+
+	val, ok := cache.Peek("bar")
+	assert.True(t, ok)
+	assert.Equal(t, 3, val)
+}
+
+func TestCostEviction(t *testing.T) {
+	var evicted []string
+
+	cache := New(Config[string, string]{
+		Capacity: 10,
+		MaxCost:  10,
+		Cost: func(key, value string) int64 {
+			return int64(len(value))
+		},
+		OnEviction: func(key, value string) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	assert.False(t, cache.Set("a", "1234"))
+	assert.False(t, cache.Set("b", "1234"))
+	assert.Equal(t, int64(8), cache.Stats().Cost)
+
+	assert.True(t, cache.Set("c", "1234"))
+	assert.Equal(t, []string{"a"}, evicted)
+	assert.Equal(t, int64(8), cache.Stats().Cost)
+
+	assert.True(t, cache.Set("c", "123456789"))
+	assert.Equal(t, []string{"a", "b"}, evicted)
+	assert.Equal(t, int64(9), cache.Stats().Cost)
+
+	assert.True(t, cache.Set("d", "12345678901"))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, evicted)
+	assert.Equal(t, int64(0), cache.Stats().Cost)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestSetMaxCost(t *testing.T) {
+	cache := New(Config[string, string]{
+		Capacity: 10,
+		Cost: func(key, value string) int64 {
+			return int64(len(value))
+		},
+	})
+
+	cache.Set("a", "1234")
+	cache.Set("b", "1234")
+	cache.Remove("b")
+	cache.Set("c", "1234")
+	assert.Equal(t, int64(8), cache.Stats().Cost)
+
+	err := cache.SetMaxCost(-1)
+	assert.Error(t, err)
+
+	err = cache.SetMaxCost(5)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, cache.Keys())
+	assert.Equal(t, int64(4), cache.Stats().Cost)
+
+	assert.Equal(t, 1, cache.Clear())
+	assert.Equal(t, int64(0), cache.Stats().Cost)
+	assert.Equal(t, 0, cache.Clear())
+}
+
 func TestExpiration(t *testing.T) {
 	var k string
 	var v int
@@ -111,6 +227,79 @@ func TestExpiration(t *testing.T) {
 	assert.False(t, eviction)
 }
 
+func TestSetWithTTL(t *testing.T) {
+	var expired []string
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		MaxAge:   time.Hour,
+		OnExpiration: func(key string, value int) {
+			expired = append(expired, key)
+		},
+	})
+
+	cache.SetWithTTL("foo", 1, time.Millisecond)
+	cache.SetWithTTL("bar", 2, 0)
+	cache.Set("baz", 3)
+	<-time.After(time.Millisecond * 2)
+
+	_, ok := cache.Get("foo")
+	assert.False(t, ok)
+	_, ok = cache.Get("bar")
+	assert.True(t, ok)
+	_, ok = cache.Get("baz")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"foo"}, expired)
+
+	cache.SetWithTTL("baz", 3, time.Millisecond)
+	cache.Set("baz", 4) // resets to MaxAge
+	<-time.After(time.Millisecond * 2)
+	_, ok = cache.Get("baz")
+	assert.True(t, ok)
+}
+
+func TestSetWithTTLActiveExpiration(t *testing.T) {
+	invoked := make(chan string)
+
+	cache := New(Config[string, int]{
+		Capacity:           10,
+		ExpirationType:     ActiveExpiration,
+		ExpirationInterval: time.Millisecond,
+		OnExpiration: func(key string, value int) {
+			invoked <- key
+		},
+	})
+
+	cache.Set("foo", 1)
+	cache.SetWithTTL("bar", 2, time.Millisecond)
+
+	assert.Equal(t, "bar", <-invoked)
+	assert.True(t, cache.Has("foo"))
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	cache := New(Config[string, int]{
+		Capacity:         10,
+		MaxAge:           50 * time.Millisecond,
+		ExpirationPolicy: SlidingExpiration,
+	})
+
+	cache.Set("foo", 1)
+	cache.SetWithTTL("bar", 2, 50*time.Millisecond)
+	cache.Set("baz", 3)
+
+	for i := 0; i < 3; i++ {
+		<-time.After(30 * time.Millisecond)
+		_, ok := cache.Get("foo")
+		assert.True(t, ok)
+		_, ok = cache.Get("bar")
+		assert.True(t, ok)
+	}
+
+	_, ok := cache.Get("baz")
+	assert.False(t, ok)
+}
+
 type MockRandGenerator struct {
 	startAt int64
 	incr    int64
@@ -126,6 +315,39 @@ func (mock *MockRandGenerator) Int63n(n int64) int64 {
 	return ret
 }
 
+func TestRand(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	config := Config[int, int]{Capacity: 10, MaxAge: time.Hour, MinAge: time.Minute, Clock: clock}
+
+	config.Rand = rand.New(rand.NewSource(42))
+	first := New(config)
+	config.Rand = rand.New(rand.NewSource(42))
+	second := New(config)
+
+	for i := 0; i < 10; i++ {
+		first.Set(i, i)
+		second.Set(i, i)
+
+		ttl, _ := first.TTL(i)
+		other, _ := second.TTL(i)
+		assert.Equal(t, ttl, other)
+	}
+
+	// The default generator is safe for concurrent use
+	generator := newLockedRand()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				generator.Int63n(10)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestJitter(t *testing.T) {
 	cache := New(Config[string, string]{
 		Capacity: 1,
@@ -166,6 +388,417 @@ func TestJitter(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestSetAll(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 3})
+	cache.Set("foo", 1)
+
+	cohort, evict := cache.SetAll(map[string]int{"bar": 2, "baz": 3})
+	assert.NotZero(t, cohort)
+	assert.False(t, evict)
+	assert.Equal(t, 3, cache.Len())
+
+	val, ok := cache.Get("bar")
+	assert.True(t, ok)
+	assert.Equal(t, 2, val)
+
+	next, evict := cache.SetAll(map[string]int{"qux": 4})
+	assert.NotEqual(t, cohort, next)
+	assert.True(t, evict)
+	assert.False(t, cache.Has("foo"))
+}
+
+func TestPreload(t *testing.T) {
+	cache := New(Config[string, int]{
+		Capacity: 3,
+		Preload: func() map[string]int {
+			return map[string]int{"foo": 1, "bar": 2}
+		},
+	})
+
+	assert.Equal(t, 2, cache.Len())
+	val, ok := cache.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+	assert.Equal(t, int64(2), cache.Stats().Sets)
+}
+
+func TestStatsReporter(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	cache.Set("foo", 1)
+
+	reports := make(chan [2]Stats, 10)
+	stop := cache.StartStatsReporter(10*time.Millisecond, func(stats, delta Stats) {
+		reports <- [2]Stats{stats, delta}
+	})
+	defer stop()
+
+	cache.Set("bar", 2)
+	cache.Get("foo")
+
+	var report [2]Stats
+	assert.Eventually(t, func() bool {
+		select {
+		case report = <-reports:
+		default:
+		}
+		return report[0].Sets == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), report[0].Hits)
+
+	cache.Get("foo")
+	assert.Eventually(t, func() bool {
+		report = <-reports
+		return report[1].Hits > 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(0), report[1].Sets)
+
+	stop()
+	stop()
+
+	assert.Panics(t, func() {
+		cache.StartStatsReporter(0, func(stats, delta Stats) {})
+	})
+}
+
+func TestStatsReporterClose(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+
+	var reports int32
+	cache.StartStatsReporter(time.Millisecond, func(stats, delta Stats) {
+		atomic.AddInt32(&reports, 1)
+	})
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&reports) > 0
+	}, time.Second, time.Millisecond)
+
+	cache.Close()
+	<-time.After(5 * time.Millisecond)
+	n := atomic.LoadInt32(&reports)
+	<-time.After(20 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&reports))
+}
+
+func TestItemsValues(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	assert.Empty(t, cache.Items())
+	assert.Empty(t, cache.Values())
+
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	cache.Get("foo")
+
+	items := cache.Items()
+	assert.Equal(t, map[string]int{"foo": 1, "bar": 2}, items)
+	assert.Equal(t, []int{2, 1}, cache.Values())
+
+	items["baz"] = 3
+	assert.False(t, cache.Has("baz"))
+}
+
+func TestRange(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	cache.Set("baz", 3)
+	cache.Get("foo")
+
+	var keys []string
+	sum := 0
+	cache.Range(func(key string, value int) bool {
+		keys = append(keys, key)
+		sum += value
+		return true
+	})
+	assert.Equal(t, []string{"bar", "baz", "foo"}, keys)
+	assert.Equal(t, 6, sum)
+
+	keys = nil
+	cache.Range(func(key string, value int) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	assert.Equal(t, []string{"bar", "baz"}, keys)
+}
+
+func TestRemoveOldest(t *testing.T) {
+	var evicted []string
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		OnEviction: func(key string, value int) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	_, _, ok := cache.RemoveOldest()
+	assert.False(t, ok)
+
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	cache.Get("foo")
+
+	key, val, ok := cache.RemoveOldest()
+	assert.True(t, ok)
+	assert.Equal(t, "bar", key)
+	assert.Equal(t, 2, val)
+	assert.Equal(t, []string{"bar"}, evicted)
+	assert.Equal(t, []string{"foo"}, cache.Keys())
+}
+
+func TestPop(t *testing.T) {
+	var removed, expired []string
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		OnRemove: func(key string, value int) {
+			removed = append(removed, key)
+		},
+		OnExpiration: func(key string, value int) {
+			expired = append(expired, key)
+		},
+	})
+
+	cache.Set("foo", 1)
+	val, ok := cache.Pop("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+	assert.False(t, cache.Has("foo"))
+	assert.Equal(t, []string{"foo"}, removed)
+
+	_, ok = cache.Pop("foo")
+	assert.False(t, ok)
+
+	cache.SetWithTTL("bar", 2, time.Millisecond)
+	<-time.After(5 * time.Millisecond)
+	_, ok = cache.Pop("bar")
+	assert.False(t, ok)
+	assert.False(t, cache.Has("bar"))
+	assert.Equal(t, []string{"bar"}, expired)
+	assert.Equal(t, []string{"foo"}, removed)
+}
+
+func TestMulti(t *testing.T) {
+	var removed []string
+
+	cache := New(Config[string, int]{
+		Capacity: 3,
+		OnRemove: func(key string, value int) {
+			removed = append(removed, key)
+		},
+	})
+
+	assert.False(t, cache.SetMulti(map[string]int{"foo": 1, "bar": 2}))
+	assert.Equal(t, map[string]int{"foo": 1, "bar": 2}, cache.GetMulti([]string{"foo", "bar", "baz"}))
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(3), stats.Gets)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+
+	assert.Equal(t, 1, cache.RemoveMulti([]string{"foo", "baz"}))
+	assert.Equal(t, []string{"foo"}, removed)
+	assert.Equal(t, 1, cache.Len())
+
+	assert.True(t, cache.SetMulti(map[string]int{"a": 1, "b": 2, "c": 3}))
+}
+
+func TestRemoveFunc(t *testing.T) {
+	var removed []string
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		OnRemove: func(key string, value int) {
+			removed = append(removed, key)
+		},
+	})
+
+	cache.SetMulti(map[string]int{"foo": 1, "bar": 2, "baz": 3, "qux": 4})
+
+	assert.Equal(t, 2, cache.RemoveFunc(func(key string, value int) bool {
+		return value%2 == 0
+	}))
+	assert.ElementsMatch(t, []string{"bar", "qux"}, removed)
+	assert.ElementsMatch(t, []string{"foo", "baz"}, cache.Keys())
+
+	assert.Equal(t, 0, cache.RemoveFunc(func(key string, value int) bool {
+		return key == "bar"
+	}))
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestExpireCohort(t *testing.T) {
+	var expired []string
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		OnExpiration: func(key string, value int) {
+			expired = append(expired, key)
+		},
+	})
+
+	cache.Set("foo", 1)
+	cohort, _ := cache.SetAll(map[string]int{"bar": 2, "baz": 3, "qux": 4})
+	other, _ := cache.SetAll(map[string]int{"quux": 5})
+	cache.Set("baz", 30)
+	cache.Remove("qux")
+
+	assert.Equal(t, 1, cache.ExpireCohort(cohort))
+	assert.Equal(t, []string{"bar"}, expired)
+	assert.Equal(t, []string{"foo", "quux", "baz"}, cache.OrderedKeys())
+
+	assert.Equal(t, 0, cache.ExpireCohort(cohort))
+	assert.Equal(t, 1, cache.ExpireCohort(other))
+	assert.Empty(t, cache.cohorts)
+}
+
+func TestBypass(t *testing.T) {
+	deny := map[string]bool{"huge": true}
+	cache := New(Config[string, int]{
+		Capacity: 4,
+		Bypass: func(key string) bool {
+			return deny[key]
+		},
+	})
+
+	cache.Set("huge", 1)
+	cache.Set("a", 2)
+	assert.False(t, cache.Has("huge"))
+	assert.Equal(t, int64(1), cache.Stats().Sets)
+
+	err := cache.TrySet("huge", 1)
+	assert.Equal(t, Bypassed, err.(*RejectedError).Reason)
+
+	loads := 0
+	for i := 0; i < 2; i++ {
+		value, err := cache.GetOrLoad(context.Background(), "huge", func(ctx context.Context, key string) (int, error) {
+			loads++
+			return 3, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, value)
+	}
+	assert.Equal(t, 2, loads)
+
+	// Updating the predicate at runtime leaves stored items in place, but
+	// reports them as misses
+	cache.SetBypass(func(key string) bool {
+		return key == "a"
+	})
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	assert.True(t, cache.Has("a"))
+
+	cache.SetBypass(nil)
+	cache.Set("huge", 1)
+	assert.True(t, cache.Has("huge"))
+}
+
+func TestGetOrLoad(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 2})
+	loader := func(ctx context.Context, key string) (int, error) {
+		return len(key), nil
+	}
+
+	val, err := cache.GetOrLoad(context.Background(), "foo", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, val)
+
+	val, ok := cache.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 3, val)
+
+	cache.Set("bar", 1)
+	val, err = cache.GetOrLoad(context.Background(), "bar", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 2})
+	loadErr := errors.New("unavailable")
+
+	_, err := cache.GetOrLoad(context.Background(), "foo", func(ctx context.Context, key string) (int, error) {
+		return 0, loadErr
+	})
+	assert.Equal(t, loadErr, err)
+	assert.False(t, cache.Has("foo"))
+}
+
+func TestGetOrLoadDeduplication(t *testing.T) {
+	var calls int32
+	cache := New(Config[string, int]{Capacity: 2})
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := cache.GetOrLoad(context.Background(), "foo", loader)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, val)
+		}()
+	}
+
+	<-time.After(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestGetOrLoadContext(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 2})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	go cache.GetOrLoad(context.Background(), "foo", func(ctx context.Context, key string) (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cache.GetOrLoad(ctx, "foo", func(ctx context.Context, key string) (int, error) {
+		return 2, nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestTouch(t *testing.T) {
+	var expired bool
+
+	cache := New(Config[string, int]{
+		Capacity: 2,
+		MaxAge:   50 * time.Millisecond,
+		OnExpiration: func(key string, value int) {
+			expired = true
+		},
+	})
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+
+	<-time.After(30 * time.Millisecond)
+	assert.True(t, cache.Touch("foo"))
+	assert.Equal(t, []string{"bar", "foo"}, cache.OrderedKeys())
+
+	<-time.After(30 * time.Millisecond)
+	_, ok := cache.Get("foo")
+	assert.True(t, ok)
+	assert.False(t, cache.Touch("bar"))
+	assert.True(t, expired)
+	assert.False(t, cache.Touch("baz"))
+}
+
 func TestHas(t *testing.T) {
 	cache := New(Config[string, string]{Capacity: 1, MaxAge: time.Millisecond})
 	cache.Set("foo", "bar")
@@ -185,14 +818,75 @@ func TestPeek(t *testing.T) {
 	assert.Equal(t, "bar", val)
 }
 
+func TestPeekEntry(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10, MaxAge: time.Hour})
+
+	_, ok := cache.PeekEntry("foo")
+	assert.False(t, ok)
+
+	start := time.Now()
+	cache.Set("foo", 1)
+	cache.SetWithTTL("bar", 2, time.Minute)
+	cache.Get("foo")
+	cache.Get("foo")
+
+	entry, ok := cache.PeekEntry("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", entry.Key)
+	assert.Equal(t, 1, entry.Value)
+	assert.False(t, entry.Timestamp.Before(start))
+	assert.Equal(t, int64(2), entry.Hits)
+	assert.True(t, entry.Remaining > 59*time.Minute && entry.Remaining <= time.Hour)
+
+	entry, ok = cache.PeekEntry("bar")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, entry.TTL)
+	assert.True(t, entry.Remaining <= time.Minute)
+	assert.Zero(t, entry.Hits)
+
+	// Peeking does not count as an access
+	assert.Equal(t, []string{"bar", "foo"}, cache.OrderedKeys())
+	assert.Equal(t, int64(2), cache.Stats().Hits)
+}
+
+func TestTTL(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10, MaxAge: time.Hour})
+	cache.Set("foo", 1)
+	cache.SetWithTTL("bar", 2, time.Millisecond)
+
+	ttl, ok := cache.TTL("foo")
+	assert.True(t, ok)
+	assert.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+
+	<-time.After(time.Millisecond * 2)
+	ttl, ok = cache.TTL("bar")
+	assert.False(t, ok)
+	assert.Zero(t, ttl)
+	assert.True(t, cache.Has("bar"))
+
+	_, ok = cache.TTL("baz")
+	assert.False(t, ok)
+
+	cache = New(Config[string, int]{Capacity: 10})
+	cache.Set("foo", 1)
+	ttl, ok = cache.TTL("foo")
+	assert.True(t, ok)
+	assert.Zero(t, ttl)
+}
+
 func TestRemove(t *testing.T) {
 	var eviction bool
+	var k, v string
 
 	cache := New(Config[string, string]{
 		Capacity: 1,
 		OnEviction: func(key, value string) {
 			eviction = true
 		},
+		OnRemove: func(key, value string) {
+			k = key
+			v = value
+		},
 	})
 
 	cache.Set("foo", "bar")
@@ -200,6 +894,8 @@ func TestRemove(t *testing.T) {
 
 	assert.True(t, ok)
 	assert.False(t, eviction)
+	assert.Equal(t, "foo", k)
+	assert.Equal(t, "bar", v)
 
 	val, ok := cache.Get("foo")
 	assert.False(t, ok)
@@ -273,6 +969,58 @@ func TestKeys(t *testing.T) {
 	assert.Equal(t, "foo", sortedKeys[1])
 }
 
+func TestScan(t *testing.T) {
+	cache := New(Config[int, int]{Capacity: 1000})
+	for i := 0; i < 1000; i++ {
+		cache.Set(i, i)
+	}
+
+	var keys []int
+	var batch []int
+	var cursor Cursor
+	batches := 0
+
+	for {
+		batch, cursor = cache.Scan(cursor, 100)
+		keys = append(keys, batch...)
+		batches++
+
+		// Remove keys which may or may not have been scanned yet
+		cache.Remove(batches)
+
+		if cursor == 0 {
+			break
+		}
+	}
+
+	assert.True(t, batches > 1)
+
+	seen := make(map[int]int)
+	for _, key := range keys {
+		seen[key]++
+	}
+	for i := 0; i < 1000; i++ {
+		if i > batches || i == 0 {
+			assert.Equal(t, 1, seen[i])
+		} else {
+			assert.True(t, seen[i] <= 1)
+		}
+	}
+}
+
+func TestScanEmpty(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	keys, cursor := cache.Scan(0, 0)
+
+	assert.Empty(t, keys)
+	assert.Equal(t, Cursor(0), cursor)
+
+	cache.Set("foo", 1)
+	keys, cursor = cache.Scan(0, 0)
+	assert.Equal(t, []string{"foo"}, keys)
+	assert.Equal(t, Cursor(0), cursor)
+}
+
 func TestOrderedKeys(t *testing.T) {
 	cache := New(Config[string, int]{Capacity: 10})
 	cache.Set("foo", 1)
@@ -285,6 +1033,143 @@ func TestOrderedKeys(t *testing.T) {
 	assert.Equal(t, "bar", keys[1])
 }
 
+func TestExport(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	before := time.Now()
+	cache.Set("foo", 1)
+	cache.SetWithTTL("bar", 2, time.Minute)
+	cache.Set("baz", 3)
+	cache.Get("foo")
+
+	entries := cache.Export()
+
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "bar", entries[0].Key)
+	assert.Equal(t, 2, entries[0].Value)
+	assert.Equal(t, time.Minute, entries[0].TTL)
+	assert.Equal(t, "baz", entries[1].Key)
+	assert.Equal(t, "foo", entries[2].Key)
+	assert.Zero(t, entries[2].TTL)
+	assert.False(t, entries[2].Timestamp.Before(before))
+}
+
+func TestOrderedEntriesInfo(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 10, MaxAge: time.Hour, Clock: clock})
+	cache.Set("foo", 1)
+	clock.Advance(time.Minute)
+	cache.SetWithTTL("bar", 2, 30*time.Second)
+	clock.Advance(time.Minute)
+	cache.Set("baz", 3)
+
+	assert.Equal(t, []EntryInfo[string]{
+		{Key: "foo", Age: 2 * time.Minute, Remaining: 58 * time.Minute},
+		{Key: "bar", Age: time.Minute},
+		{Key: "baz", Remaining: time.Hour},
+	}, cache.OrderedEntriesInfo())
+}
+
+func TestTrackTimes(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	start := clock.Now()
+	cache := New(Config[string, int]{Capacity: 2, Clock: clock, TrackTimes: true})
+
+	cache.Set("foo", 1)
+	clock.Advance(time.Minute)
+	cache.Set("foo", 2)
+	clock.Advance(time.Minute)
+	cache.Get("foo")
+	cache.Set("bar", 3)
+
+	entry, ok := cache.PeekEntry("foo")
+	assert.True(t, ok)
+	assert.Equal(t, EntryTimes{
+		Created:  start,
+		Updated:  start.Add(time.Minute),
+		Accessed: start.Add(2 * time.Minute),
+	}, entry.Times)
+
+	infos := cache.OrderedEntriesInfo()
+	assert.Equal(t, EntryTimes{Created: start.Add(2 * time.Minute), Updated: start.Add(2 * time.Minute)}, infos[1].Times)
+
+	// Times are only tracked if configured
+	untracked := New(Config[string, int]{Capacity: 2})
+	untracked.Set("foo", 1)
+	untracked.Get("foo")
+	entry, _ = untracked.PeekEntry("foo")
+	assert.Zero(t, entry.Times)
+}
+
+func TestImportFrom(t *testing.T) {
+	src := New(Config[string, int]{Capacity: 10, MaxAge: time.Hour})
+	src.Set("foo", 1)
+	src.SetWithTTL("bar", 2, time.Millisecond)
+	src.Set("baz", 3)
+	src.Set("qux", 4)
+	<-time.After(time.Millisecond * 2)
+
+	dst := New(Config[string, int]{Capacity: 2, MaxAge: time.Minute})
+	n := dst.ImportFrom(src, ImportOptions{})
+
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"baz", "qux"}, dst.OrderedKeys())
+
+	ttl, ok := dst.TTL("qux")
+	assert.True(t, ok)
+	assert.True(t, ttl > time.Minute)
+
+	n = dst.ImportFrom(src, ImportOptions{ResetAge: true})
+	assert.Equal(t, 2, n)
+	ttl, ok = dst.TTL("qux")
+	assert.True(t, ok)
+	assert.True(t, ttl <= time.Minute)
+}
+
+func TestImportFromSkipExisting(t *testing.T) {
+	src := New(Config[string, int]{Capacity: 10})
+	src.Set("foo", 1)
+	src.Set("bar", 2)
+
+	dst := New(Config[string, int]{Capacity: 10})
+	dst.Set("foo", 10)
+	n := dst.ImportFrom(src, ImportOptions{SkipExisting: true})
+
+	assert.Equal(t, 1, n)
+	val, _ := dst.Get("foo")
+	assert.Equal(t, 10, val)
+	val, _ = dst.Get("bar")
+	assert.Equal(t, 2, val)
+}
+
+func TestBurstOrdering(t *testing.T) {
+	var evicted []int
+
+	cache := New(Config[int, int]{
+		Capacity: 1000,
+		OnEviction: func(key, value int) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	expected := make([]int, 1000)
+	for i := 0; i < 1000; i++ {
+		cache.Set(i, i)
+		expected[i] = i
+	}
+
+	assert.Equal(t, expected, cache.OrderedKeys())
+
+	entries := cache.Export()
+	for i := 1; i < len(entries); i++ {
+		assert.True(t, entries[i-1].Seq < entries[i].Seq)
+	}
+
+	for i := 0; i < 10; i++ {
+		cache.EvictOldest()
+	}
+	assert.Equal(t, expected[:10], evicted)
+}
+
 func TestSetMaxAge(t *testing.T) {
 	cache := New(Config[string, int]{Capacity: 10})
 	err := cache.SetMaxAge(-1 * time.Hour)
@@ -303,6 +1188,49 @@ func TestSetMinAge(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestScaleMaxAge(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10, MaxAge: 100 * time.Millisecond})
+	err := cache.ScaleMaxAge(0)
+	assert.Error(t, err)
+
+	err = cache.ScaleMaxAge(1.5)
+	assert.Error(t, err)
+
+	cache.Set("foo", 1)
+	<-time.After(60 * time.Millisecond)
+	_, ok := cache.Get("foo")
+	assert.True(t, ok)
+
+	err = cache.ScaleMaxAge(0.5)
+	assert.NoError(t, err)
+	_, ok = cache.Get("foo")
+	assert.False(t, ok)
+
+	err = cache.ScaleMaxAge(1)
+	assert.NoError(t, err)
+}
+
+func TestRemoveExpired(t *testing.T) {
+	var expirations int
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		MaxAge:   time.Millisecond,
+		OnExpiration: func(key string, value int) {
+			expirations++
+		},
+	})
+
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	<-time.After(time.Millisecond * 2)
+	cache.Set("baz", 3)
+
+	assert.Equal(t, 2, cache.RemoveExpired())
+	assert.Equal(t, 2, expirations)
+	assert.Equal(t, 1, cache.Len())
+}
+
 func TestOnEviction(t *testing.T) {
 	var eviction bool
 
@@ -335,6 +1263,101 @@ func TestOnExpiration(t *testing.T) {
 	assert.True(t, expiration)
 }
 
+func TestOnRemove(t *testing.T) {
+	var removals int
+
+	cache := New(Config[string, int]{Capacity: 1})
+	cache.OnRemove(func(key string, value int) {
+		removals++
+	})
+
+	cache.Set("foo", 1)
+	cache.Remove("foo")
+	cache.Remove("foo")
+
+	assert.Equal(t, 1, removals)
+}
+
+func TestOnRemoval(t *testing.T) {
+	type removal struct {
+		key    string
+		value  int
+		reason RemovalReason
+	}
+	var removals []removal
+
+	cache := New(Config[string, int]{
+		Capacity: 2,
+		MaxAge:   time.Hour,
+		OnRemoval: func(key string, value int, reason RemovalReason) {
+			removals = append(removals, removal{key, value, reason})
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("a", 2)
+	cache.Set("b", 3)
+	cache.Set("c", 4)
+	cache.Remove("b")
+	cache.SetWithTTL("d", 5, time.Nanosecond)
+	<-time.After(time.Millisecond)
+	cache.Get("d")
+	cache.Clear()
+
+	assert.Equal(t, []removal{
+		{"a", 1, Replaced},
+		{"a", 2, Evicted},
+		{"b", 3, Removed},
+		{"d", 5, Expired},
+		{"c", 4, Cleared},
+	}, removals)
+	assert.Equal(t, "replaced", Replaced.String())
+
+	removals = nil
+	cache.OnRemoval(nil)
+	cache.Set("e", 6)
+	cache.Remove("e")
+	assert.Empty(t, removals)
+}
+
+func TestOnSetAndOnHit(t *testing.T) {
+	var sets []string
+	var hits []string
+
+	var cache *Cache[string, int]
+	cache = New(Config[string, int]{
+		Capacity: 2,
+		OnSet: func(key string, value int, replaced bool) {
+			if replaced {
+				key += " replaced"
+			}
+			sets = append(sets, key)
+		},
+		OnHit: func(key string, value int) {
+			hits = append(hits, key)
+			// Callbacks may call back into the cache
+			cache.Peek(key)
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("a", 2)
+	cache.SetAll(map[string]int{"b": 3})
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Peek("b")
+
+	assert.Equal(t, []string{"a", "a replaced", "b"}, sets)
+	assert.Equal(t, []string{"a"}, hits)
+
+	cache.OnSet(nil)
+	cache.OnHit(nil)
+	cache.Set("c", 4)
+	cache.Get("c")
+	assert.Len(t, sets, 3)
+	assert.Len(t, hits, 1)
+}
+
 func TestActiveExpiration(t *testing.T) {
 	invoked := make(chan bool)
 
@@ -356,6 +1379,57 @@ func TestActiveExpiration(t *testing.T) {
 	assert.True(t, duration < time.Millisecond*2)
 }
 
+func TestClose(t *testing.T) {
+	expirations := 0
+
+	cache := New(Config[string, int]{
+		Capacity:       1,
+		MaxAge:         time.Millisecond,
+		ExpirationType: ActiveExpiration,
+		OnExpiration: func(key string, value int) {
+			expirations++
+		},
+	})
+
+	assert.NoError(t, cache.Close())
+	assert.NoError(t, cache.Close())
+
+	cache.Set("foo", 1)
+	<-time.After(10 * time.Millisecond)
+	assert.True(t, cache.Has("foo"))
+
+	// Expiration is still enforced on access
+	_, ok := cache.Get("foo")
+	assert.False(t, ok)
+	assert.Equal(t, 1, expirations)
+}
+
+func TestNewWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cache := NewWithContext(ctx, Config[string, int]{
+		Capacity:       1,
+		MaxAge:         time.Millisecond,
+		ExpirationType: ActiveExpiration,
+	})
+
+	cache.Set("foo", 1)
+	<-time.After(10 * time.Millisecond)
+	assert.False(t, cache.Has("foo"))
+
+	cancel()
+	select {
+	case <-cache.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected cancelling the context to close the cache")
+	}
+	<-time.After(5 * time.Millisecond)
+
+	cache.Set("foo", 1)
+	<-time.After(10 * time.Millisecond)
+	assert.True(t, cache.Has("foo"))
+}
+
 func TestResize(t *testing.T) {
 	cache := New(Config[string, int]{
 		Capacity: 2,
@@ -385,6 +1459,11 @@ func TestResize(t *testing.T) {
 	assert.False(t, cache.Has("b"))
 	assert.True(t, cache.Has("c"))
 	assert.True(t, cache.Has("d"))
+
+	cache.Resize(10)
+	cache.Resize(5) // fewer items than either capacity
+	assert.True(t, cache.Has("c"))
+	assert.True(t, cache.Has("d"))
 }
 
 func TestStats(t *testing.T) {
@@ -438,6 +1517,23 @@ func TestStats(t *testing.T) {
 		assert.Equal(t, int64(9), cache.Stats().Evictions)
 	})
 
+	t.Run("increments expirations", func(t *testing.T) {
+		cache := New(Config[string, int]{Capacity: 100, MaxAge: 10 * time.Millisecond})
+		cache.Set("passive", 1)
+		cache.Set("active", 2)
+		<-time.After(20 * time.Millisecond)
+
+		cache.Get("passive")
+		assert.Equal(t, int64(1), cache.Stats().Expirations)
+		assert.Equal(t, int64(1), cache.Stats().Misses)
+
+		cache.RemoveExpired()
+		stats := cache.Stats()
+		assert.Equal(t, int64(2), stats.Expirations)
+		assert.Equal(t, int64(1), stats.Delta(Stats{Expirations: 1}).Expirations)
+		assert.Equal(t, int64(0), stats.Evictions)
+	})
+
 	t.Run("delta stats", func(t *testing.T) {
 		cache := New(Config[string, string]{Capacity: 100, MaxAge: time.Second})
 		cache.Set("a", "1")
@@ -461,6 +1557,29 @@ func TestStats(t *testing.T) {
 		}
 	})
 
+	t.Run("ratios", func(t *testing.T) {
+		assert.Equal(t, 0.0, Stats{}.HitRatio())
+		assert.Equal(t, 0.0, Stats{}.Utilization())
+
+		cache := New(Config[string, string]{Capacity: 4})
+		cache.Set("a", "1")
+		cache.Get("a")
+		cache.Get("a")
+		cache.Get("a")
+		cache.Get("b")
+
+		stats := cache.Stats()
+		assert.Equal(t, 0.75, stats.HitRatio())
+		assert.Equal(t, 0.25, stats.Utilization())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(Stats{Capacity: 4, Hits: 1})
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"capacity":4`)
+		assert.Contains(t, string(data), `"hits":1`)
+	})
+
 	t.Run("copy", func(t *testing.T) {
 		cache := New(Config[string, string]{Capacity: 100, MaxAge: time.Second})
 		stats := cache.Stats()
@@ -481,3 +1600,39 @@ func BenchmarkCache(b *testing.B) {
 		}
 	})
 }
+
+type smallValue struct {
+	id    int32
+	flags uint16
+}
+
+func BenchmarkSetSmallValue(b *testing.B) {
+	cache := New(Config[int, smallValue]{Capacity: 1024})
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		cache.Set(i%2048, smallValue{int32(i), 1})
+	}
+}
+
+func BenchmarkGetSmallValue(b *testing.B) {
+	cache := New(Config[int, smallValue]{Capacity: 1024})
+	for i := 0; i < 1024; i++ {
+		cache.Set(i, smallValue{int32(i), 1})
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		cache.Get(i % 1024)
+	}
+}
+
+func BenchmarkSetInt(b *testing.B) {
+	cache := New(Config[int, int]{Capacity: 1024})
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		cache.Set(i%2048, i)
+	}
+}
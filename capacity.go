@@ -0,0 +1,66 @@
+package agecache
+
+import "time"
+
+const defaultCapacityInterval = time.Minute
+
+// tuneEvery adjusts the capacity of the cache every interval, within its
+// bounds, until it is closed.
+func (cache *Cache[K, V]) tuneEvery(interval time.Duration) {
+	ticker := cache.clock.NewTicker(interval)
+	previous := cache.Stats()
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.Chan():
+				previous = cache.tune(previous)
+			case <-cache.done:
+				return
+			}
+		}
+	}()
+}
+
+// tune adjusts the capacity of the cache by a tenth given its Stats since
+// previous, returning its current Stats. The capacity grows while items are
+// evicted and requests miss, suggesting that the working set does not fit,
+// and shrinks while a tenth of it was left unused without any eviction.
+// Shrinking never evicts an item, growing stops at the capacity last set by
+// Resize, and a capacity which was Resized beyond the bounds is only adjusted
+// towards them.
+func (cache *Cache[K, V]) tune(previous Stats) Stats {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	current := cache.stats()
+	window := current.Delta(previous)
+
+	step := cache.capacity / 10
+	if step < 1 {
+		step = 1
+	}
+
+	limit := cache.maxCapacity
+	if cache.resizedCapacity > 0 && cache.resizedCapacity < limit {
+		limit = cache.resizedCapacity
+	}
+
+	switch {
+	case window.Evictions > 0 && window.Misses > 0 && cache.capacity < limit:
+		n := cache.capacity + step
+		if n > limit {
+			n = limit
+		}
+		cache.resize(n)
+	case window.Evictions == 0 && len(cache.items)+step <= cache.capacity && cache.capacity > cache.minCapacity:
+		n := cache.capacity - step
+		if n < cache.minCapacity {
+			n = cache.minCapacity
+		}
+		cache.resize(n)
+	}
+
+	return current
+}
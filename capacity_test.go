@@ -0,0 +1,118 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuneCapacity(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[int, int]{Capacity: 10, MinCapacity: 5, MaxCapacity: 12, Clock: clock})
+
+	// Grows while items are evicted and requests miss
+	stats := cache.Stats()
+	for key := 0; key < 15; key++ {
+		cache.Set(key, key)
+	}
+	cache.Get(0)
+	stats = cache.tune(stats)
+	assert.Equal(t, int64(11), cache.Stats().Capacity)
+
+	// Evictions alone do not grow the cache
+	cache.Set(15, 15)
+	cache.Set(16, 16)
+	stats = cache.tune(stats)
+	assert.Equal(t, int64(11), cache.Stats().Capacity)
+
+	cache.Set(17, 17)
+	cache.Get(0)
+	stats = cache.tune(stats)
+	cache.Set(18, 18)
+	cache.Get(0)
+	stats = cache.tune(stats)
+	assert.Equal(t, int64(12), cache.Stats().Capacity)
+
+	// Shrinks within the room left unused, without evicting
+	for key := 7; key < 13; key++ {
+		cache.Remove(key)
+	}
+	assert.Equal(t, 6, cache.Len())
+	for i := 0; i < 10; i++ {
+		stats = cache.tune(stats)
+	}
+	assert.Equal(t, int64(6), cache.Stats().Capacity)
+	assert.Equal(t, 6, cache.Len())
+
+	// Down to the MinCapacity
+	cache.Clear()
+	for i := 0; i < 10; i++ {
+		stats = cache.tune(stats)
+	}
+	assert.Equal(t, int64(5), cache.Stats().Capacity)
+}
+
+func TestTuneCapacityInterval(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[int, int]{Capacity: 10, MaxCapacity: 20, CapacityInterval: time.Second, Clock: clock})
+	defer cache.Close()
+
+	for key := 0; key < 20; key++ {
+		cache.Set(key, key)
+		cache.Get(-key)
+	}
+
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool {
+		return cache.Stats().Capacity == 11
+	}, time.Second, time.Millisecond)
+}
+
+func TestShardedTuneCapacity(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 40, MinCapacity: 20, MaxCapacity: 80},
+		Shards: 4,
+	})
+	defer cache.Close()
+
+	for _, shard := range cache.shards {
+		assert.Equal(t, 5, shard.minCapacity)
+		assert.Equal(t, 20, shard.maxCapacity)
+	}
+}
+
+func TestTuneCapacityCoordinated(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[int, int]{Capacity: 10, MaxCapacity: 40, CapacityInterval: time.Second, Clock: clock})
+	defer cache.Close()
+	other := New(Config[int, int]{Capacity: 30})
+
+	coordinator := NewCoordinator(20)
+	assert.NoError(t, coordinator.Register(cache))
+	assert.NoError(t, coordinator.Register(other))
+	assert.Equal(t, int64(5), cache.Stats().Capacity)
+
+	// Evictions and misses do not grow the cache past its share
+	stats := cache.Stats()
+	for i := 0; i < 10; i++ {
+		for key := 0; key < 20; key++ {
+			cache.Set(key, key)
+			cache.Get(-key)
+		}
+		clock.Advance(time.Second)
+		stats = cache.tune(stats)
+		assert.Equal(t, int64(5), cache.Stats().Capacity)
+	}
+
+	// Up to the capacity it is entitled to once the budget allows
+	coordinator.Unregister(other)
+	for i := 0; i < 10; i++ {
+		for key := 0; key < 20; key++ {
+			cache.Set(key, key)
+			cache.Get(-key)
+		}
+		stats = cache.tune(stats)
+	}
+	assert.Equal(t, int64(10), cache.Stats().Capacity)
+}
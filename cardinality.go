@@ -0,0 +1,124 @@
+package agecache
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Precision of the hyperLogLog, whose standard error is 1.04/sqrt(2^p), or
+// about 1.6%
+const (
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog estimates the number of distinct hashes added to it.
+type hyperLogLog [hllRegisters]uint8
+
+func (hll *hyperLogLog) add(hash uint64) {
+	index := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > hll[index] {
+		hll[index] = rank
+	}
+}
+
+// merge sets each register to the greater of those of hll and other, so that
+// hll estimates the union of both.
+func (hll *hyperLogLog) merge(other *hyperLogLog) {
+	for i, rank := range other {
+		if rank > hll[i] {
+			hll[i] = rank
+		}
+	}
+}
+
+func (hll *hyperLogLog) estimate() int64 {
+	const m = float64(hllRegisters)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	sum := 0.0
+	zeros := 0
+	for _, rank := range hll {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(estimate + 0.5)
+}
+
+// cardinality estimates the number of distinct keys requested within the
+// current and previous windows.
+type cardinality struct {
+	window   time.Duration
+	start    time.Time // Start of the current window
+	current  hyperLogLog
+	previous hyperLogLog
+}
+
+func newCardinality(window time.Duration, now time.Time) *cardinality {
+	if window <= 0 {
+		return nil
+	}
+	return &cardinality{window: window, start: now}
+}
+
+// record notes a request of the key with the given hash.
+func (c *cardinality) record(hash uint64, now time.Time) {
+	if elapsed := now.Sub(c.start); elapsed >= 2*c.window {
+		c.previous = hyperLogLog{}
+		c.current = hyperLogLog{}
+		c.start = now
+	} else if elapsed >= c.window {
+		c.previous = c.current
+		c.current = hyperLogLog{}
+		c.start = c.start.Add(c.window)
+	}
+
+	c.current.add(mix(hash))
+}
+
+// mix applies the finalizer of MurmurHash3 to the hash of a key, as neither
+// the FNV-1a hashes of strings nor the hashes of integers are uniform enough
+// to estimate their cardinality.
+func mix(hash uint64) uint64 {
+	hash ^= hash >> 33
+	hash *= 0xff51afd7ed558ccd
+	hash ^= hash >> 33
+	hash *= 0xc4ceb9fe1a85ec53
+	hash ^= hash >> 33
+	return hash
+}
+
+// estimate returns the number of distinct keys requested within the last one
+// to two windows, as of now.
+func (c *cardinality) estimate(now time.Time) int64 {
+	elapsed := now.Sub(c.start)
+	switch {
+	case elapsed >= 2*c.window:
+		return 0
+	case elapsed >= c.window:
+		return c.current.estimate()
+	}
+
+	union := c.previous
+	union.merge(&c.current)
+	return union.estimate()
+}
+
+// cardinality returns the estimated number of distinct keys requested with
+// the mutex held, or zero without a CardinalityWindow.
+func (cache *Cache[K, V]) cardinality() int64 {
+	if cache.requested == nil {
+		return 0
+	}
+	return cache.requested.estimate(cache.clock.Now())
+}
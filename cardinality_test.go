@@ -0,0 +1,44 @@
+package agecache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10000, 200000} {
+		var hll hyperLogLog
+		for i := 0; i < n; i++ {
+			hll.add(mix(hashKey(strconv.Itoa(i))))
+			hll.add(mix(hashKey(strconv.Itoa(i))))
+		}
+		assert.InEpsilon(t, float64(n)+1, float64(hll.estimate())+1, 0.05, "n = %d", n)
+	}
+}
+
+func TestCardinalityWindow(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[int, int]{Capacity: 10, CardinalityWindow: time.Minute, Clock: clock})
+
+	// Requests of missing keys are counted
+	for key := 0; key < 1000; key++ {
+		cache.Get(key % 500)
+	}
+	assert.InEpsilon(t, 500, cache.Stats().Cardinality, 0.05)
+
+	// The previous window is counted until the next ends
+	clock.Advance(time.Minute)
+	for key := 250; key < 750; key++ {
+		cache.Get(key)
+	}
+	assert.InEpsilon(t, 750, cache.Stats().Cardinality, 0.05)
+
+	clock.Advance(time.Minute)
+	assert.InEpsilon(t, 500, cache.Stats().Cardinality, 0.05)
+
+	clock.Advance(time.Minute)
+	assert.Zero(t, cache.Stats().Cardinality)
+}
@@ -0,0 +1,184 @@
+package agecache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of the time measured by a cache, configured with
+// Config.Clock. It is used to timestamp and expire items, to tick active
+// expiration, periodic persistence and stats reports, and to time the
+// delivery of coalesced evictions.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTicker returns a Ticker delivering the time every d
+	NewTicker(d time.Duration) Ticker
+	// AfterFunc invokes f once d has elapsed, as with time.AfterFunc
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a call scheduled by Clock.AfterFunc, as with a time.Timer.
+type Timer interface {
+	// Stop prevents the call, returning false if it was already made or
+	// stopped
+	Stop() bool
+}
+
+// Ticker delivers ticks of a Clock, as with a time.Ticker.
+type Ticker interface {
+	// Chan returns the channel on which the ticks are delivered
+	Chan() <-chan time.Time
+	// Stop turns off the ticker
+	Stop()
+}
+
+// systemClock is the default Clock, reading the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (ticker systemTicker) Chan() <-chan time.Time {
+	return ticker.C
+}
+
+// ManualClock is a Clock which only moves when advanced, so that tests can
+// expire items deterministically rather than sleeping. It is safe for
+// concurrent use.
+type ManualClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers map[*manualTicker]struct{}
+	timers  map[*manualTimer]struct{}
+}
+
+// NewManualClock constructs a ManualClock reading the given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{
+		now:     now,
+		tickers: make(map[*manualTicker]struct{}),
+		timers:  make(map[*manualTimer]struct{}),
+	}
+}
+
+// Now returns the time of the clock.
+func (clock *ManualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// NewTicker returns a Ticker delivering the time of the clock whenever it is
+// advanced past a multiple of d. As with a time.Ticker, ticks are dropped
+// while the previous tick is unread.
+func (clock *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("Must supply a positive duration to NewTicker")
+	}
+
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	ticker := &manualTicker{
+		clock:  clock,
+		period: d,
+		next:   clock.now.Add(d),
+		c:      make(chan time.Time, 1),
+	}
+	clock.tickers[ticker] = struct{}{}
+	return ticker
+}
+
+// AfterFunc returns a Timer invoking f once the clock is advanced by d. Unlike
+// with time.AfterFunc, f is invoked by Advance, in its goroutine.
+func (clock *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	timer := &manualTimer{clock: clock, when: clock.now.Add(d), f: f}
+	clock.timers[timer] = struct{}{}
+	return timer
+}
+
+// Advance moves the clock forward by d, delivering the ticks due meanwhile,
+// and then invoking the functions of the timers due, in order.
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+
+	clock.now = clock.now.Add(d)
+	for ticker := range clock.tickers {
+		for !ticker.next.After(clock.now) {
+			select {
+			case ticker.c <- ticker.next:
+			default:
+			}
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+
+	var due []*manualTimer
+	for timer := range clock.timers {
+		if !timer.when.After(clock.now) {
+			due = append(due, timer)
+			delete(clock.timers, timer)
+		}
+	}
+	clock.mutex.Unlock()
+
+	// Invoked without the lock, as they may read the clock
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].when.Before(due[j].when)
+	})
+	for _, timer := range due {
+		timer.f()
+	}
+}
+
+type manualTicker struct {
+	clock  *ManualClock
+	period time.Duration
+	next   time.Time // Time of the next tick
+	c      chan time.Time
+}
+
+func (ticker *manualTicker) Chan() <-chan time.Time {
+	return ticker.c
+}
+
+func (ticker *manualTicker) Stop() {
+	ticker.clock.mutex.Lock()
+	defer ticker.clock.mutex.Unlock()
+
+	delete(ticker.clock.tickers, ticker)
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	when  time.Time
+	f     func()
+}
+
+func (timer *manualTimer) Stop() bool {
+	timer.clock.mutex.Lock()
+	defer timer.clock.mutex.Unlock()
+
+	_, ok := timer.clock.timers[timer]
+	delete(timer.clock.timers, timer)
+	return ok
+}
@@ -0,0 +1,117 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	assert.Equal(t, start, clock.Now())
+
+	ticker := clock.NewTicker(time.Second)
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.Chan():
+		t.Fatal("ticked early")
+	default:
+	}
+
+	// Ticks are dropped while the previous tick is unread
+	clock.Advance(3 * time.Second)
+	assert.Equal(t, start.Add(time.Second), <-ticker.Chan())
+	select {
+	case <-ticker.Chan():
+		t.Fatal("ticks were not dropped")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(4*time.Second), <-ticker.Chan())
+
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	select {
+	case <-ticker.Chan():
+		t.Fatal("ticked once stopped")
+	default:
+	}
+
+	assert.Equal(t, start.Add(time.Minute+4500*time.Millisecond), clock.Now())
+}
+
+func TestManualClockAfterFunc(t *testing.T) {
+	clock := NewManualClock(time.Now())
+
+	var calls []string
+	clock.AfterFunc(2*time.Second, func() { calls = append(calls, "b") })
+	clock.AfterFunc(time.Second, func() { calls = append(calls, "a") })
+	stopped := clock.AfterFunc(time.Second, func() { calls = append(calls, "stopped") })
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Empty(t, calls)
+
+	// Due timers are invoked once each, in order
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, []string{"a", "b"}, calls)
+	clock.Advance(time.Minute)
+	assert.Equal(t, []string{"a", "b"}, calls)
+}
+
+func TestClockStatsReporter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 10, Clock: clock})
+
+	reports := make(chan Stats, 1)
+	stop := cache.StartStatsReporter(time.Minute, func(stats, delta Stats) {
+		reports <- delta
+	})
+	defer stop()
+
+	cache.Set("foo", 1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, int64(1), (<-reports).Sets)
+}
+
+func TestClockExpiration(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 10, MaxAge: time.Hour, Clock: clock})
+
+	cache.Set("foo", 1)
+	clock.Advance(30 * time.Minute)
+	ttl, ok := cache.TTL("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Minute, ttl)
+
+	clock.Advance(31 * time.Minute)
+	_, ok = cache.Get("foo")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), cache.Stats().Expirations)
+}
+
+func TestClockActiveExpiration(t *testing.T) {
+	expired := make(chan string, 1)
+
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{
+		Capacity:           10,
+		MaxAge:             time.Hour,
+		ExpirationType:     ActiveExpiration,
+		ExpirationInterval: time.Minute,
+		Clock:              clock,
+		OnExpiration: func(key string, value int) {
+			expired <- key
+		},
+	})
+	defer cache.Close()
+
+	cache.Set("foo", 1)
+	clock.Advance(59 * time.Minute)
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, "foo", <-expired)
+}
@@ -0,0 +1,40 @@
+package agecache
+
+// coalesce adds the evicted entry to its group for OnEvictionGroup. With an
+// EvictionWindow, the first eviction of a window schedules its delivery.
+func (cache *Cache[K, V]) coalesce(entry *cacheEntry[K, V]) {
+	group := ""
+	if cache.evictionGroup != nil {
+		group = cache.evictionGroup(entry.key)
+	}
+
+	if cache.coalesced == nil {
+		cache.coalesced = make(map[string]map[K]V)
+	}
+	evicted, ok := cache.coalesced[group]
+	if !ok {
+		evicted = make(map[K]V)
+		cache.coalesced[group] = evicted
+	}
+	evicted[entry.key] = entry.value
+
+	if cache.evictionWindow > 0 && cache.flush == nil {
+		cache.flush = cache.clock.AfterFunc(cache.evictionWindow, cache.flushEvictions)
+	}
+}
+
+// flushEvictions delivers all coalesced evictions to OnEvictionGroup.
+func (cache *Cache[K, V]) flushEvictions() {
+	cache.mutex.Lock()
+	coalesced := cache.coalesced
+	cache.coalesced = nil
+	if cache.flush != nil {
+		cache.flush.Stop()
+		cache.flush = nil
+	}
+	cache.mutex.Unlock()
+
+	for group, evicted := range coalesced {
+		cache.onEvictionGroup(group, evicted)
+	}
+}
@@ -0,0 +1,105 @@
+package agecache
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvictionGroups(t *testing.T) {
+	groups := make(map[string]map[string]int)
+	calls := 0
+
+	cache := New(Config[string, int]{
+		Capacity: 4,
+		EvictionGroup: func(key string) string {
+			return strings.SplitN(key, ":", 2)[0]
+		},
+		OnEvictionGroup: func(group string, evicted map[string]int) {
+			calls++
+			groups[group] = evicted
+		},
+	})
+
+	cache.Set("a:1", 1)
+	cache.Set("b:1", 2)
+	cache.Set("a:2", 3)
+	cache.Set("b:2", 4)
+
+	// A single operation delivers its evictions grouped
+	cache.Resize(1)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, map[string]int{"a:1": 1, "a:2": 3}, groups["a"])
+	assert.Equal(t, map[string]int{"b:1": 2}, groups["b"])
+}
+
+func TestEvictionWindow(t *testing.T) {
+	var mutex sync.Mutex
+	var delivered []map[string]int
+	evictions := 0
+
+	cache := New(Config[string, int]{
+		Capacity:       1,
+		EvictionWindow: 20 * time.Millisecond,
+		OnEviction: func(key string, value int) {
+			evictions++
+		},
+		OnEvictionGroup: func(group string, evicted map[string]int) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			assert.Equal(t, "", group)
+			delivered = append(delivered, evicted)
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("a", 3)
+	cache.Set("b", 4)
+	cache.Set("c", 5)
+	assert.Equal(t, 4, evictions)
+
+	// Keys evicted more than once within the window are delivered once
+	<-time.After(40 * time.Millisecond)
+	mutex.Lock()
+	assert.Equal(t, []map[string]int{{"a": 3, "b": 4}}, delivered)
+	mutex.Unlock()
+
+	// Close delivers evictions without waiting for the window
+	cache.Set("d", 6)
+	cache.Close()
+	mutex.Lock()
+	assert.Equal(t, map[string]int{"c": 5}, delivered[1])
+	mutex.Unlock()
+}
+
+func TestEvictionWindowClock(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	var delivered []map[string]int
+
+	cache := New(Config[string, int]{
+		Capacity:       1,
+		EvictionWindow: time.Minute,
+		Clock:          clock,
+		OnEvictionGroup: func(group string, evicted map[string]int) {
+			delivered = append(delivered, evicted)
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	clock.Advance(59 * time.Second)
+	assert.Empty(t, delivered)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, []map[string]int{{"a": 1}}, delivered)
+}
+
+func TestInvalidEvictionWindow(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, EvictionWindow: -1})
+	})
+}
@@ -0,0 +1,211 @@
+package agecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+)
+
+// Codec encodes the values of an EncodedCache to bytes, and decodes them.
+// Decode must reverse Encode. Its methods are invoked without the cache's
+// lock held, and may be invoked concurrently.
+type Codec[V any] interface {
+	Encode(value V) ([]byte, error)
+	Decode(data []byte) (V, error)
+}
+
+// GobCodec is a Codec encoding values with encoding/gob.
+type GobCodec[V any] struct{}
+
+// Encode encodes value with encoding/gob.
+func (GobCodec[V]) Encode(value V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode decodes a value encoded by Encode.
+func (GobCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// TransformCodec returns a Codec passing the values encoded by codec through
+// each of stages in order, such as a GzipTransformer to store values
+// compressed, and through each in reverse order before decoding them.
+func TransformCodec[V any](codec Codec[V], stages ...Transformer) Codec[V] {
+	return transformCodec[V]{codec, stages}
+}
+
+type transformCodec[V any] struct {
+	codec  Codec[V]
+	stages []Transformer
+}
+
+func (codec transformCodec[V]) Encode(value V) ([]byte, error) {
+	data, err := codec.codec.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range codec.stages {
+		if data, err = stage.Encode(data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (codec transformCodec[V]) Decode(data []byte) (V, error) {
+	for i := len(codec.stages) - 1; i >= 0; i-- {
+		var err error
+		if data, err = codec.stages[i].Decode(data); err != nil {
+			var value V
+			return value, err
+		}
+	}
+	return codec.codec.Decode(data)
+}
+
+// EncodedCache is a Cache storing its values encoded by a Codec, such as to
+// store large values compressed, trading the CPU time of encoding each Set
+// and decoding each Get for memory. Its methods behave as those of Cache,
+// additionally returning any error of the Codec.
+type EncodedCache[K comparable, V any] struct {
+	cache *Cache[K, []byte]
+	codec Codec[V]
+}
+
+// NewEncoded constructs an EncodedCache with the given Config object, as with
+// New, storing values encoded by codec. The callbacks of config are invoked
+// with encoded values. With a MaxCost and no Cost, the cost of each item is
+// the length of its encoded value, and with a MaxMemory, a BytesSizer is the
+// default Sizer. Panics given an invalid configuration, or a nil codec.
+func NewEncoded[K comparable, V any](config Config[K, []byte], codec Codec[V]) *EncodedCache[K, V] {
+	if codec == nil {
+		panic("Must supply a codec")
+	}
+
+	if config.MaxCost > 0 && config.Cost == nil {
+		config.Cost = func(key K, value []byte) int64 {
+			return int64(len(value))
+		}
+	}
+
+	if config.MaxMemory > 0 && config.Sizer == nil {
+		config.Sizer = BytesSizer[[]byte]{}
+	}
+
+	return &EncodedCache[K, V]{cache: New(config), codec: codec}
+}
+
+// Cache returns the underlying Cache of encoded values.
+func (encoded *EncodedCache[K, V]) Cache() *Cache[K, []byte] {
+	return encoded.cache
+}
+
+// Set encodes value, and stores it at key. Returns true if an eviction
+// occurred, or any error encoding the value, in which case nothing is
+// stored. See Cache.Set.
+func (encoded *EncodedCache[K, V]) Set(key K, value V) (bool, error) {
+	data, err := encoded.codec.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	return encoded.cache.Set(key, data), nil
+}
+
+// SetWithTTL encodes value, and stores it at key, expiring it once ttl has
+// elapsed. See Cache.SetWithTTL.
+func (encoded *EncodedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) (bool, error) {
+	data, err := encoded.codec.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	return encoded.cache.SetWithTTL(key, data, ttl), nil
+}
+
+// Get returns the decoded value stored at key. The boolean value reports
+// whether the value was found. Also returns any error decoding the value.
+// See Cache.Get.
+func (encoded *EncodedCache[K, V]) Get(key K) (value V, found bool, err error) {
+	data, ok := encoded.cache.Get(key)
+	if !ok {
+		return value, false, nil
+	}
+
+	value, err = encoded.codec.Decode(data)
+	return value, true, err
+}
+
+// Peek returns the decoded value stored at key, as with Get, without
+// updating its position in the eviction order. See Cache.Peek.
+func (encoded *EncodedCache[K, V]) Peek(key K) (value V, found bool, err error) {
+	data, ok := encoded.cache.Peek(key)
+	if !ok {
+		return value, false, nil
+	}
+
+	value, err = encoded.codec.Decode(data)
+	return value, true, err
+}
+
+// GetOrLoad returns the decoded value stored at key, invoking loader to
+// produce and store it on a miss. Values which fail to encode are returned
+// with the error, but not stored. See Cache.GetOrLoad.
+func (encoded *EncodedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error)) (V, error) {
+	var loaded V
+	var ok bool
+
+	data, err := encoded.cache.GetOrLoad(ctx, key, func(ctx context.Context, key K) ([]byte, error) {
+		value, err := loader(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		loaded, ok = value, true
+		return encoded.codec.Encode(value)
+	})
+	if ok || err != nil {
+		return loaded, err
+	}
+
+	return encoded.codec.Decode(data)
+}
+
+// Has returns whether an item is stored at key, as with Cache.Has, without
+// checking whether it expired.
+func (encoded *EncodedCache[K, V]) Has(key K) bool {
+	return encoded.cache.Has(key)
+}
+
+// Remove removes the item stored at key, returning whether it existed. See
+// Cache.Remove.
+func (encoded *EncodedCache[K, V]) Remove(key K) bool {
+	return encoded.cache.Remove(key)
+}
+
+// Len returns the number of items in the cache.
+func (encoded *EncodedCache[K, V]) Len() int {
+	return encoded.cache.Len()
+}
+
+// Clear removes all items from the cache, returning the number removed. See
+// Cache.Clear.
+func (encoded *EncodedCache[K, V]) Clear() int {
+	return encoded.cache.Clear()
+}
+
+// Stats returns the stats of the cache, whose Cost is that of the encoded
+// values.
+func (encoded *EncodedCache[K, V]) Stats() Stats {
+	return encoded.cache.Stats()
+}
+
+// Close closes the cache, as with Cache.Close.
+func (encoded *EncodedCache[K, V]) Close() error {
+	return encoded.cache.Close()
+}
@@ -0,0 +1,87 @@
+package agecache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type document struct {
+	Title string
+	Body  string
+}
+
+func TestEncodedCache(t *testing.T) {
+	codec := TransformCodec[document](GobCodec[document]{}, GzipTransformer{})
+	cache := NewEncoded(Config[string, []byte]{Capacity: 10, MaxCost: 1000}, codec)
+
+	doc := document{Title: "foo", Body: strings.Repeat("bar", 1000)}
+	_, err := cache.Set("foo", doc)
+	assert.NoError(t, err)
+
+	value, found, err := cache.Get("foo")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, doc, value)
+
+	// Stored compressed, costing the length of the encoded value
+	data, _ := cache.Cache().Peek("foo")
+	assert.Less(t, len(data), len(doc.Body))
+	assert.Equal(t, int64(len(data)), cache.Stats().Cost)
+
+	_, found, err = cache.Get("bar")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	loads := 0
+	loader := func(ctx context.Context, key string) (document, error) {
+		loads++
+		return document{Title: key}, nil
+	}
+	value, err = cache.GetOrLoad(context.Background(), "bar", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, document{Title: "bar"}, value)
+	value, err = cache.GetOrLoad(context.Background(), "bar", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, document{Title: "bar"}, value)
+	assert.Equal(t, 1, loads)
+
+	// Corrupted values fail to decode
+	cache.Cache().Set("corrupt", []byte("corrupt"))
+	_, found, err = cache.Peek("corrupt")
+	assert.True(t, found)
+	assert.Error(t, err)
+
+	assert.Equal(t, 3, cache.Len())
+	assert.True(t, cache.Remove("corrupt"))
+	assert.Equal(t, 2, cache.Clear())
+	assert.Zero(t, cache.Stats().Cost)
+
+	assert.Panics(t, func() {
+		NewEncoded[string, document](Config[string, []byte]{Capacity: 10}, nil)
+	})
+}
+
+// failingCodec fails to encode values.
+type failingCodec struct{ GobCodec[int] }
+
+func (failingCodec) Encode(value int) ([]byte, error) {
+	return nil, errors.New("unencodable")
+}
+
+func TestEncodedCacheErrors(t *testing.T) {
+	cache := NewEncoded[string, int](Config[string, []byte]{Capacity: 10}, failingCodec{})
+
+	_, err := cache.Set("foo", 1)
+	assert.EqualError(t, err, "unencodable")
+	assert.False(t, cache.Has("foo"))
+
+	_, err = cache.GetOrLoad(context.Background(), "foo", func(ctx context.Context, key string) (int, error) {
+		return 1, nil
+	})
+	assert.EqualError(t, err, "unencodable")
+	assert.False(t, cache.Has("foo"))
+}
@@ -0,0 +1,29 @@
+// Package compat exposes agecache caches with interface{} keys and values, for
+// call sites written against the API which predates generics. It is a separate
+// module, as interface{} keys require Go 1.20.
+package compat
+
+import "github.com/segmentio/agecache"
+
+// Config configures a Cache. See agecache.Config.
+type Config = agecache.Config[interface{}, interface{}]
+
+// Cache is an agecache.Cache of interface{} keys and values.
+type Cache = agecache.Cache[interface{}, interface{}]
+
+// ShardedConfig configures a ShardedCache. See agecache.ShardedConfig.
+type ShardedConfig = agecache.ShardedConfig[interface{}, interface{}]
+
+// ShardedCache is an agecache.ShardedCache of interface{} keys and values.
+type ShardedCache = agecache.ShardedCache[interface{}, interface{}]
+
+// New constructs a Cache with the given Config. See agecache.New.
+func New(config Config) *Cache {
+	return agecache.New(config)
+}
+
+// NewSharded constructs a ShardedCache with the given ShardedConfig. See
+// agecache.NewSharded.
+func NewSharded(config ShardedConfig) *ShardedCache {
+	return agecache.NewSharded(config)
+}
@@ -0,0 +1,60 @@
+package compat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	var evicted []interface{}
+
+	cache := New(Config{
+		Capacity: 2,
+		MaxAge:   time.Hour,
+		OnEviction: func(key, value interface{}) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	cache.Set("foo", 1)
+	cache.Set(2, "bar")
+	cache.Set(struct{}{}, nil)
+
+	assert.Equal(t, []interface{}{"foo"}, evicted)
+
+	val, ok := cache.Get(2)
+	assert.True(t, ok)
+	assert.Equal(t, "bar", val)
+}
+
+func TestShardedCache(t *testing.T) {
+	cache := NewSharded(ShardedConfig{
+		Config: Config{Capacity: 10},
+		Shards: 2,
+	})
+
+	cache.Set("foo", 1)
+	cache.Set(1, "foo")
+
+	val, ok := cache.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestOrigins(t *testing.T) {
+	cache := New(Config{
+		Capacity:         2,
+		OriginSampleRate: 1,
+		Preload: func() map[interface{}]interface{} {
+			return map[interface{}]interface{}{"foo": 1}
+		},
+	})
+
+	entries := cache.Export()
+	if assert.Len(t, entries, 1) {
+		assert.Contains(t, entries[0].Origin, "compat_test.go:")
+	}
+}
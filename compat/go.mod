@@ -0,0 +1,16 @@
+module github.com/segmentio/agecache/compat
+
+go 1.20
+
+replace github.com/segmentio/agecache => ../
+
+require (
+	github.com/segmentio/agecache v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.7.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
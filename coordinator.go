@@ -0,0 +1,256 @@
+package agecache
+
+import (
+	"errors"
+	"sync"
+)
+
+// Resizable is implemented by caches which can take part in a Coordinator.
+// *Cache satisfies this interface for any key and value type.
+type Resizable interface {
+	Stats() Stats
+	Resize(n int) error
+}
+
+// AgeScalable is implemented by caches whose effective max age can be scaled
+// down by a Coordinator while over budget. *Cache satisfies this interface for
+// any key and value type.
+type AgeScalable interface {
+	ScaleMaxAge(factor float64) error
+	RemoveExpired() int
+}
+
+// CostLimited is implemented by caches whose total cost can be limited by the
+// cost budget of a Coordinator. *Cache satisfies this interface for any key
+// and value type, limiting the memory used by its items with a MaxMemory.
+type CostLimited interface {
+	MaxCost() int64
+	SetMaxCost(maxCost int64) error
+}
+
+// Coordinator enforces a process-wide entry budget across a set of caches.
+// Each registered cache keeps the capacity it had when it was registered
+// while the combined capacity fits the budget. Once it no longer fits, every
+// cache is resized to a share of the budget proportional to its registered
+// capacity, so the caches can never collectively hold more entries than the
+// budget allows.
+//
+// An age scale may optionally be configured with SetAgeScale. While over
+// budget, caches implementing AgeScalable then have their effective max age
+// scaled down and their expired items removed before being resized, so that
+// the stalest items are dropped in preference to recently used ones.
+//
+// A cost budget may optionally be configured with SetCostBudget, such as a
+// byte budget across caches configured with a MaxMemory. It is enforced as
+// the entry budget, over the MaxCost of the caches implementing CostLimited
+// with a MaxCost when registered. Caches without one are not limited by it.
+type Coordinator struct {
+	budget     int
+	costBudget int64
+	ageScale   float64
+	members    []*member
+	mutex      sync.Mutex
+}
+
+type member struct {
+	cache    Resizable
+	capacity int
+	maxCost  int64
+}
+
+// NewCoordinator constructs a Coordinator enforcing the given entry budget.
+// Panics given a budget that is not a positive int.
+func NewCoordinator(budget int) *Coordinator {
+	if budget <= 0 {
+		panic("Must supply a positive budget")
+	}
+
+	return &Coordinator{budget: budget, ageScale: 1}
+}
+
+// Register adds the cache to the coordinator, recording its current capacity
+// as the capacity it is entitled to when the budget allows, and rebalances
+// all registered caches. Registering a cache twice has no effect.
+func (coordinator *Coordinator) Register(cache Resizable) error {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	for _, m := range coordinator.members {
+		if m.cache == cache {
+			return nil
+		}
+	}
+
+	m := &member{
+		cache:    cache,
+		capacity: int(cache.Stats().Capacity),
+	}
+	if limited, ok := cache.(CostLimited); ok {
+		m.maxCost = limited.MaxCost()
+	}
+	coordinator.members = append(coordinator.members, m)
+
+	return coordinator.rebalance()
+}
+
+// Unregister removes the cache from the coordinator, restoring the capacity
+// it was registered with, and rebalances the remaining caches. Returns a
+// bool indicating whether the cache was registered.
+func (coordinator *Coordinator) Unregister(cache Resizable) bool {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	for i, m := range coordinator.members {
+		if m.cache == cache {
+			coordinator.members = append(coordinator.members[:i], coordinator.members[i+1:]...)
+			if scaler, ok := m.cache.(AgeScalable); ok && coordinator.ageScale < 1 {
+				scaler.ScaleMaxAge(1)
+			}
+			m.cache.Resize(m.capacity)
+			if limited, ok := m.cache.(CostLimited); ok && m.maxCost > 0 {
+				limited.SetMaxCost(m.maxCost)
+			}
+			coordinator.rebalance()
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetBudget updates the entry budget and rebalances all registered caches.
+// A budget that is not a positive int results in an error.
+func (coordinator *Coordinator) SetBudget(budget int) error {
+	if budget <= 0 {
+		return errors.New("Must supply a positive budget")
+	}
+
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.budget = budget
+
+	return coordinator.rebalance()
+}
+
+// SetCostBudget updates the cost budget and rebalances all registered
+// caches. A budget of zero disables it, restoring the MaxCost with which each
+// cache was registered. A negative budget results in an error.
+func (coordinator *Coordinator) SetCostBudget(budget int64) error {
+	if budget < 0 {
+		return errors.New("Must supply a zero or positive cost budget")
+	}
+
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.costBudget = budget
+
+	return coordinator.rebalance()
+}
+
+// SetAgeScale updates the factor applied to the max age of registered caches
+// while over budget, and rebalances all registered caches. A factor of 1
+// disables age scaling. A factor outside of (0, 1] results in an error.
+func (coordinator *Coordinator) SetAgeScale(factor float64) error {
+	if factor <= 0 || factor > 1 {
+		return errors.New("Must supply a factor greater than 0 and at most 1")
+	}
+
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	restore := coordinator.ageScale < 1 && factor == 1
+	coordinator.ageScale = factor
+	if restore {
+		for _, m := range coordinator.members {
+			if scaler, ok := m.cache.(AgeScalable); ok {
+				scaler.ScaleMaxAge(1)
+			}
+		}
+	}
+
+	return coordinator.rebalance()
+}
+
+// Budget returns the entry budget.
+func (coordinator *Coordinator) Budget() int {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	return coordinator.budget
+}
+
+// CostBudget returns the cost budget, or zero if it is disabled.
+func (coordinator *Coordinator) CostBudget() int64 {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	return coordinator.costBudget
+}
+
+// Len returns the number of registered caches.
+func (coordinator *Coordinator) Len() int {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	return len(coordinator.members)
+}
+
+// rebalance resizes every member to its share of the budget, and limits the
+// cost of every member to its share of the cost budget. Every cache is left
+// with a capacity and max cost of at least one, so the budgets can only be
+// exceeded when there are more registered caches than units in the budget.
+func (coordinator *Coordinator) rebalance() error {
+	total := 0
+	var totalCost int64
+	for _, m := range coordinator.members {
+		total += m.capacity
+		totalCost += m.maxCost
+	}
+
+	over := total > coordinator.budget
+	costOver := coordinator.costBudget > 0 && totalCost > coordinator.costBudget
+
+	var err error
+	for _, m := range coordinator.members {
+		if scaler, ok := m.cache.(AgeScalable); ok && coordinator.ageScale < 1 {
+			if over || costOver && m.maxCost > 0 {
+				scaler.ScaleMaxAge(coordinator.ageScale)
+				scaler.RemoveExpired()
+			} else {
+				scaler.ScaleMaxAge(1)
+			}
+		}
+
+		share := m.capacity
+		if over {
+			share = int(int64(m.capacity) * int64(coordinator.budget) / int64(total))
+		}
+		if share < 1 {
+			share = 1
+		}
+
+		if resizeErr := m.cache.Resize(share); resizeErr != nil && err == nil {
+			err = resizeErr
+		}
+
+		if limited, ok := m.cache.(CostLimited); ok && m.maxCost > 0 {
+			maxCost := m.maxCost
+			if costOver {
+				// Scaled in floating point, as costs such as bytes may
+				// overflow the product
+				maxCost = int64(float64(m.maxCost) * float64(coordinator.costBudget) / float64(totalCost))
+			}
+			if maxCost < 1 {
+				maxCost = 1
+			}
+
+			if costErr := limited.SetMaxCost(maxCost); costErr != nil && err == nil {
+				err = costErr
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,136 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidBudget(t *testing.T) {
+	assert.Panics(t, func() {
+		NewCoordinator(0)
+	})
+}
+
+func TestCoordinatorWithinBudget(t *testing.T) {
+	coordinator := NewCoordinator(10)
+	a := New(Config[string, int]{Capacity: 4})
+	b := New(Config[string, int]{Capacity: 6})
+
+	assert.NoError(t, coordinator.Register(a))
+	assert.NoError(t, coordinator.Register(b))
+
+	assert.Equal(t, 2, coordinator.Len())
+	assert.Equal(t, int64(4), a.Stats().Capacity)
+	assert.Equal(t, int64(6), b.Stats().Capacity)
+}
+
+func TestCoordinatorOverBudget(t *testing.T) {
+	coordinator := NewCoordinator(10)
+	a := New(Config[int, int]{Capacity: 10})
+	b := New(Config[string, string]{Capacity: 30})
+
+	for i := 0; i < 10; i++ {
+		a.Set(i, i)
+	}
+
+	coordinator.Register(a)
+	assert.Equal(t, int64(10), a.Stats().Capacity)
+
+	coordinator.Register(b)
+	assert.Equal(t, int64(2), a.Stats().Capacity)
+	assert.Equal(t, int64(7), b.Stats().Capacity)
+	assert.Equal(t, 2, a.Len())
+
+	ok := coordinator.Unregister(a)
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), a.Stats().Capacity)
+	assert.Equal(t, int64(10), b.Stats().Capacity)
+
+	ok = coordinator.Unregister(a)
+	assert.False(t, ok)
+}
+
+func TestCoordinatorSetBudget(t *testing.T) {
+	coordinator := NewCoordinator(100)
+	a := New(Config[string, int]{Capacity: 10})
+	b := New(Config[string, int]{Capacity: 10})
+	coordinator.Register(a)
+	coordinator.Register(b)
+
+	err := coordinator.SetBudget(0)
+	assert.Error(t, err)
+
+	err = coordinator.SetBudget(10)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, coordinator.Budget())
+	assert.Equal(t, int64(5), a.Stats().Capacity)
+	assert.Equal(t, int64(5), b.Stats().Capacity)
+
+	err = coordinator.SetBudget(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), a.Stats().Capacity)
+	assert.Equal(t, int64(1), b.Stats().Capacity)
+}
+
+func TestCoordinatorCostBudget(t *testing.T) {
+	coordinator := NewCoordinator(100)
+	assert.Error(t, coordinator.SetCostBudget(-1))
+
+	// A byte budget across caches limited by their memory
+	a := New(Config[string, string]{Capacity: 10, MaxMemory: 3000, Sizer: StringSizer[string]{}})
+	b := New(Config[string, string]{Capacity: 10, MaxMemory: 1000, Sizer: StringSizer[string]{}})
+	unlimited := New(Config[string, string]{Capacity: 10})
+	coordinator.Register(a)
+	coordinator.Register(b)
+	coordinator.Register(unlimited)
+
+	assert.NoError(t, coordinator.SetCostBudget(2000))
+	assert.Equal(t, int64(2000), coordinator.CostBudget())
+	assert.Equal(t, int64(1500), a.MaxCost())
+	assert.Equal(t, int64(500), b.MaxCost())
+	assert.Zero(t, unlimited.MaxCost())
+	assert.Equal(t, int64(10), a.Stats().Capacity)
+
+	// Within the budget, caches keep their registered max cost
+	assert.NoError(t, coordinator.SetCostBudget(8000))
+	assert.Equal(t, int64(3000), a.MaxCost())
+
+	assert.NoError(t, coordinator.SetCostBudget(2000))
+	assert.True(t, coordinator.Unregister(a))
+	assert.Equal(t, int64(3000), a.MaxCost())
+	assert.Equal(t, int64(1000), b.MaxCost())
+
+	assert.NoError(t, coordinator.SetCostBudget(0))
+	assert.Equal(t, int64(1000), b.MaxCost())
+}
+
+func TestCoordinatorAgeScale(t *testing.T) {
+	coordinator := NewCoordinator(4)
+	err := coordinator.SetAgeScale(0)
+	assert.Error(t, err)
+
+	err = coordinator.SetAgeScale(0.5)
+	assert.NoError(t, err)
+
+	a := New(Config[string, int]{Capacity: 4, MaxAge: 100 * time.Millisecond})
+	coordinator.Register(a)
+
+	a.Set("stale", 1)
+	<-time.After(60 * time.Millisecond)
+	a.Set("fresh", 2)
+
+	b := New(Config[string, int]{Capacity: 4})
+	coordinator.Register(b)
+
+	assert.Equal(t, int64(2), a.Stats().Capacity)
+	assert.False(t, a.Has("stale"))
+	assert.True(t, a.Has("fresh"))
+
+	coordinator.Unregister(b)
+	a.Set("stale", 1)
+	<-time.After(60 * time.Millisecond)
+	_, ok := a.Get("stale")
+	assert.True(t, ok)
+}
@@ -0,0 +1,70 @@
+package agecache
+
+// SetWithDeps updates a key:value pair in the cache as with Set, declaring that
+// the item depends on the items at deps, such as a rendered page depending on
+// the records it displays. Once any of them is removed, expires, is evicted or
+// is replaced, the item is invalidated: it is removed from the cache, and
+// OnRemoval is invoked with the Invalidated reason. Invalidation cascades to
+// the items depending on it in turn. Dependencies are replaced by each Set of
+// the item, and need not be in the cache.
+func (cache *Cache[K, V]) SetWithDeps(key K, value V, deps ...K) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	evict := cache.set(key, value, cache.getTimestamp(), 0)
+	if entry, ok := cache.items[key]; ok {
+		cache.link(entry, deps)
+	}
+
+	return evict
+}
+
+// link records that the entry depends on the items at deps, ignoring the
+// entry's own key.
+func (cache *Cache[K, V]) link(entry *cacheEntry[K, V], deps []K) {
+	for _, dep := range deps {
+		if dep == entry.key {
+			continue
+		}
+		if cache.dependents == nil {
+			cache.dependents = make(map[K]map[K]struct{})
+		}
+
+		entry.deps = append(entry.deps, dep)
+		dependents, ok := cache.dependents[dep]
+		if !ok {
+			dependents = make(map[K]struct{})
+			cache.dependents[dep] = dependents
+		}
+		dependents[entry.key] = struct{}{}
+	}
+}
+
+// unlink removes the dependencies of the entry.
+func (cache *Cache[K, V]) unlink(entry *cacheEntry[K, V]) {
+	for _, dep := range entry.deps {
+		dependents := cache.dependents[dep]
+		delete(dependents, entry.key)
+		if len(dependents) == 0 {
+			delete(cache.dependents, dep)
+		}
+	}
+	entry.deps = nil
+}
+
+// invalidate removes the items depending on the key, and those depending on
+// them in turn.
+func (cache *Cache[K, V]) invalidate(key K) {
+	dependents, ok := cache.dependents[key]
+	if !ok {
+		return
+	}
+	delete(cache.dependents, key)
+
+	for dependent := range dependents {
+		if entry, ok := cache.items[dependent]; ok {
+			cache.deleteEntry(entry)
+			cache.notify(nil, entry, Invalidated)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWithDeps(t *testing.T) {
+	var invalidated []string
+
+	cache := New(Config[string, string]{
+		Capacity: 10,
+		OnRemoval: func(key, value string, reason RemovalReason) {
+			if reason == Invalidated {
+				invalidated = append(invalidated, key)
+			}
+		},
+	})
+
+	cache.Set("user", "alice")
+	cache.Set("team", "core")
+	cache.SetWithDeps("profile", "<alice>", "user")
+	cache.SetWithDeps("page", "<core><alice>", "team", "profile")
+
+	t.Run("cascades removals", func(t *testing.T) {
+		cache.Remove("user")
+		assert.False(t, cache.Has("profile"))
+		assert.False(t, cache.Has("page"))
+		assert.True(t, cache.Has("team"))
+		assert.Equal(t, []string{"profile", "page"}, invalidated)
+		assert.Equal(t, 0, len(cache.dependents))
+	})
+
+	t.Run("invalidates on replacement", func(t *testing.T) {
+		invalidated = nil
+		cache.SetWithDeps("page", "<core>", "team", "page")
+		cache.Set("team", "platform")
+		assert.False(t, cache.Has("page"))
+		assert.Equal(t, []string{"page"}, invalidated)
+	})
+
+	t.Run("replaces dependencies", func(t *testing.T) {
+		invalidated = nil
+		cache.SetWithDeps("page", "<platform>", "team")
+		cache.Set("page", "static")
+		cache.Remove("team")
+		assert.True(t, cache.Has("page"))
+		assert.Nil(t, invalidated)
+	})
+
+	t.Run("cascades expirations and evictions", func(t *testing.T) {
+		cache := New(Config[int, int]{Capacity: 3})
+		cache.SetWithTTL(1, 1, 10*time.Millisecond)
+		cache.SetWithDeps(2, 2, 1)
+		cache.SetWithDeps(3, 3, 2)
+
+		<-time.After(20 * time.Millisecond)
+		cache.RemoveExpired()
+		assert.Equal(t, 0, cache.Len())
+
+		cache.Set(1, 1)
+		cache.SetWithDeps(2, 2, 1)
+		cache.Set(3, 3)
+		cache.Set(4, 4)
+		assert.Equal(t, []int{3, 4}, cache.OrderedKeys())
+	})
+}
+
+func TestDepsCycle(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 4})
+	cache.SetWithDeps("a", 1, "b")
+	cache.SetWithDeps("b", 2, "a")
+
+	cache.Set("a", 3)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+	assert.False(t, cache.Has("b"))
+
+	cache.SetWithDeps("b", 4, "a")
+	cache.SetWithDeps("a", 5, "b")
+	assert.Equal(t, []string{"a"}, cache.Keys())
+}
+
+func TestClearDeps(t *testing.T) {
+	var reasons []RemovalReason
+	cache := New(Config[string, int]{
+		Capacity: 4,
+		OnRemoval: func(key string, value int, reason RemovalReason) {
+			reasons = append(reasons, reason)
+		},
+	})
+	cache.Set("a", 1)
+	cache.SetWithDeps("b", 2, "a")
+
+	assert.Equal(t, 2, cache.Clear())
+	assert.Equal(t, []RemovalReason{Cleared, Cleared}, reasons)
+}
@@ -0,0 +1,103 @@
+package agecache
+
+import "sync"
+
+// Number of batches of callbacks queued when Config.CallbackQueue is zero
+const defaultCallbackQueue = 1024
+
+// dispatcher invokes batches of callbacks on a pool of worker goroutines.
+// When its queue is full, or once closed, batches are refused so that the
+// caller invokes them itself, which bounds the queue without blocking callbacks
+// which call back into the cache.
+type dispatcher struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	idle   *sync.Cond // Broadcast once no batches are queued or running
+	queue  []func()
+	limit  int
+	active int // Batches being invoked
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newDispatcher(workers, limit int) *dispatcher {
+	if workers == 0 {
+		return nil
+	}
+	if limit == 0 {
+		limit = defaultCallbackQueue
+	}
+
+	dispatcher := &dispatcher{limit: limit}
+	dispatcher.cond = sync.NewCond(&dispatcher.mutex)
+	dispatcher.idle = sync.NewCond(&dispatcher.mutex)
+	dispatcher.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go dispatcher.work()
+	}
+	return dispatcher
+}
+
+// dispatch queues the batch, returning false if it was refused.
+func (dispatcher *dispatcher) dispatch(batch func()) bool {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	if dispatcher.closed || len(dispatcher.queue) >= dispatcher.limit {
+		return false
+	}
+
+	dispatcher.queue = append(dispatcher.queue, batch)
+	dispatcher.cond.Signal()
+	return true
+}
+
+// close refuses further batches, and waits for the queued batches to be
+// invoked.
+func (dispatcher *dispatcher) close() {
+	dispatcher.mutex.Lock()
+	dispatcher.closed = true
+	dispatcher.cond.Broadcast()
+	dispatcher.mutex.Unlock()
+
+	dispatcher.wg.Wait()
+}
+
+// wait blocks until all queued batches have been invoked.
+func (dispatcher *dispatcher) wait() {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	for len(dispatcher.queue) > 0 || dispatcher.active > 0 {
+		dispatcher.idle.Wait()
+	}
+}
+
+func (dispatcher *dispatcher) work() {
+	defer dispatcher.wg.Done()
+
+	dispatcher.mutex.Lock()
+	for {
+		for len(dispatcher.queue) == 0 && !dispatcher.closed {
+			dispatcher.cond.Wait()
+		}
+		if len(dispatcher.queue) == 0 {
+			dispatcher.mutex.Unlock()
+			return
+		}
+
+		batch := dispatcher.queue[0]
+		dispatcher.queue[0] = nil
+		dispatcher.queue = dispatcher.queue[1:]
+		dispatcher.active++
+		dispatcher.mutex.Unlock()
+
+		batch()
+
+		dispatcher.mutex.Lock()
+		dispatcher.active--
+		if len(dispatcher.queue) == 0 && dispatcher.active == 0 {
+			dispatcher.idle.Broadcast()
+		}
+	}
+}
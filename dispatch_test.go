@@ -0,0 +1,127 @@
+package agecache
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallbackWorkers(t *testing.T) {
+	gate := make(chan struct{})
+	var evicted int32
+
+	cache := New(Config[string, int]{
+		Capacity:        1,
+		CallbackWorkers: 2,
+		OnEviction: func(key string, value int) {
+			<-gate
+			atomic.AddInt32(&evicted, 1)
+		},
+	})
+
+	// Operations return without waiting for their callbacks
+	cache.Set("a", 1)
+	assert.True(t, cache.Set("b", 2))
+	assert.True(t, cache.Set("c", 3))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&evicted))
+
+	close(gate)
+	cache.Close()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&evicted))
+
+	// Once closed, callbacks are invoked synchronously
+	cache.Set("d", 4)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&evicted))
+}
+
+func TestSync(t *testing.T) {
+	var sets int32
+
+	cache := New(Config[int, int]{
+		Capacity:        100,
+		CallbackWorkers: 2,
+		OnSet: func(key, value int, replaced bool) {
+			<-time.After(time.Millisecond)
+			atomic.AddInt32(&sets, 1)
+		},
+	})
+	defer cache.Close()
+
+	for i := 0; i < 20; i++ {
+		cache.Set(i, i)
+
+		// Writes are observed before their callbacks are invoked
+		value, ok := cache.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, i, value)
+	}
+
+	cache.Sync()
+	assert.Equal(t, int32(20), atomic.LoadInt32(&sets))
+
+	// The cache remains usable asynchronously
+	cache.Set(20, 20)
+	cache.Sync()
+	assert.Equal(t, int32(21), atomic.LoadInt32(&sets))
+
+	// Without workers, Sync has nothing to wait for
+	New(Config[int, int]{Capacity: 1}).Sync()
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	ran := 0
+
+	dispatcher := newDispatcher(1, 1)
+	assert.True(t, dispatcher.dispatch(func() {
+		close(started)
+		<-gate
+	}))
+	<-started
+
+	assert.True(t, dispatcher.dispatch(func() { ran++ }))
+	assert.False(t, dispatcher.dispatch(func() { ran++ }))
+
+	close(gate)
+	dispatcher.close()
+	assert.Equal(t, 1, ran)
+	assert.False(t, dispatcher.dispatch(func() {}))
+
+	assert.Nil(t, newDispatcher(0, 0))
+}
+
+func TestCallbackWorkersReentrant(t *testing.T) {
+	done := make(chan struct{})
+
+	var cache *Cache[string, int]
+	cache = New(Config[string, int]{
+		Capacity:        1,
+		CallbackWorkers: 1,
+		CallbackQueue:   1,
+		OnEviction: func(key string, value int) {
+			if key == "a" {
+				cache.Set("a", value)
+				close(done)
+			}
+		},
+	})
+	defer cache.Close()
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the callback to call back into the cache")
+	}
+}
+
+func TestInvalidCallbackWorkers(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, CallbackWorkers: -1})
+	})
+}
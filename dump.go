@@ -0,0 +1,239 @@
+package agecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// Defaults of DumpConfig.Interval and DumpConfig.History, retaining an hour
+// of Stats
+const (
+	defaultDumpInterval = time.Minute
+	defaultDumpHistory  = 60
+)
+
+// Dump is a diagnostic snapshot of a cache, as written by WriteDump, for
+// analysis after an incident. It describes the items of the cache without
+// their values, and with their keys redacted.
+type Dump struct {
+	Time     time.Time    `json:"time"`
+	Settings DumpSettings `json:"settings"`
+	Stats    Stats        `json:"stats"`
+
+	// Samples of the Stats taken every DumpConfig.Interval, oldest first.
+	// Empty unless written by the dump function of HandleDumps
+	History []Stats `json:"history,omitempty"`
+
+	HitRatio float64 `json:"hit_ratio"` // Hits per get
+	Churn    float64 `json:"churn"`     // Evictions per set
+
+	// Distribution of the age of the items in the cache, zero when empty
+	MedianAge time.Duration `json:"median_age"`
+	P90Age    time.Duration `json:"p90_age"`
+	MaxAge    time.Duration `json:"max_age"`
+
+	// Redacted keys with the most hits since they were last stored, most hit
+	// first, as in Report.TopKeys
+	TopKeys    []string      `json:"top_keys,omitempty"`
+	TopOrigins []OriginStats `json:"top_origins,omitempty"`
+
+	// Estimate of wasted capacity, as in Report
+	Unused int64 `json:"unused"`
+	Idle   int64 `json:"idle"`
+}
+
+// DumpSettings describes the effective configuration of a cache in a Dump,
+// including any changes made since it was constructed, such as by Resize.
+type DumpSettings struct {
+	Capacity           int            `json:"capacity"`
+	MinCapacity        int            `json:"min_capacity,omitempty"`
+	MaxCapacity        int            `json:"max_capacity,omitempty"`
+	MaxCost            int64          `json:"max_cost,omitempty"`
+	MinAge             time.Duration  `json:"min_age,omitempty"`
+	MaxAge             time.Duration  `json:"max_age,omitempty"`
+	ExpirationType     ExpirationType `json:"expiration_type"`
+	ExpirationInterval time.Duration  `json:"expiration_interval,omitempty"`
+	StaleWindow        time.Duration  `json:"stale_while_revalidate,omitempty"`
+	NegativeTTL        time.Duration  `json:"negative_ttl,omitempty"`
+	Closed             bool           `json:"closed"`
+}
+
+// DumpConfig configures HandleDumps.
+type DumpConfig[K comparable] struct {
+	// Writer to which each dump is written. Required
+	Writer io.Writer
+	// Optional signals upon which a dump is written, such as syscall.SIGQUIT
+	// and syscall.SIGUSR1. Once handled, the signals no longer terminate the
+	// process
+	Signals []os.Signal
+	// Optional function redacting keys in dumps. Defaults to a hash of the
+	// key, which identifies it across dumps without revealing it. Keys drawn
+	// from a small set, such as sequential IDs, can be recovered from their
+	// hash, and so should be redacted otherwise
+	Redact func(key K) string
+	// How often the Stats are sampled for the Dump.History. Defaults to one
+	// minute
+	Interval time.Duration
+	// Number of samples of the Stats retained. Defaults to 60
+	History int
+	// Optional callback invoked with any error writing a dump upon a signal
+	OnError func(err error)
+}
+
+// WriteDump writes a Dump of the cache to w, as indented JSON. Keys are
+// redacted with redact, or replaced by a hash of them when redact is nil. It
+// visits every item, as with Report.
+func (cache *Cache[K, V]) WriteDump(w io.Writer, redact func(key K) string) error {
+	return cache.writeDump(w, redact, nil)
+}
+
+// HandleDumps samples the Stats of the cache every config.Interval, and
+// writes a Dump including them to config.Writer upon each of config.Signals,
+// and whenever the returned dump function is called. To dump after a panic,
+// call dump from a deferred function which recovers it, before panicking
+// again. Samples and handles signals until the returned stop function is
+// called or the cache is closed. Panics given an invalid configuration.
+func (cache *Cache[K, V]) HandleDumps(config DumpConfig[K]) (dump func() error, stop func()) {
+	if config.Writer == nil {
+		panic("Must supply a config.Writer")
+	}
+	if config.Interval < 0 {
+		panic("Must supply a zero or positive config.Interval")
+	}
+	if config.History < 0 {
+		panic("Must supply a zero or positive config.History")
+	}
+
+	interval := config.Interval
+	if interval == 0 {
+		interval = defaultDumpInterval
+	}
+	n := config.History
+	if n == 0 {
+		n = defaultDumpHistory
+	}
+
+	var mutex sync.Mutex // Guards history and serializes writes
+	history := make([]Stats, 0, n)
+
+	dump = func() error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return cache.writeDump(config.Writer, config.Redact, history)
+	}
+
+	stopped := make(chan struct{})
+	var once sync.Once
+
+	signals := make(chan os.Signal, 1)
+	if len(config.Signals) > 0 {
+		signal.Notify(signals, config.Signals...)
+	}
+
+	ticker := cache.clock.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		defer signal.Stop(signals)
+
+		for {
+			select {
+			case <-ticker.Chan():
+				stats := cache.Stats()
+				mutex.Lock()
+				if len(history) == n {
+					history = append(history[:0], history[1:]...)
+				}
+				history = append(history, stats)
+				mutex.Unlock()
+			case <-signals:
+				if err := dump(); err != nil && config.OnError != nil {
+					config.OnError(err)
+				}
+			case <-stopped:
+				return
+			case <-cache.done:
+				return
+			}
+		}
+	}()
+
+	return dump, func() {
+		once.Do(func() {
+			close(stopped)
+		})
+	}
+}
+
+// writeDump writes a Dump of the cache, including history, to w.
+func (cache *Cache[K, V]) writeDump(w io.Writer, redact func(key K) string, history []Stats) error {
+	if redact == nil {
+		redact = redactKey[K]
+	}
+
+	report := cache.Report()
+	dump := Dump{
+		Time:       cache.clock.Now(),
+		Settings:   cache.settings(),
+		Stats:      report.Stats,
+		History:    history,
+		HitRatio:   report.HitRatio,
+		Churn:      report.Churn,
+		MedianAge:  report.MedianAge,
+		P90Age:     report.P90Age,
+		MaxAge:     report.MaxAge,
+		TopOrigins: report.TopOrigins,
+		Unused:     report.Unused,
+		Idle:       report.Idle,
+	}
+	for _, key := range report.TopKeys {
+		dump.TopKeys = append(dump.TopKeys, redact(key))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dump)
+}
+
+// settings returns the effective configuration of the cache.
+func (cache *Cache[K, V]) settings() DumpSettings {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	settings := DumpSettings{
+		Capacity:           cache.capacity,
+		MaxCost:            cache.maxCost,
+		MinAge:             cache.minAge,
+		MaxAge:             cache.maxAge,
+		ExpirationType:     cache.expirationType,
+		ExpirationInterval: cache.expirationInterval,
+		StaleWindow:        cache.staleWindow,
+		NegativeTTL:        cache.negativeTTL,
+	}
+	if cache.maxCapacity > 0 {
+		settings.MinCapacity = cache.minCapacity
+		settings.MaxCapacity = cache.maxCapacity
+	}
+
+	select {
+	case <-cache.done:
+		settings.Closed = true
+	default:
+	}
+
+	return settings
+}
+
+// redactKey is the default DumpConfig.Redact, returning the first 8 bytes of
+// the SHA-256 of the key's fmt.Sprint formatting, in hex.
+func redactKey[K comparable](key K) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(key)))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,83 @@
+package agecache
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDump(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 10, MaxAge: time.Hour, Clock: clock})
+
+	cache.Set("secret", 1)
+	cache.Set("other", 2)
+	clock.Advance(time.Minute)
+	cache.Get("secret")
+	cache.Get("missing")
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.WriteDump(&buf, nil))
+	assert.NotContains(t, buf.String(), "secret")
+
+	var dump Dump
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	assert.Equal(t, clock.Now().UnixNano(), dump.Time.UnixNano())
+	assert.Equal(t, 10, dump.Settings.Capacity)
+	assert.Equal(t, time.Hour, dump.Settings.MaxAge)
+	assert.Equal(t, int64(2), dump.Stats.Count)
+	assert.Equal(t, 0.5, dump.HitRatio)
+	assert.Equal(t, time.Minute, dump.MaxAge)
+	assert.Equal(t, []string{redactKey("secret")}, dump.TopKeys)
+	assert.Empty(t, dump.History)
+
+	buf.Reset()
+	assert.NoError(t, cache.WriteDump(&buf, func(key string) string {
+		return "key " + strconv.Itoa(len(key))
+	}))
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	assert.Equal(t, []string{"key 6"}, dump.TopKeys)
+}
+
+func TestHandleDumps(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[int, int]{Capacity: 10, Clock: clock})
+	defer cache.Close()
+
+	var buf bytes.Buffer
+	dump, stop := cache.HandleDumps(DumpConfig[int]{Writer: &buf, Interval: time.Second, History: 2})
+	defer stop()
+
+	history := func() []Stats {
+		buf.Reset()
+		assert.NoError(t, dump())
+
+		var dump Dump
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+		return dump.History
+	}
+
+	// Only the latest samples are retained
+	for i := 1; i <= 3; i++ {
+		cache.Set(i, i)
+		clock.Advance(time.Second)
+		assert.Eventually(t, func() bool {
+			samples := history()
+			return len(samples) > 0 && samples[len(samples)-1].Count == int64(i)
+		}, time.Second, time.Millisecond)
+	}
+
+	samples := history()
+	if assert.Len(t, samples, 2) {
+		assert.Equal(t, int64(2), samples[0].Count)
+		assert.Equal(t, int64(3), samples[1].Count)
+	}
+
+	assert.Panics(t, func() {
+		cache.HandleDumps(DumpConfig[int]{})
+	})
+}
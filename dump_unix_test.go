@@ -0,0 +1,46 @@
+//go:build unix
+
+package agecache
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (buf *lockedBuffer) Write(p []byte) (int, error) {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+	return buf.buf.Write(p)
+}
+
+func (buf *lockedBuffer) Len() int {
+	buf.mutex.Lock()
+	defer buf.mutex.Unlock()
+	return buf.buf.Len()
+}
+
+func TestHandleDumpsSignal(t *testing.T) {
+	cache := New(Config[int, int]{Capacity: 10})
+	defer cache.Close()
+
+	var buf lockedBuffer
+	_, stop := cache.HandleDumps(DumpConfig[int]{Writer: &buf, Signals: []os.Signal{syscall.SIGUSR1}})
+	defer stop()
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return buf.Len() > 0
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,124 @@
+package agecache
+
+import "time"
+
+// EventType enumerates the operations reported to subscribers by Subscribe.
+type EventType int
+
+const (
+	// EventSet reports an item stored by Set or one of its variants
+	EventSet EventType = iota + 1
+	// EventHit reports a Get which found an unexpired item
+	EventHit
+	// EventMiss reports a Get which found no unexpired item
+	EventMiss
+	// EventEviction reports an item removed by the eviction policy
+	EventEviction
+	// EventExpiration reports an item removed for having expired
+	EventExpiration
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventSet:
+		return "set"
+	case EventHit:
+		return "hit"
+	case EventMiss:
+		return "miss"
+	case EventEviction:
+		return "eviction"
+	case EventExpiration:
+		return "expiration"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes an operation on a cache, as reported by Subscribe.
+type Event[K comparable, V any] struct {
+	Type EventType
+	Key  K
+	// The value stored, found, evicted or expired. Zero for EventMiss
+	Value V
+	// When the operation occurred
+	Time time.Time
+	// For EventEviction and EventExpiration, when the item was created,
+	// updated and accessed, per Config.TrackTimes
+	Times EntryTimes
+}
+
+// Registered by Subscribe. Compared by pointer when unsubscribing
+type subscriber[K comparable, V any] struct {
+	fn func(event Event[K, V])
+}
+
+// Subscribe registers fn to be invoked with an Event for every set, hit,
+// miss, eviction and expiration, such as to stream cache activity to an audit
+// pipeline. As with other callbacks, fn is invoked once the cache's lock has
+// been released, and with CallbackWorkers may be invoked concurrently and out
+// of order. Returns a function which unsubscribes fn.
+func (cache *Cache[K, V]) Subscribe(fn func(event Event[K, V])) (unsubscribe func()) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	s := &subscriber[K, V]{fn}
+	// Copied on write, as queued notifications share the slice
+	cache.subscribers = append(cache.subscribers[:len(cache.subscribers):len(cache.subscribers)], s)
+
+	return func() {
+		cache.mutex.Lock()
+		defer cache.mutex.Unlock()
+
+		for i, other := range cache.subscribers {
+			if other == s {
+				subscribers := make([]*subscriber[K, V], 0, len(cache.subscribers)-1)
+				subscribers = append(subscribers, cache.subscribers[:i]...)
+				cache.subscribers = append(subscribers, cache.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Subscribe registers fn to be invoked with an Event for every operation on
+// any shard. See Cache.Subscribe.
+func (cache *ShardedCache[K, V]) Subscribe(fn func(event Event[K, V])) (unsubscribe func()) {
+	unsubscribes := make([]func(), len(cache.shards))
+	for i, shard := range cache.shards {
+		unsubscribes[i] = shard.Subscribe(fn)
+	}
+
+	return func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	}
+}
+
+// emit queues an Event for the subscribers.
+func (cache *Cache[K, V]) emit(eventType EventType, key K, value V) {
+	if len(cache.subscribers) > 0 {
+		cache.pending = append(cache.pending, notification[K, V]{
+			subscribers: cache.subscribers,
+			event:       eventType,
+			at:          cache.clock.Now(),
+			key:         key,
+			value:       value,
+		})
+	}
+}
+
+// emitEntry queues an Event of the entry, including its times, as with emit.
+func (cache *Cache[K, V]) emitEntry(eventType EventType, entry *cacheEntry[K, V]) {
+	if len(cache.subscribers) > 0 {
+		cache.pending = append(cache.pending, notification[K, V]{
+			subscribers: cache.subscribers,
+			event:       eventType,
+			at:          cache.clock.Now(),
+			key:         entry.key,
+			value:       entry.value,
+			times:       entry.trackedTimes(),
+		})
+	}
+}
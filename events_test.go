@@ -0,0 +1,88 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 1, MaxAge: 10 * time.Millisecond})
+
+	var events []Event[string, int]
+	unsubscribe := cache.Subscribe(func(event Event[string, int]) {
+		events = append(events, event)
+	})
+
+	start := time.Now()
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Get("b")
+	cache.Set("b", 2)
+	<-time.After(20 * time.Millisecond)
+	cache.Get("b")
+
+	var types []EventType
+	for _, event := range events {
+		types = append(types, event.Type)
+		assert.False(t, event.Time.Before(start))
+	}
+	assert.Equal(t, []EventType{EventSet, EventHit, EventMiss, EventSet, EventEviction, EventExpiration, EventMiss}, types)
+	assert.Equal(t, Event[string, int]{Type: EventEviction, Key: "a", Value: 1, Time: events[4].Time}, events[4])
+	assert.Equal(t, "b", events[6].Key)
+	assert.Equal(t, 0, events[6].Value)
+	assert.Equal(t, "expiration", EventExpiration.String())
+
+	unsubscribe()
+	cache.Set("c", 3)
+	assert.Len(t, events, 7)
+}
+
+func TestSubscribeEvictionTimes(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	start := clock.Now()
+	cache := New(Config[string, int]{Capacity: 1, Clock: clock, TrackTimes: true})
+
+	var evicted []Event[string, int]
+	cache.Subscribe(func(event Event[string, int]) {
+		if event.Type == EventEviction {
+			evicted = append(evicted, event)
+		}
+	})
+
+	cache.Set("foo", 1)
+	clock.Advance(time.Minute)
+	cache.Get("foo")
+	clock.Advance(time.Minute)
+	cache.Set("bar", 2)
+
+	assert.Len(t, evicted, 1)
+	assert.Equal(t, "foo", evicted[0].Key)
+	assert.Equal(t, EntryTimes{Created: start, Updated: start, Accessed: start.Add(time.Minute)}, evicted[0].Times)
+}
+
+func TestSubscribeMany(t *testing.T) {
+	cache := NewSharded(ShardedConfig[string, int]{Config: Config[string, int]{Capacity: 4}, Shards: 2})
+
+	counts := make([]int, 3)
+	unsubscribes := make([]func(), len(counts))
+	for i := range counts {
+		i := i
+		unsubscribes[i] = cache.Subscribe(func(event Event[string, int]) {
+			counts[i]++
+		})
+	}
+
+	cache.Set("a", 1)
+	unsubscribes[1]()
+	cache.Set("b", 2)
+	cache.Get("b")
+
+	assert.Equal(t, []int{3, 1, 3}, counts)
+
+	unsubscribes[1]()
+	unsubscribes[0]()
+	cache.Set("c", 3)
+	assert.Equal(t, []int{3, 1, 4}, counts)
+}
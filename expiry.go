@@ -0,0 +1,139 @@
+package agecache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// ExpirationQueue enumerates how expiring items are ordered for removal by
+// ActiveExpiration and RemoveExpired. Items are expired on access regardless.
+type ExpirationQueue int
+
+const (
+	// HeapQueue orders items in a min-heap by deadline, so that they are
+	// removed as soon as they expire, at a cost of O(log n) per update.
+	HeapQueue ExpirationQueue = iota
+
+	// TimingWheelQueue places items in a hierarchical timing wheel, with
+	// slots of Config.WheelTick. Updates cost O(1), but items may outlive
+	// their deadline by up to one tick before being removed.
+	TimingWheelQueue
+)
+
+// Width of the slots of the timing wheel when Config.WheelTick is zero
+const defaultWheelTick = 100 * time.Millisecond
+
+// expiryIndex orders the entries which expire by deadline. Its methods are
+// invoked with the cache's mutex held.
+type expiryIndex[K comparable, V any] interface {
+	// schedule queues an entry by its deadline, or requeues it if its
+	// deadline changed
+	schedule(entry *cacheEntry[K, V])
+	// unschedule removes an entry, if queued
+	unschedule(entry *cacheEntry[K, V])
+	// next returns a queued entry whose deadline is before now, or nil if
+	// there are none
+	next(now time.Time) *cacheEntry[K, V]
+	// clear removes all entries
+	clear()
+	// len returns the number of queued entries
+	len() int
+}
+
+func newExpiryIndex[K comparable, V any](config Config[K, V]) expiryIndex[K, V] {
+	if config.ExpirationQueue == TimingWheelQueue {
+		tick := config.WheelTick
+		if tick == 0 {
+			tick = defaultWheelTick
+		}
+		return newTimingWheel[K, V](tick, config.Clock.Now())
+	}
+	return &expiryQueue[K, V]{}
+}
+
+// schedule updates the deadline of the entry after its timestamp or lifetime
+// changed, queueing it if it expires.
+func (cache *Cache[K, V]) schedule(entry *cacheEntry[K, V]) {
+	maxAge := cache.lifetime(entry)
+	if maxAge <= 0 {
+		cache.expiry.unschedule(entry)
+		return
+	}
+
+	entry.deadline = entry.timestamp.Add(maxAge)
+	cache.expiry.schedule(entry)
+}
+
+// reschedule rebuilds the expiry index after a change to the max age of all
+// entries.
+func (cache *Cache[K, V]) reschedule() {
+	cache.expiry.clear()
+	for _, entry := range cache.items {
+		cache.schedule(entry)
+	}
+}
+
+// expiryQueue is a min-heap of the entries which expire, ordered by deadline,
+// implementing HeapQueue.
+type expiryQueue[K comparable, V any] []*cacheEntry[K, V]
+
+func (queue expiryQueue[K, V]) Len() int {
+	return len(queue)
+}
+
+func (queue expiryQueue[K, V]) Less(i, j int) bool {
+	return queue[i].deadline.Before(queue[j].deadline)
+}
+
+func (queue expiryQueue[K, V]) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+	queue[i].expiry = i
+	queue[j].expiry = j
+}
+
+func (queue *expiryQueue[K, V]) Push(x any) {
+	entry := x.(*cacheEntry[K, V])
+	entry.expiry = len(*queue)
+	*queue = append(*queue, entry)
+}
+
+func (queue *expiryQueue[K, V]) Pop() any {
+	old := *queue
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.expiry = -1
+	*queue = old[:len(old)-1]
+	return entry
+}
+
+func (queue *expiryQueue[K, V]) schedule(entry *cacheEntry[K, V]) {
+	if entry.expiry >= 0 {
+		heap.Fix(queue, entry.expiry)
+	} else {
+		heap.Push(queue, entry)
+	}
+}
+
+func (queue *expiryQueue[K, V]) unschedule(entry *cacheEntry[K, V]) {
+	if entry.expiry >= 0 {
+		heap.Remove(queue, entry.expiry)
+	}
+}
+
+func (queue *expiryQueue[K, V]) next(now time.Time) *cacheEntry[K, V] {
+	if len(*queue) == 0 || !now.After((*queue)[0].deadline) {
+		return nil
+	}
+	return (*queue)[0]
+}
+
+func (queue *expiryQueue[K, V]) clear() {
+	for _, entry := range *queue {
+		entry.expiry = -1
+	}
+	*queue = (*queue)[:0]
+}
+
+func (queue *expiryQueue[K, V]) len() int {
+	return len(*queue)
+}
@@ -0,0 +1,60 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryOrder(t *testing.T) {
+	var expired []string
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		OnExpiration: func(key string, value int) {
+			expired = append(expired, key)
+		},
+	})
+
+	cache.SetWithTTL("c", 3, 500*time.Millisecond)
+	cache.SetWithTTL("a", 1, 10*time.Millisecond)
+	cache.SetWithTTL("b", 2, 20*time.Millisecond)
+	cache.Set("d", 4)
+	assert.Equal(t, 3, cache.expiry.len())
+
+	<-time.After(100 * time.Millisecond)
+	assert.Equal(t, 2, cache.RemoveExpired())
+	assert.Equal(t, []string{"a", "b"}, expired)
+
+	// Entries leave the queue once removed, and join it once they expire
+	cache.Remove("c")
+	assert.Equal(t, 0, cache.expiry.len())
+	assert.Equal(t, -1, cache.items["d"].expiry)
+
+	cache.SetMaxAge(time.Hour)
+	assert.Equal(t, 1, cache.expiry.len())
+}
+
+func TestExpiryReschedule(t *testing.T) {
+	cache := New(Config[string, int]{
+		Capacity:         10,
+		MaxAge:           40 * time.Millisecond,
+		ExpirationPolicy: SlidingExpiration,
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	<-time.After(30 * time.Millisecond)
+	cache.Get("a")
+	assert.Equal(t, "b", (*cache.expiry.(*expiryQueue[string, int]))[0].key)
+
+	<-time.After(20 * time.Millisecond)
+	assert.Equal(t, 1, cache.RemoveExpired())
+	assert.True(t, cache.Has("a"))
+
+	// Scaling the max age brings the deadlines forward
+	cache.ScaleMaxAge(0.25)
+	assert.Equal(t, 1, cache.RemoveExpired())
+	assert.Equal(t, 0, cache.expiry.len())
+}
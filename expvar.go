@@ -0,0 +1,20 @@
+package agecache
+
+import "expvar"
+
+// PublishExpvar publishes the live Stats of the cache as an expvar under the
+// given name, such that they are served by /debug/vars. As with
+// expvar.Publish, it panics if the name is already in use.
+func (cache *Cache[K, V]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return cache.Stats()
+	}))
+}
+
+// PublishExpvar publishes the live Stats of the cache, aggregated across all
+// shards, as an expvar under the given name. See Cache.PublishExpvar.
+func (cache *ShardedCache[K, V]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return cache.Stats()
+	}))
+}
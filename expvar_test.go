@@ -0,0 +1,45 @@
+package agecache
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	// Names are unique, as expvars cannot be unpublished between runs
+	name := fmt.Sprint("agecache_test_", time.Now().UnixNano())
+
+	cache := New(Config[string, int]{Capacity: 4})
+	cache.PublishExpvar(name)
+	cache.Set("a", 1)
+	cache.Get("a")
+
+	var stats Stats
+	err := json.Unmarshal([]byte(expvar.Get(name).String()), &stats)
+	assert.NoError(t, err)
+	assert.Equal(t, cache.Stats(), stats)
+	assert.Equal(t, int64(1), stats.Hits)
+
+	assert.Panics(t, func() {
+		cache.PublishExpvar(name)
+	})
+}
+
+func TestShardedPublishExpvar(t *testing.T) {
+	name := fmt.Sprint("agecache_sharded_test_", time.Now().UnixNano())
+
+	cache := NewSharded(ShardedConfig[string, int]{Config: Config[string, int]{Capacity: 4}, Shards: 2})
+	cache.PublishExpvar(name)
+	cache.Set("a", 1)
+
+	var stats Stats
+	err := json.Unmarshal([]byte(expvar.Get(name).String()), &stats)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Count)
+	assert.Equal(t, int64(4), stats.Capacity)
+}
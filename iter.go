@@ -0,0 +1,92 @@
+//go:build go1.23
+
+package agecache
+
+import "iter"
+
+// All returns an iterator over the items in the cache, in no particular
+// order. The items are copied when iteration begins, so the loop body may
+// modify the cache, and iterates a point-in-time snapshot which does not
+// reflect those modifications. Does not update how recently items were
+// accessed or delete those which have expired.
+func (cache *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, entry := range cache.snapshot(false) {
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys in the cache, in no particular
+// order, with the snapshot semantics of All.
+func (cache *Cache[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for _, entry := range cache.snapshot(false) {
+			if !yield(entry.key) {
+				return
+			}
+		}
+	}
+}
+
+// FromOldest returns an iterator over the items in the cache, ordered from
+// oldest to newest as with OrderedKeys, with the snapshot semantics of All.
+func (cache *Cache[K, V]) FromOldest() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, entry := range cache.snapshot(true) {
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// snapshot copies the keys and values of all items in the cache, in eviction
+// order if ordered.
+func (cache *Cache[K, V]) snapshot(ordered bool) []cacheEntry[K, V] {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	entries := make([]cacheEntry[K, V], 0, len(cache.items))
+	if ordered {
+		cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+			entries = append(entries, cacheEntry[K, V]{key: entry.key, value: entry.value})
+			return true
+		})
+		return entries
+	}
+
+	for key, entry := range cache.items {
+		entries = append(entries, cacheEntry[K, V]{key: key, value: entry.value})
+	}
+	return entries
+}
+
+// All returns an iterator over the items in the cache, one shard at a time,
+// with the snapshot semantics of Cache.All. Each shard is copied when
+// iteration reaches it.
+func (cache *ShardedCache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, shard := range cache.shards {
+			for key, value := range shard.All() {
+				if !yield(key, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys in the cache, with the snapshot
+// semantics of ShardedCache.All.
+func (cache *ShardedCache[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range cache.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
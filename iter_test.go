@@ -0,0 +1,71 @@
+//go:build go1.23
+
+package agecache
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterators(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	cache.Set("baz", 3)
+	cache.Get("foo")
+
+	items := make(map[string]int)
+	for key, value := range cache.All() {
+		items[key] = value
+		cache.Remove(key)
+	}
+	assert.Equal(t, map[string]int{"foo": 1, "bar": 2, "baz": 3}, items)
+	assert.Equal(t, 0, cache.Len())
+
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	cache.Set("baz", 3)
+
+	var keys []string
+	for key := range cache.KeysSeq() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"bar", "baz", "foo"}, keys)
+
+	keys = nil
+	for key := range cache.FromOldest() {
+		keys = append(keys, key)
+		if len(keys) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"foo", "bar"}, keys)
+}
+
+func TestShardedIterators(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 100},
+		Shards: 4,
+	})
+	for i := 0; i < 20; i++ {
+		cache.Set(i, i)
+	}
+
+	items := make(map[int]int)
+	for key, value := range cache.All() {
+		items[key] = value
+	}
+	assert.Len(t, items, 20)
+
+	n := 0
+	for range cache.KeysSeq() {
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	assert.Equal(t, 5, n)
+}
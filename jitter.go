@@ -0,0 +1,49 @@
+package agecache
+
+import "time"
+
+// Number of items scheduled to expire within the same second above which the
+// jitter band is widened when JitterThreshold is zero
+const defaultJitterThreshold = 100
+
+// adapt records the second in which an item stored with the given timestamp
+// is scheduled to expire. Once more than JitterThreshold items are scheduled
+// to expire within the same second, the extra jitter applied by getTimestamp
+// is doubled, up to AdaptiveJitter. It is halved again while items are stored
+// in seconds holding at most a quarter of the threshold. The counts are those
+// of stores rather than of items still in the cache, and are discarded once
+// their second has passed.
+func (cache *Cache[K, V]) adapt(timestamp time.Time) {
+	if cache.maxAge == 0 {
+		return
+	}
+
+	now := cache.clock.Now().Unix()
+	if now != cache.pruned {
+		for second := range cache.expiring {
+			if second < now {
+				delete(cache.expiring, second)
+			}
+		}
+		cache.pruned = now
+	}
+
+	second := timestamp.Add(cache.maxAge).Unix()
+	cache.expiring[second]++
+	count := cache.expiring[second]
+
+	if count > cache.jitterThreshold {
+		cache.extraJitter *= 2
+		if cache.extraJitter < time.Second {
+			cache.extraJitter = time.Second
+		}
+		if cache.extraJitter > cache.adaptiveJitter {
+			cache.extraJitter = cache.adaptiveJitter
+		}
+	} else if count <= cache.jitterThreshold/4 && cache.extraJitter > 0 {
+		cache.extraJitter /= 2
+		if cache.extraJitter < time.Second {
+			cache.extraJitter = 0
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package agecache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveJitter(t *testing.T) {
+	cache := New(Config[string, int]{
+		Capacity:        1000,
+		MaxAge:          time.Hour,
+		AdaptiveJitter:  time.Minute,
+		JitterThreshold: 10,
+	})
+
+	start := time.Now()
+	for i := 0; i < 500; i++ {
+		cache.Set(strconv.Itoa(i), i)
+	}
+
+	assert.True(t, cache.extraJitter > 0)
+	assert.True(t, cache.extraJitter <= time.Minute)
+
+	ttls := make(map[int64]int)
+	for i := 0; i < 500; i++ {
+		ttl, ok := cache.TTL(strconv.Itoa(i))
+		assert.True(t, ok)
+		assert.True(t, ttl > time.Hour-time.Minute-time.Since(start))
+		ttls[int64(ttl/time.Second)]++
+	}
+	assert.True(t, len(ttls) > 10, "expirations are spread across seconds")
+
+	// Stores in sparse seconds halve the extra jitter again
+	cache.extraJitter = 4 * time.Second
+	cache.adapt(time.Now().Add(time.Hour))
+	assert.Equal(t, 2*time.Second, cache.extraJitter)
+}
+
+func TestAdaptiveJitterDisabled(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 1000, MaxAge: time.Hour})
+	for i := 0; i < 500; i++ {
+		cache.Set(strconv.Itoa(i), i)
+	}
+	assert.Zero(t, cache.extraJitter)
+	assert.Empty(t, cache.expiring)
+
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, AdaptiveJitter: -1})
+	})
+}
+
+func TestAdaptiveJitterMinAge(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, MaxAge: time.Hour, MinAge: time.Minute, AdaptiveJitter: time.Minute})
+	})
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, MaxAge: time.Minute, AdaptiveJitter: 2 * time.Minute})
+	})
+
+	// A lowered min age leaves items with a positive age
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{
+		Capacity:        1000,
+		MaxAge:          time.Hour,
+		MinAge:          time.Hour,
+		AdaptiveJitter:  time.Minute,
+		JitterThreshold: 1,
+		Clock:           clock,
+	})
+	assert.NoError(t, cache.SetMinAge(time.Second))
+	cache.extraJitter = time.Minute
+
+	for i := 0; i < 100; i++ {
+		cache.Set(strconv.Itoa(i), i)
+	}
+	for i := 0; i < 100; i++ {
+		_, ok := cache.Get(strconv.Itoa(i))
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,66 @@
+package agecache
+
+import "sync"
+
+// keyLock is the lock of a key, shared by the callers of WithKeyLock and
+// WithKeyRLock for the key until they all release it.
+type keyLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// WithKeyLock invokes fn while holding an exclusive lock on `key`, so that
+// multi-step operations on the key, such as reading its item, updating an
+// external resource and storing the result, do not interleave. The per-key
+// lock is independent of the cache's own lock, so fn may call any method of
+// the cache, but must not call WithKeyLock or WithKeyRLock for the same key.
+// The key need not be in the cache.
+func (cache *Cache[K, V]) WithKeyLock(key K, fn func()) {
+	lock := cache.acquireKeyLock(key)
+	defer cache.releaseKeyLock(key)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	fn()
+}
+
+// WithKeyRLock invokes fn while holding a shared lock on `key`, excluding
+// callers of WithKeyLock for the key but not other callers of WithKeyRLock.
+// See WithKeyLock.
+func (cache *Cache[K, V]) WithKeyRLock(key K, fn func()) {
+	lock := cache.acquireKeyLock(key)
+	defer cache.releaseKeyLock(key)
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	fn()
+}
+
+// acquireKeyLock returns the lock of the key, referencing it until released.
+func (cache *Cache[K, V]) acquireKeyLock(key K) *keyLock {
+	cache.keyLockMutex.Lock()
+	defer cache.keyLockMutex.Unlock()
+
+	lock, ok := cache.keyLocks[key]
+	if !ok {
+		lock = &keyLock{}
+		cache.keyLocks[key] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// releaseKeyLock drops a reference to the lock of the key, discarding the lock
+// once it is unreferenced so that locks are only held for keys in use.
+func (cache *Cache[K, V]) releaseKeyLock(key K) {
+	cache.keyLockMutex.Lock()
+	defer cache.keyLockMutex.Unlock()
+
+	lock := cache.keyLocks[key]
+	lock.refs--
+	if lock.refs == 0 {
+		delete(cache.keyLocks, key)
+	}
+}
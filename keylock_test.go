@@ -0,0 +1,52 @@
+package agecache
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithKeyLock(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	cache.Set("foo", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.WithKeyLock("foo", func() {
+				value, _ := cache.Get("foo")
+				cache.Set("foo", value+1)
+			})
+		}()
+	}
+	wg.Wait()
+
+	value, _ := cache.Get("foo")
+	assert.Equal(t, 50, value)
+	assert.Empty(t, cache.keyLocks)
+}
+
+func TestWithKeyLockIndependent(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+
+	// Other keys, and shared locks on the same key, are not excluded
+	cache.WithKeyLock("foo", func() {
+		cache.WithKeyLock("bar", func() {
+			cache.Set("bar", 1)
+		})
+	})
+
+	cache.WithKeyRLock("foo", func() {
+		done := make(chan struct{})
+		go cache.WithKeyRLock("foo", func() {
+			close(done)
+		})
+		<-done
+	})
+
+	assert.True(t, cache.Has("bar"))
+	assert.Empty(t, cache.keyLocks)
+}
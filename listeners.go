@@ -0,0 +1,74 @@
+package agecache
+
+// Listener receives the items evicted or expired from a cache, as registered
+// by AddListener, such that subsystems sharing a cache may each listen for
+// their own keys alone.
+type Listener[K comparable, V any] struct {
+	// Optional predicate selecting the keys the listener is notified of, such
+	// as those with the prefix of a subsystem. Defaults to all keys
+	Keys func(key K) bool
+	// Optional callback invoked with each selected item evicted by the
+	// eviction policy
+	OnEviction func(key K, value V)
+	// Optional callback invoked with each selected item removed for having
+	// expired
+	OnExpiration func(key K, value V)
+}
+
+// AddListener registers listener to be notified of the items evicted or
+// expired from the cache, in addition to the OnEviction and OnExpiration
+// callbacks and to other listeners. As with other callbacks, Keys and the
+// callbacks of the listener are invoked once the cache's lock has been
+// released. Returns a function which removes the listener.
+func (cache *Cache[K, V]) AddListener(listener Listener[K, V]) (remove func()) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	l := &listener
+	// Copied on write, as queued notifications share the slice
+	cache.listeners = append(cache.listeners[:len(cache.listeners):len(cache.listeners)], l)
+
+	return func() {
+		cache.mutex.Lock()
+		defer cache.mutex.Unlock()
+
+		for i, other := range cache.listeners {
+			if other == l {
+				listeners := make([]*Listener[K, V], 0, len(cache.listeners)-1)
+				listeners = append(listeners, cache.listeners[:i]...)
+				cache.listeners = append(listeners, cache.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// AddListener registers listener to be notified of the items evicted or
+// expired from any shard. See Cache.AddListener.
+func (cache *ShardedCache[K, V]) AddListener(listener Listener[K, V]) (remove func()) {
+	removes := make([]func(), len(cache.shards))
+	for i, shard := range cache.shards {
+		removes[i] = shard.AddListener(listener)
+	}
+
+	return func() {
+		for _, remove := range removes {
+			remove()
+		}
+	}
+}
+
+// notifyListeners invokes the callback of each listener selecting the key
+// for the reason of its removal.
+func notifyListeners[K comparable, V any](listeners []*Listener[K, V], key K, value V, reason RemovalReason) {
+	for _, listener := range listeners {
+		callback := listener.OnEviction
+		if reason == Expired {
+			callback = listener.OnExpiration
+		}
+
+		if callback != nil && (listener.Keys == nil || listener.Keys(key)) {
+			callback(key, value)
+		}
+	}
+}
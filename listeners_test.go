@@ -0,0 +1,89 @@
+package agecache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListeners(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 2, MaxAge: time.Minute, Clock: clock})
+
+	var users, sessions, all []string
+	removeUsers := cache.AddListener(Listener[string, int]{
+		Keys: func(key string) bool { return strings.HasPrefix(key, "user:") },
+		OnEviction: func(key string, value int) {
+			users = append(users, "evicted "+key)
+		},
+		OnExpiration: func(key string, value int) {
+			users = append(users, "expired "+key)
+		},
+	})
+	cache.AddListener(Listener[string, int]{
+		Keys: func(key string) bool { return strings.HasPrefix(key, "session:") },
+		OnEviction: func(key string, value int) {
+			sessions = append(sessions, "evicted "+key)
+		},
+	})
+	cache.AddListener(Listener[string, int]{
+		OnExpiration: func(key string, value int) {
+			all = append(all, "expired "+key)
+		},
+	})
+
+	cache.Set("user:1", 1)
+	cache.Set("session:1", 1)
+	cache.Set("user:2", 2)
+	cache.Set("session:2", 2)
+	assert.Equal(t, []string{"evicted user:1"}, users)
+	assert.Equal(t, []string{"evicted session:1"}, sessions)
+	assert.Empty(t, all)
+
+	// Removals are not notified
+	cache.Remove("session:2")
+
+	clock.Advance(2 * time.Minute)
+	cache.RemoveExpired()
+	assert.Equal(t, []string{"evicted user:1", "expired user:2"}, users)
+	assert.Equal(t, []string{"evicted session:1"}, sessions)
+	assert.Equal(t, []string{"expired user:2"}, all)
+
+	removeUsers()
+	cache.Set("user:3", 3)
+	cache.Set("user:4", 4)
+	cache.Set("user:5", 5)
+	assert.Len(t, users, 2)
+}
+
+func TestShardedListeners(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 4},
+		Shards: 4,
+	})
+
+	var evicted []int
+	remove := cache.AddListener(Listener[int, int]{
+		Keys: func(key int) bool { return key%2 == 0 },
+		OnEviction: func(key, value int) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	for key := 0; key < 100; key++ {
+		cache.Set(key, key)
+	}
+	assert.NotEmpty(t, evicted)
+	for _, key := range evicted {
+		assert.Zero(t, key%2)
+	}
+
+	remove()
+	n := len(evicted)
+	for key := 100; key < 200; key++ {
+		cache.Set(key, key)
+	}
+	assert.Len(t, evicted, n)
+}
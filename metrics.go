@@ -0,0 +1,132 @@
+package agecache
+
+import (
+	"reflect"
+	"time"
+)
+
+// Default Config.MetricsInterval
+const defaultMetricsInterval = 10 * time.Second
+
+// Name of the histogram of the durations of loads, in seconds
+const loadMetric = "load_seconds"
+
+// MetricKind classifies a metric reported to a MetricsSink.
+type MetricKind int
+
+// Kinds of metric
+const (
+	CounterMetric MetricKind = iota
+	GaugeMetric
+	HistogramMetric
+)
+
+// Metric describes a metric reported to a MetricsSink.
+type Metric struct {
+	Name string
+	Kind MetricKind
+	Help string
+}
+
+// Metrics returns a description of each metric reported to a MetricsSink,
+// such that exporters may register them up front.
+func Metrics() []Metric {
+	metrics := make([]Metric, 0, len(statsMetrics)+1)
+	for _, metric := range statsMetrics {
+		kind := GaugeMetric
+		if metric.counter {
+			kind = CounterMetric
+		}
+		metrics = append(metrics, Metric{Name: metric.name, Kind: kind, Help: metric.help})
+	}
+
+	return append(metrics, Metric{Name: loadMetric, Kind: HistogramMetric, Help: "The duration of loads of the cache, in seconds."})
+}
+
+// MetricsSink receives the metrics of a cache, so that exporters such as the
+// prometheus and statsd packages share a single wiring point, configured by
+// Config.Metrics. Counters and gauges are named after the metric tag of their
+// Stats field, such as "hits" and "count", and the durations of the loaders
+// of GetOrLoad and of Revalidate are observed by the "load_seconds"
+// histogram, as listed by Metrics. Its methods may be invoked concurrently,
+// but never with the cache's lock held. Exporters depending on third-party
+// clients, such as the prometheus package, are modules of their own, so the
+// agecache module itself depends on none.
+type MetricsSink interface {
+	// Count adds delta to the counter of the given name
+	Count(name string, delta int64)
+	// Gauge sets the gauge of the given name to value
+	Gauge(name string, value int64)
+	// Observe records value in the histogram of the given name
+	Observe(name string, value float64)
+}
+
+// statsMetric is a field of Stats reported to a MetricsSink.
+type statsMetric struct {
+	name    string
+	help    string
+	counter bool
+	field   int
+}
+
+// statsMetrics lists the fields of Stats with a metric tag.
+var statsMetrics = func() []statsMetric {
+	var metrics []statsMetric
+
+	t := reflect.TypeOf(Stats{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, ok := field.Tag.Lookup("metric"); ok {
+			metrics = append(metrics, statsMetric{
+				name:    name,
+				help:    "The " + field.Name + " stat of the cache.",
+				counter: field.Tag.Get("type") == "counter",
+				field:   i,
+			})
+		}
+	}
+
+	return metrics
+}()
+
+// reportMetrics reports the stats returned by stats to sink every interval,
+// and once more when done is closed.
+func reportMetrics(sink MetricsSink, interval time.Duration, clock Clock, stats func() Stats, done <-chan struct{}) {
+	ticker := clock.NewTicker(interval)
+	previous := stats()
+
+	report := func() {
+		current := stats()
+		delta := reflect.ValueOf(current.Delta(previous))
+		values := reflect.ValueOf(current)
+		for _, metric := range statsMetrics {
+			if metric.counter {
+				sink.Count(metric.name, delta.Field(metric.field).Int())
+			} else {
+				sink.Gauge(metric.name, values.Field(metric.field).Int())
+			}
+		}
+		previous = current
+	}
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.Chan():
+				report()
+			case <-done:
+				report()
+				return
+			}
+		}
+	}()
+}
+
+// observeLoad records the duration of a load started at start, if reporting
+// metrics.
+func (cache *Cache[K, V]) observeLoad(start time.Time) {
+	if cache.metrics != nil {
+		cache.metrics.Observe(loadMetric, cache.clock.Now().Sub(start).Seconds())
+	}
+}
@@ -0,0 +1,123 @@
+package agecache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSink is a MetricsSink recording the latest value of each counter, gauge
+// and histogram.
+type testSink struct {
+	mutex        sync.Mutex
+	counters     map[string]int64
+	gauges       map[string]int64
+	observations map[string][]float64
+}
+
+func newTestSink() *testSink {
+	return &testSink{
+		counters:     map[string]int64{},
+		gauges:       map[string]int64{},
+		observations: map[string][]float64{},
+	}
+}
+
+func (sink *testSink) Count(name string, delta int64) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.counters[name] += delta
+}
+
+func (sink *testSink) Gauge(name string, value int64) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.gauges[name] = value
+}
+
+func (sink *testSink) Observe(name string, value float64) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.observations[name] = append(sink.observations[name], value)
+}
+
+func (sink *testSink) counter(name string) int64 {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.counters[name]
+}
+
+func (sink *testSink) gauge(name string) int64 {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.gauges[name]
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := Metrics()
+	assert.Contains(t, metrics, Metric{Name: "hits", Kind: CounterMetric, Help: "The Hits stat of the cache."})
+	assert.Contains(t, metrics, Metric{Name: "count", Kind: GaugeMetric, Help: "The Count stat of the cache."})
+	assert.Equal(t, Metric{Name: "load_seconds", Kind: HistogramMetric, Help: "The duration of loads of the cache, in seconds."}, metrics[len(metrics)-1])
+}
+
+func TestMetricsSink(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	sink := newTestSink()
+	cache := New(Config[string, int]{Capacity: 1, Metrics: sink, MetricsInterval: time.Second, Clock: clock})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("b")
+
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool {
+		return sink.counter("hits") == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), sink.counter("misses"))
+	assert.Equal(t, int64(1), sink.counter("evictions"))
+	assert.Equal(t, int64(1), sink.gauge("count"))
+	assert.Equal(t, int64(1), sink.gauge("capacity"))
+
+	// Counters are reported as deltas, and once more on Close
+	cache.Get("b")
+	value, err := cache.GetOrLoad(context.Background(), "c", func(ctx context.Context, key string) (int, error) {
+		clock.Advance(time.Millisecond)
+		return 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, value)
+
+	cache.Close()
+	assert.Eventually(t, func() bool {
+		return sink.counter("hits") == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(2), sink.counter("misses"))
+
+	sink.mutex.Lock()
+	assert.Equal(t, []float64{0.001}, sink.observations["load_seconds"])
+	sink.mutex.Unlock()
+}
+
+func TestShardedMetricsSink(t *testing.T) {
+	sink := newTestSink()
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 64, Metrics: sink},
+		Shards: 4,
+	})
+
+	for key := 0; key < 8; key++ {
+		cache.Set(key, key)
+	}
+
+	// Gauges are reported for the cache as a whole
+	cache.Close()
+	assert.Eventually(t, func() bool {
+		return sink.gauge("count") == 8
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(64), sink.gauge("capacity"))
+	assert.Equal(t, int64(8), sink.counter("sets"))
+}
@@ -0,0 +1,280 @@
+package agecache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NamespacedKey is the key of an item in a NamespacedCache, qualified by the
+// name of its Namespace.
+type NamespacedKey[K comparable] struct {
+	Namespace string
+	Key       K
+}
+
+// NamespacedCache partitions a single Cache into any number of Namespaces,
+// such as for users, sessions and configuration, which share its capacity and
+// eviction policy rather than each reserving capacity of its own. Each
+// Namespace has its own keys, Stats and callbacks, and may be cleared alone.
+type NamespacedCache[K comparable, V any] struct {
+	cache *Cache[NamespacedKey[K], V]
+
+	mutex      sync.Mutex
+	namespaces map[string]*Namespace[K, V]
+}
+
+// Namespace is a view of the items of a NamespacedCache within one namespace.
+// Its methods behave as those of Cache, on its items alone.
+type Namespace[K comparable, V any] struct {
+	// Namespace statistics, first so that they are aligned for atomic access
+	sets        int64
+	gets        int64
+	hits        int64
+	misses      int64
+	evictions   int64
+	expirations int64
+
+	name  string
+	cache *Cache[NamespacedKey[K], V]
+
+	mutex        sync.RWMutex
+	onEviction   func(key K, value V)
+	onExpiration func(key K, value V)
+	onRemoval    func(key K, value V, reason RemovalReason)
+}
+
+// NewNamespaced constructs a NamespacedCache with the given Config object, as
+// with New. The callbacks of config are invoked for the items of every
+// namespace, before those of the item's Namespace. The stats and callbacks of
+// each Namespace are kept even if OnRemoval is later set on the shared Cache.
+func NewNamespaced[K comparable, V any](config Config[NamespacedKey[K], V]) *NamespacedCache[K, V] {
+	namespaced := &NamespacedCache[K, V]{
+		namespaces: make(map[string]*Namespace[K, V]),
+	}
+
+	hook := func(key NamespacedKey[K], value V, reason RemovalReason) {
+		namespaced.Namespace(key.Namespace).removed(key.Key, value, reason)
+	}
+
+	// Chained to OnRemoval while New stores any Preload, and then installed
+	// as the cache's removal hook, so that OnRemoval may be replaced
+	onRemoval := config.OnRemoval
+	config.OnRemoval = func(key NamespacedKey[K], value V, reason RemovalReason) {
+		if onRemoval != nil {
+			onRemoval(key, value, reason)
+		}
+		hook(key, value, reason)
+	}
+	namespaced.cache = New(config)
+
+	namespaced.cache.mutex.Lock()
+	namespaced.cache.onRemoval = onRemoval
+	namespaced.cache.removalHook = hook
+	namespaced.cache.mutex.Unlock()
+
+	// Namespaces of the items removed by New precede the cache
+	namespaced.mutex.Lock()
+	for _, namespace := range namespaced.namespaces {
+		namespace.cache = namespaced.cache
+	}
+	namespaced.mutex.Unlock()
+
+	return namespaced
+}
+
+// Namespace returns the Namespace of the given name, creating it if it was not
+// yet used. Namespaces cannot be deleted, but are empty once cleared.
+func (namespaced *NamespacedCache[K, V]) Namespace(name string) *Namespace[K, V] {
+	namespaced.mutex.Lock()
+	defer namespaced.mutex.Unlock()
+
+	namespace, ok := namespaced.namespaces[name]
+	if !ok {
+		namespace = &Namespace[K, V]{name: name, cache: namespaced.cache}
+		namespaced.namespaces[name] = namespace
+	}
+	return namespace
+}
+
+// Cache returns the Cache shared by all namespaces.
+func (namespaced *NamespacedCache[K, V]) Cache() *Cache[NamespacedKey[K], V] {
+	return namespaced.cache
+}
+
+// Stats returns the Stats of the cache shared by all namespaces.
+func (namespaced *NamespacedCache[K, V]) Stats() Stats {
+	return namespaced.cache.Stats()
+}
+
+// Close stops active expiration of the shared cache. See Cache.Close.
+func (namespaced *NamespacedCache[K, V]) Close() error {
+	return namespaced.cache.Close()
+}
+
+// Name returns the name of the namespace.
+func (namespace *Namespace[K, V]) Name() string {
+	return namespace.name
+}
+
+// Set updates a key:value pair in the namespace. Returns true if an eviction
+// occurred, possibly of an item of another namespace. See Cache.Set.
+func (namespace *Namespace[K, V]) Set(key K, value V) bool {
+	atomic.AddInt64(&namespace.sets, 1)
+	return namespace.cache.Set(namespace.key(key), value)
+}
+
+// SetWithTTL updates a key:value pair in the namespace, expiring it once ttl
+// has elapsed. See Cache.SetWithTTL.
+func (namespace *Namespace[K, V]) SetWithTTL(key K, value V, ttl time.Duration) bool {
+	atomic.AddInt64(&namespace.sets, 1)
+	return namespace.cache.SetWithTTL(namespace.key(key), value, ttl)
+}
+
+// Get returns the value stored at `key` in the namespace. See Cache.Get.
+func (namespace *Namespace[K, V]) Get(key K) (V, bool) {
+	atomic.AddInt64(&namespace.gets, 1)
+	value, ok := namespace.cache.Get(namespace.key(key))
+	if ok {
+		atomic.AddInt64(&namespace.hits, 1)
+	} else {
+		atomic.AddInt64(&namespace.misses, 1)
+	}
+	return value, ok
+}
+
+// Peek returns the value stored at `key` in the namespace without updating
+// how recently it was accessed. See Cache.Peek.
+func (namespace *Namespace[K, V]) Peek(key K) (V, bool) {
+	return namespace.cache.Peek(namespace.key(key))
+}
+
+// Has returns whether the `key` is in the namespace. See Cache.Has.
+func (namespace *Namespace[K, V]) Has(key K) bool {
+	return namespace.cache.Has(namespace.key(key))
+}
+
+// Remove removes the provided key from the namespace, returning a bool
+// indicating whether it existed. See Cache.Remove.
+func (namespace *Namespace[K, V]) Remove(key K) bool {
+	return namespace.cache.Remove(namespace.key(key))
+}
+
+// Keys returns all keys in the namespace.
+func (namespace *Namespace[K, V]) Keys() []K {
+	cache := namespace.cache
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	var keys []K
+	for key := range cache.items {
+		if key.Namespace == namespace.name {
+			keys = append(keys, key.Key)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of items in the namespace. Takes time proportional
+// to the number of items in all namespaces.
+func (namespace *Namespace[K, V]) Len() int {
+	return len(namespace.Keys())
+}
+
+// Clear removes all items of the namespace, leaving those of other namespaces
+// in place, and invokes the OnRemoval callbacks for each of them. Returns the
+// number of items removed.
+func (namespace *Namespace[K, V]) Clear() int {
+	cache := namespace.cache
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	n := 0
+	for key, entry := range cache.items {
+		if key.Namespace == namespace.name {
+			cache.deleteEntry(entry)
+			cache.notify(nil, entry, Cleared)
+			n++
+		}
+	}
+	return n
+}
+
+// Stats returns the Stats of the namespace. Capacity is that of the shared
+// cache, Gets, Hits and Misses count the calls to Get of the namespace, and
+// Corruptions, Thrashes and Cardinality are not counted. Takes time
+// proportional to the number of items in all namespaces.
+func (namespace *Namespace[K, V]) Stats() Stats {
+	cache := namespace.cache
+	cache.mutex.RLock()
+	stats := Stats{Capacity: int64(cache.capacity)}
+	for key, entry := range cache.items {
+		if key.Namespace == namespace.name {
+			stats.Count++
+			stats.Cost += entry.cost
+		}
+	}
+	cache.mutex.RUnlock()
+
+	stats.Sets = atomic.LoadInt64(&namespace.sets)
+	stats.Gets = atomic.LoadInt64(&namespace.gets)
+	stats.Hits = atomic.LoadInt64(&namespace.hits)
+	stats.Misses = atomic.LoadInt64(&namespace.misses)
+	stats.Evictions = atomic.LoadInt64(&namespace.evictions)
+	stats.Expirations = atomic.LoadInt64(&namespace.expirations)
+	return stats
+}
+
+// OnEviction sets the callback invoked with items of the namespace evicted
+// to make room for any item of the shared cache.
+func (namespace *Namespace[K, V]) OnEviction(callback func(key K, value V)) {
+	namespace.mutex.Lock()
+	defer namespace.mutex.Unlock()
+
+	namespace.onEviction = callback
+}
+
+// OnExpiration sets the callback invoked with expired items of the namespace.
+func (namespace *Namespace[K, V]) OnExpiration(callback func(key K, value V)) {
+	namespace.mutex.Lock()
+	defer namespace.mutex.Unlock()
+
+	namespace.onExpiration = callback
+}
+
+// OnRemoval sets the callback invoked whenever an item leaves the namespace.
+func (namespace *Namespace[K, V]) OnRemoval(callback func(key K, value V, reason RemovalReason)) {
+	namespace.mutex.Lock()
+	defer namespace.mutex.Unlock()
+
+	namespace.onRemoval = callback
+}
+
+func (namespace *Namespace[K, V]) key(key K) NamespacedKey[K] {
+	return NamespacedKey[K]{namespace.name, key}
+}
+
+// removed counts and invokes the callbacks of an item which left the
+// namespace.
+func (namespace *Namespace[K, V]) removed(key K, value V, reason RemovalReason) {
+	namespace.mutex.RLock()
+	onEviction, onExpiration, onRemoval := namespace.onEviction, namespace.onExpiration, namespace.onRemoval
+	namespace.mutex.RUnlock()
+
+	switch reason {
+	case Evicted:
+		atomic.AddInt64(&namespace.evictions, 1)
+		if onEviction != nil {
+			onEviction(key, value)
+		}
+	case Expired:
+		atomic.AddInt64(&namespace.expirations, 1)
+		if onExpiration != nil {
+			onExpiration(key, value)
+		}
+	}
+
+	if onRemoval != nil {
+		onRemoval(key, value, reason)
+	}
+}
@@ -0,0 +1,99 @@
+package agecache
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaces(t *testing.T) {
+	cache := NewNamespaced(Config[NamespacedKey[string], int]{Capacity: 3})
+	users := cache.Namespace("users")
+	sessions := cache.Namespace("sessions")
+	assert.Same(t, users, cache.Namespace("users"))
+
+	var mutex sync.Mutex
+	var evicted []string
+	sessions.OnEviction(func(key string, value int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		evicted = append(evicted, key)
+	})
+
+	// Keys are independent, but the capacity is shared
+	users.Set("foo", 1)
+	sessions.Set("foo", 2)
+	sessions.Set("bar", 3)
+	value, ok := users.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, ok = sessions.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+
+	assert.True(t, users.Set("baz", 4))
+	assert.Equal(t, []string{"bar"}, evicted)
+	assert.Equal(t, int64(3), cache.Stats().Count)
+
+	keys := users.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"baz", "foo"}, keys)
+
+	_, ok = sessions.Get("bar")
+	assert.False(t, ok)
+	assert.Equal(t, Stats{Capacity: 3, Count: 1, Sets: 2, Gets: 2, Hits: 1, Misses: 1, Evictions: 1}, sessions.Stats())
+	assert.Equal(t, Stats{Capacity: 3, Count: 2, Sets: 2, Gets: 1, Hits: 1}, users.Stats())
+
+	// Clearing a namespace leaves the others in place
+	assert.Equal(t, 2, users.Clear())
+	assert.Equal(t, 0, users.Len())
+	assert.True(t, sessions.Has("foo"))
+}
+
+func TestNamespacedCallbacks(t *testing.T) {
+	var removed []NamespacedKey[string]
+	cache := NewNamespaced(Config[NamespacedKey[string], int]{
+		Capacity: 3,
+		OnRemoval: func(key NamespacedKey[string], value int, reason RemovalReason) {
+			removed = append(removed, key)
+		},
+	})
+
+	var reasons []RemovalReason
+	users := cache.Namespace("users")
+	users.OnRemoval(func(key string, value int, reason RemovalReason) {
+		reasons = append(reasons, reason)
+	})
+
+	users.Set("foo", 1)
+	users.Set("foo", 2)
+	cache.Namespace("sessions").Set("foo", 3)
+	users.Remove("foo")
+	cache.Namespace("sessions").Clear()
+
+	assert.Equal(t, []RemovalReason{Replaced, Removed}, reasons)
+	assert.Equal(t, []NamespacedKey[string]{{"users", "foo"}, {"users", "foo"}, {"sessions", "foo"}}, removed)
+}
+
+func TestNamespacedOnRemoval(t *testing.T) {
+	cache := NewNamespaced(Config[NamespacedKey[string], int]{
+		Capacity: 2,
+		Preload: func() map[NamespacedKey[string]]int {
+			return map[NamespacedKey[string]]int{{"users", "a"}: 1, {"users", "b"}: 2, {"users", "c"}: 3}
+		},
+	})
+	users := cache.Namespace("users")
+	assert.Equal(t, int64(1), users.Stats().Evictions)
+
+	// Replacing the callback of the shared cache keeps the namespace stats
+	var removed []NamespacedKey[string]
+	cache.Cache().OnRemoval(func(key NamespacedKey[string], value int, reason RemovalReason) {
+		removed = append(removed, key)
+	})
+
+	users.Set("d", 4)
+	assert.Equal(t, int64(2), users.Stats().Evictions)
+	assert.Len(t, removed, 1)
+}
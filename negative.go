@@ -0,0 +1,53 @@
+package agecache
+
+import "time"
+
+// Error returned by the loader of GetOrLoad, cached for the NegativeTTL
+type negative struct {
+	err     error
+	expires time.Time
+}
+
+// getOrNegative returns the value stored at key as with Get, or if it is
+// missing, the unexpired error cached for it.
+func (cache *Cache[K, V]) getOrNegative(key K) (value V, found bool, err error) {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	if value, found = cache.get(key); found {
+		return value, true, nil
+	}
+
+	if negative, ok := cache.negatives[key]; ok {
+		if cache.clock.Now().Before(negative.expires) {
+			return value, false, negative.err
+		}
+		delete(cache.negatives, key)
+	}
+
+	return value, false, nil
+}
+
+// setNegative caches the error returned by the loader of key. Once Capacity
+// errors are cached, the error is dropped unless some have expired.
+func (cache *Cache[K, V]) setNegative(key K, err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.clock.Now()
+	if len(cache.negatives) >= cache.capacity {
+		for key, negative := range cache.negatives {
+			if !now.Before(negative.expires) {
+				delete(cache.negatives, key)
+			}
+		}
+		if len(cache.negatives) >= cache.capacity {
+			return
+		}
+	}
+
+	if cache.negatives == nil {
+		cache.negatives = make(map[K]negative)
+	}
+	cache.negatives[key] = negative{err: err, expires: now.Add(cache.negativeTTL)}
+}
@@ -0,0 +1,64 @@
+package agecache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegativeTTL(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 2, NegativeTTL: time.Minute, Clock: clock})
+
+	errNotFound := errors.New("not found")
+	loads := 0
+	loader := func(ctx context.Context, key string) (int, error) {
+		loads++
+		return 0, errNotFound
+	}
+
+	// Errors are returned without invoking the loader until they expire
+	_, err := cache.GetOrLoad(context.Background(), "foo", loader)
+	assert.Equal(t, errNotFound, err)
+	_, err = cache.GetOrLoad(context.Background(), "foo", loader)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, 1, loads)
+
+	clock.Advance(2 * time.Minute)
+	_, err = cache.GetOrLoad(context.Background(), "foo", loader)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, 2, loads)
+
+	// Storing an item forgets the error
+	cache.Set("foo", 1)
+	cache.Remove("foo")
+	_, err = cache.GetOrLoad(context.Background(), "foo", loader)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, 3, loads)
+
+	// At most Capacity errors are cached
+	cache.GetOrLoad(context.Background(), "bar", loader)
+	cache.GetOrLoad(context.Background(), "baz", loader)
+	cache.GetOrLoad(context.Background(), "baz", loader)
+	assert.Equal(t, 6, loads)
+}
+
+func TestNegativeTTLCanceled(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 2, NegativeTTL: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.GetOrLoad(ctx, "foo", func(ctx context.Context, key string) (int, error) {
+		return 0, ctx.Err()
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	value, err := cache.GetOrLoad(context.Background(), "foo", func(ctx context.Context, key string) (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+}
@@ -0,0 +1,98 @@
+package agecache
+
+import "time"
+
+// Option configures the Config of a Cache constructed with NewCache. Options
+// are bound to the key and value types of the cache, such that an option of
+// other types fails to compile. Options which do not depend on the types,
+// such as WithMaxAge, are instantiated with them explicitly, while those such
+// as WithOnEviction infer them from their arguments.
+type Option[K comparable, V any] func(config *Config[K, V])
+
+// NewCache constructs a Cache with the given capacity, applying each of the
+// options to its Config in order, then deriving the MinAge of WithJitter.
+// Panics given an invalid configuration, as with New.
+func NewCache[K comparable, V any](capacity int, opts ...Option[K, V]) *Cache[K, V] {
+	config := Config[K, V]{Capacity: capacity}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.jitter > 0 {
+		config.MinAge = config.MaxAge - config.jitter
+	}
+
+	return New(config)
+}
+
+// WithMaxAge sets the duration after which items expire. See Config.MaxAge.
+func WithMaxAge[K comparable, V any](maxAge time.Duration) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.MaxAge = maxAge
+	}
+}
+
+// WithJitter staggers the expiration of items by up to the given duration,
+// such that they live between the max age less the jitter and the max age,
+// whichever option sets the max age. The jitter must be less than the max
+// age, and takes precedence over a MinAge set by WithConfig.
+func WithJitter[K comparable, V any](jitter time.Duration) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.jitter = jitter
+	}
+}
+
+// WithActiveExpiration expires items in the background every interval. See
+// ActiveExpiration.
+func WithActiveExpiration[K comparable, V any](interval time.Duration) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.ExpirationType = ActiveExpiration
+		config.ExpirationInterval = interval
+	}
+}
+
+// WithPolicy sets the policy selecting which items to evict. See
+// Config.Policy.
+func WithPolicy[K comparable, V any](policy EvictionPolicy) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.Policy = policy
+	}
+}
+
+// WithOnEviction sets the callback invoked when an item is evicted. See
+// Config.OnEviction.
+func WithOnEviction[K comparable, V any](callback func(key K, value V)) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.OnEviction = callback
+	}
+}
+
+// WithOnExpiration sets the callback invoked when an item expires. See
+// Config.OnExpiration.
+func WithOnExpiration[K comparable, V any](callback func(key K, value V)) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.OnExpiration = callback
+	}
+}
+
+// WithOnRemoval sets the callback invoked whenever an item leaves the cache.
+// See Config.OnRemoval.
+func WithOnRemoval[K comparable, V any](callback func(key K, value V, reason RemovalReason)) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.OnRemoval = callback
+	}
+}
+
+// WithCost limits the total cost of the items in the cache to maxCost, as
+// computed by cost. See Config.Cost and Config.MaxCost.
+func WithCost[K comparable, V any](cost func(key K, value V) int64, maxCost int64) Option[K, V] {
+	return func(config *Config[K, V]) {
+		config.Cost = cost
+		config.MaxCost = maxCost
+	}
+}
+
+// WithConfig applies override to the Config of the cache, for settings without
+// a dedicated Option.
+func WithConfig[K comparable, V any](override func(config *Config[K, V])) Option[K, V] {
+	return override
+}
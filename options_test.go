@@ -0,0 +1,71 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCache(t *testing.T) {
+	var evicted []string
+
+	cache := NewCache(2,
+		WithMaxAge[string, int](time.Hour),
+		WithJitter[string, int](10*time.Minute),
+		WithPolicy[string, int](LFUEviction),
+		WithOnEviction(func(key string, value int) {
+			evicted = append(evicted, key)
+		}),
+		WithConfig(func(config *Config[string, int]) {
+			config.Checksums = true
+		}),
+	)
+
+	assert.Equal(t, time.Hour, cache.maxAge)
+	assert.Equal(t, 50*time.Minute, cache.minAge)
+	assert.True(t, cache.checksums)
+
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	assert.Equal(t, []string{"b"}, evicted)
+}
+
+func TestNewCacheJitterOrder(t *testing.T) {
+	// Jitter is relative to the max age, whether set before or after it
+	cache := NewCache(1, WithJitter[string, int](time.Second), WithMaxAge[string, int](time.Minute))
+	assert.Equal(t, time.Minute, cache.maxAge)
+	assert.Equal(t, 59*time.Second, cache.minAge)
+}
+
+func TestNewCacheActiveExpiration(t *testing.T) {
+	expired := make(chan string, 1)
+
+	cache := NewCache(1,
+		WithMaxAge[string, int](time.Millisecond),
+		WithActiveExpiration[string, int](time.Millisecond),
+		WithOnExpiration(func(key string, value int) {
+			expired <- key
+		}),
+	)
+	defer cache.Close()
+
+	cache.Set("foo", 1)
+	assert.Equal(t, "foo", <-expired)
+}
+
+func TestNewCacheInvalid(t *testing.T) {
+	assert.Panics(t, func() {
+		NewCache(1, WithMaxAge[string, int](time.Minute), WithJitter[string, int](time.Minute))
+	})
+
+	assert.Panics(t, func() {
+		NewCache(1, WithJitter[string, int](time.Second))
+	})
+
+	assert.Panics(t, func() {
+		NewCache[string, int](0)
+	})
+}
@@ -0,0 +1,59 @@
+package agecache
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Prefixes of the names of the functions of this package and of the compat
+// package, skipped when looking for the call site of a Set
+var libraryPrefixes = []string{
+	"github.com/segmentio/agecache.",
+	"github.com/segmentio/agecache/compat.",
+}
+
+// Resolution of Config.OriginSampleRate
+const originSampleScale = 1 << 20
+
+// origin returns the call site of the operation storing an item, or an empty
+// string if it is not sampled.
+func (cache *Cache[K, V]) origin() string {
+	if cache.originSampleRate == 0 ||
+		cache.rand.Int63n(originSampleScale) >= int64(cache.originSampleRate*originSampleScale) {
+		return ""
+	}
+
+	return callSite()
+}
+
+// callSite returns the file and line of the innermost caller outside of this
+// package and the compat package, such as the caller of Cache.Set or of New
+// storing a Preload. The tests of the packages are callers.
+func callSite() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if !library(frame) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// library returns whether the frame is of a function of this package or of
+// the compat package, other than of their tests.
+func library(frame runtime.Frame) bool {
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	for _, prefix := range libraryPrefixes {
+		if strings.HasPrefix(frame.Function, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package agecache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidOriginSampleRate(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, OriginSampleRate: 2})
+	})
+}
+
+func TestOrigins(t *testing.T) {
+	origin := func(cache *Cache[string, int], key string) string {
+		for _, entry := range cache.Export() {
+			if entry.Key == key {
+				return entry.Origin
+			}
+		}
+		return ""
+	}
+
+	t.Run("not sampled", func(t *testing.T) {
+		cache := New(Config[string, int]{Capacity: 4})
+		cache.Set("a", 1)
+		assert.Equal(t, "", origin(cache, "a"))
+	})
+
+	t.Run("records call site", func(t *testing.T) {
+		cache := New(Config[string, int]{Capacity: 4, OriginSampleRate: 1})
+		cache.Set("a", 1)
+		cache.GetOrLoad(context.Background(), "b", func(ctx context.Context, key string) (int, error) {
+			return 2, nil
+		})
+
+		assert.True(t, strings.Contains(origin(cache, "a"), "origin_test.go:"))
+		assert.True(t, strings.Contains(origin(cache, "b"), "origin_test.go:"))
+		assert.NotEqual(t, origin(cache, "a"), origin(cache, "b"))
+	})
+
+	t.Run("records call site of package functions", func(t *testing.T) {
+		cache := New(Config[string, int]{
+			Capacity:         4,
+			OriginSampleRate: 1,
+			Preload: func() map[string]int {
+				return map[string]int{"a": 1}
+			},
+		})
+		tiered := NewTiered[string, int](Config[string, int]{Capacity: 4, OriginSampleRate: 1}, &mapBackend{values: map[string]int{}})
+		assert.NoError(t, tiered.Set(context.Background(), "b", 2))
+
+		assert.True(t, strings.Contains(origin(cache, "a"), "origin_test.go:"))
+		assert.True(t, strings.Contains(origin(tiered.Cache(), "b"), "origin_test.go:"))
+	})
+
+	t.Run("caller supplied", func(t *testing.T) {
+		cache := New(Config[string, int]{Capacity: 4})
+		cache.SetWithOrigin("a", 1, "importer")
+		assert.Equal(t, "importer", origin(cache, "a"))
+
+		cache.Set("a", 2)
+		assert.Equal(t, "", origin(cache, "a"))
+	})
+}
+
+func TestReportTopOrigins(t *testing.T) {
+	cache := New(Config[string, string]{
+		Capacity: 8,
+		Cost: func(key, value string) int64 {
+			return int64(len(value))
+		},
+	})
+
+	cache.SetWithOrigin("a", "x", "small")
+	cache.SetWithOrigin("b", "x", "small")
+	cache.SetWithOrigin("c", "xxxxxxxx", "huge")
+	cache.Set("d", "xxxxxxxxxxxxxxxx")
+
+	assert.Equal(t, []OriginStats{
+		{Origin: "huge", Count: 1, Cost: 8},
+		{Origin: "small", Count: 2, Cost: 2},
+	}, cache.Report().TopOrigins)
+}
@@ -0,0 +1,149 @@
+// Package otelagecache reports the Stats of agecache caches as OpenTelemetry
+// metrics.
+package otelagecache
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/segmentio/agecache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Prefix of the names of the instruments registered by Register
+const namespace = "agecache."
+
+// Attribute identifying the cache observed by an instrument
+const nameKey = attribute.Key("cache.name")
+
+// Source is implemented by caches whose stats can be registered, such as
+// *agecache.Cache and *agecache.ShardedCache.
+type Source interface {
+	Stats() agecache.Stats
+}
+
+type instrument struct {
+	observable metric.Int64Observable
+	field      int
+}
+
+// Register registers an asynchronous instrument on the meter for each of the
+// stats of the cache, observed with a cache.name attribute of the given name.
+// Gauges are named after the metric tag of their Stats field, such as
+// agecache.count, as are counters, such as agecache.hits. Unregistering the
+// returned registration stops observing the cache.
+func Register(meter metric.Meter, name string, cache Source) (metric.Registration, error) {
+	var instruments []instrument
+	var observables []metric.Observable
+
+	t := reflect.TypeOf(agecache.Stats{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		metricName, ok := field.Tag.Lookup("metric")
+		if !ok {
+			continue
+		}
+
+		description := "The " + field.Name + " stat of the cache"
+
+		var observable metric.Int64Observable
+		var err error
+		if field.Tag.Get("type") == "counter" {
+			observable, err = meter.Int64ObservableCounter(namespace+metricName, metric.WithDescription(description))
+		} else {
+			observable, err = meter.Int64ObservableGauge(namespace+metricName, metric.WithDescription(description))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		instruments = append(instruments, instrument{observable, i})
+		observables = append(observables, observable)
+	}
+
+	attributes := metric.WithAttributes(nameKey.String(name))
+
+	return meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		stats := reflect.ValueOf(cache.Stats())
+		for _, instrument := range instruments {
+			observer.ObserveInt64(instrument.observable, stats.Field(instrument.field).Int(), attributes)
+		}
+		return nil
+	}, observables...)
+}
+
+// Sink implements agecache.MetricsSink, recording the metrics reported by a
+// cache with synchronous instruments, with a cache.name attribute of the name
+// of the cache. Instruments are named as by Register, with counters recorded
+// by Int64Counter instruments, gauges by Int64UpDownCounter instruments, and
+// histograms by Float64Histogram instruments, such as agecache.load_seconds.
+type Sink struct {
+	attributes metric.MeasurementOption
+	counters   map[string]metric.Int64Counter
+	gauges     map[string]*gauge
+	histograms map[string]metric.Float64Histogram
+}
+
+// gauge records a gauge as an up-down counter of the changes to its value.
+type gauge struct {
+	counter metric.Int64UpDownCounter
+	mutex   sync.Mutex
+	value   int64
+}
+
+// NewSink constructs a Sink for the cache of the given name, creating an
+// instrument on the meter for each of agecache.Metrics.
+func NewSink(meter metric.Meter, name string) (*Sink, error) {
+	sink := &Sink{
+		attributes: metric.WithAttributes(nameKey.String(name)),
+		counters:   make(map[string]metric.Int64Counter),
+		gauges:     make(map[string]*gauge),
+		histograms: make(map[string]metric.Float64Histogram),
+	}
+
+	for _, m := range agecache.Metrics() {
+		var err error
+		switch m.Kind {
+		case agecache.CounterMetric:
+			sink.counters[m.Name], err = meter.Int64Counter(namespace+m.Name, metric.WithDescription(m.Help))
+		case agecache.GaugeMetric:
+			var counter metric.Int64UpDownCounter
+			counter, err = meter.Int64UpDownCounter(namespace+m.Name, metric.WithDescription(m.Help))
+			sink.gauges[m.Name] = &gauge{counter: counter}
+		case agecache.HistogramMetric:
+			sink.histograms[m.Name], err = meter.Float64Histogram(namespace+m.Name, metric.WithDescription(m.Help), metric.WithUnit("s"))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sink, nil
+}
+
+// Count implements agecache.MetricsSink.
+func (sink *Sink) Count(name string, delta int64) {
+	if counter, ok := sink.counters[name]; ok {
+		counter.Add(context.Background(), delta, sink.attributes)
+	}
+}
+
+// Gauge implements agecache.MetricsSink.
+func (sink *Sink) Gauge(name string, value int64) {
+	if gauge, ok := sink.gauges[name]; ok {
+		gauge.mutex.Lock()
+		defer gauge.mutex.Unlock()
+
+		gauge.counter.Add(context.Background(), value-gauge.value, sink.attributes)
+		gauge.value = value
+	}
+}
+
+// Observe implements agecache.MetricsSink.
+func (sink *Sink) Observe(name string, value float64) {
+	if histogram, ok := sink.histograms[name]; ok {
+		histogram.Record(context.Background(), value, sink.attributes)
+	}
+}
@@ -0,0 +1,119 @@
+package otelagecache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/agecache"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collect returns the value observed for each instrument and cache name.
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]map[string]int64 {
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+
+	values := map[string]map[string]int64{}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			var points []metricdata.DataPoint[int64]
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				points = data.DataPoints
+			case metricdata.Sum[int64]:
+				assert.True(t, data.IsMonotonic)
+				points = data.DataPoints
+			}
+
+			for _, point := range points {
+				name, _ := point.Attributes.Value(attribute.Key("cache.name"))
+				if values[m.Name] == nil {
+					values[m.Name] = map[string]int64{}
+				}
+				values[m.Name][name.AsString()] = point.Value
+			}
+		}
+	}
+	return values
+}
+
+func TestRegister(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	users := agecache.New(agecache.Config[string, int]{Capacity: 1})
+	users.Set("a", 1)
+	users.Set("b", 2)
+	users.Get("b")
+	users.Get("a")
+
+	sessions := agecache.NewSharded(agecache.ShardedConfig[string, int]{
+		Config: agecache.Config[string, int]{Capacity: 4},
+		Shards: 2,
+	})
+
+	_, err := Register(meter, "users", users)
+	assert.NoError(t, err)
+	registration, err := Register(meter, "sessions", sessions)
+	assert.NoError(t, err)
+
+	values := collect(t, reader)
+	assert.Equal(t, map[string]int64{"users": 1, "sessions": 4}, values["agecache.capacity"])
+	assert.Equal(t, map[string]int64{"users": 1, "sessions": 0}, values["agecache.count"])
+	assert.Equal(t, int64(1), values["agecache.hits"]["users"])
+	assert.Equal(t, int64(1), values["agecache.misses"]["users"])
+	assert.Equal(t, int64(1), values["agecache.evictions"]["users"])
+	assert.Contains(t, values, "agecache.expirations")
+
+	assert.NoError(t, registration.Unregister())
+	sessions.Set("a", 1)
+	users.Get("b")
+
+	values = collect(t, reader)
+	assert.Equal(t, int64(2), values["agecache.hits"]["users"])
+	assert.Equal(t, int64(0), values["agecache.count"]["sessions"])
+}
+
+func TestSink(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	sink, err := NewSink(meter, "users")
+	assert.NoError(t, err)
+
+	sink.Count("hits", 2)
+	sink.Count("hits", 1)
+	sink.Gauge("count", 5)
+	sink.Gauge("count", 3)
+	sink.Observe("load_seconds", 0.5)
+	sink.Count("unknown", 1)
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+
+	values := map[string]int64{}
+	var loads uint64
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range data.DataPoints {
+					name, _ := point.Attributes.Value(attribute.Key("cache.name"))
+					assert.Equal(t, "users", name.AsString())
+					values[m.Name] = point.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range data.DataPoints {
+					loads += point.Count
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, int64(3), values["agecache.hits"])
+	assert.Equal(t, int64(3), values["agecache.count"])
+	assert.Equal(t, uint64(1), loads)
+}
@@ -0,0 +1,261 @@
+package agecache
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Separator of the segments of hierarchical keys, unless configured with
+// Config.PathSeparator
+const defaultPathSeparator = "/"
+
+// pathIndex is a trie of the keys in a cache, split into path segments, such
+// that the keys beneath a path can be found in time proportional to their
+// number.
+type pathIndex[K comparable] struct {
+	root      pathNode[K]
+	separator string
+}
+
+type pathNode[K comparable] struct {
+	children map[string]*pathNode[K]
+	key      K
+	present  bool // Whether key is in the cache, rather than only beneath it
+}
+
+// isStringKey returns whether K is a string type, for which hierarchical keys
+// are supported.
+func isStringKey[K comparable]() bool {
+	return reflect.TypeOf((*K)(nil)).Elem().Kind() == reflect.String
+}
+
+// keyPath returns the string of a key of a string type.
+func keyPath[K comparable](key K) string {
+	if path, ok := any(key).(string); ok {
+		return path
+	}
+	return reflect.ValueOf(key).String()
+}
+
+func (index *pathIndex[K]) insert(key K) {
+	node := &index.root
+	for _, segment := range strings.Split(keyPath(key), index.separator) {
+		child, ok := node.children[segment]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[string]*pathNode[K])
+			}
+			child = &pathNode[K]{}
+			node.children[segment] = child
+		}
+		node = child
+	}
+	node.key = key
+	node.present = true
+}
+
+// remove removes the key, pruning the nodes left without keys beneath them.
+func (index *pathIndex[K]) remove(key K) {
+	segments := strings.Split(keyPath(key), index.separator)
+	nodes := make([]*pathNode[K], 0, len(segments)+1)
+
+	node := &index.root
+	nodes = append(nodes, node)
+	for _, segment := range segments {
+		if node = node.children[segment]; node == nil {
+			return
+		}
+		nodes = append(nodes, node)
+	}
+	node.present = false
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		if child := nodes[i+1]; child.present || len(child.children) > 0 {
+			return
+		}
+		delete(nodes[i].children, segments[i])
+	}
+}
+
+// subtree returns the keys at or beneath the path, ignoring a trailing
+// separator.
+func (index *pathIndex[K]) subtree(path string) []K {
+	node := index.find(strings.Split(strings.TrimSuffix(path, index.separator), index.separator))
+	if node == nil {
+		return nil
+	}
+
+	var keys []K
+	node.walk(func(key K) {
+		keys = append(keys, key)
+	})
+
+	return keys
+}
+
+// prefixed returns the keys starting with prefix, visiting only the keys
+// beneath its last complete segment.
+func (index *pathIndex[K]) prefixed(prefix string) []K {
+	segments := strings.Split(prefix, index.separator)
+	node := index.find(segments[:len(segments)-1])
+	if node == nil {
+		return nil
+	}
+
+	var keys []K
+	node.walk(func(key K) {
+		if strings.HasPrefix(keyPath(key), prefix) {
+			keys = append(keys, key)
+		}
+	})
+
+	return keys
+}
+
+// find returns the node at the path of segments, or nil if there are no keys
+// at or beneath it.
+func (index *pathIndex[K]) find(segments []string) *pathNode[K] {
+	node := &index.root
+	for _, segment := range segments {
+		if node = node.children[segment]; node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// walk visits the keys at or beneath the node.
+func (node *pathNode[K]) walk(fn func(key K)) {
+	if node.present {
+		fn(node.key)
+	}
+	for _, child := range node.children {
+		child.walk(fn)
+	}
+}
+
+// InvalidateSubtree removes the item at the path-like key prefix, and all
+// items beneath it, invoking OnRemoval with the Invalidated reason for each of
+// them. Keys are split into segments on the Config.PathSeparator, "/" by
+// default, so that for example a prefix of "/org/123" matches "/org/123" and
+// "/org/123/projects/1", but not "/org/1234". A trailing separator in the
+// prefix is ignored. With Config.Hierarchical, this takes time proportional
+// to the number of items removed, and otherwise visits every item. Returns
+// the number of items removed, not including their dependents.
+func (cache *Cache[K, V]) InvalidateSubtree(prefix K) int {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	var keys []K
+	if cache.paths != nil {
+		keys = cache.paths.subtree(keyPath(prefix))
+	} else if isStringKey[K]() {
+		path := strings.TrimSuffix(keyPath(prefix), cache.pathSeparator)
+		for key := range cache.items {
+			if s := keyPath(key); s == path || strings.HasPrefix(s, path+cache.pathSeparator) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	removed := 0
+	for _, key := range keys {
+		// Dependents of earlier keys may already have been invalidated
+		if entry, ok := cache.items[key]; ok {
+			cache.deleteEntry(entry)
+			cache.notify(nil, entry, Invalidated)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// RemovePrefix removes every item whose key starts with prefix, such as
+// "tenant:" to flush the items of a tenant, invoking the OnRemove callback for
+// each of them. Unlike InvalidateSubtree, the prefix need not end at a
+// separator. With Config.Hierarchical, this visits only the items beneath the
+// last complete segment of the prefix, and otherwise visits every item.
+// Removes nothing unless keys are of a string type. Returns the number of
+// items removed, not including their dependents.
+func (cache *Cache[K, V]) RemovePrefix(prefix string) int {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	var keys []K
+	if cache.paths != nil {
+		keys = cache.paths.prefixed(prefix)
+	} else if isStringKey[K]() {
+		for key := range cache.items {
+			if strings.HasPrefix(keyPath(key), prefix) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return cache.removeKeys(keys)
+}
+
+// RemoveMatch removes every item whose key is matched by match, invoking the
+// OnRemove callback for each of them. match is invoked with every key while
+// the cache's lock is held, and so must not call back into the cache. Removes
+// nothing unless keys are of a string type. Returns the number of items
+// removed, not including their dependents.
+func (cache *Cache[K, V]) RemoveMatch(match func(key string) bool) int {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	var keys []K
+	if isStringKey[K]() {
+		for key := range cache.items {
+			if match(keyPath(key)) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return cache.removeKeys(keys)
+}
+
+// removeKeys removes the items at keys with the mutex held, returning the
+// number removed.
+func (cache *Cache[K, V]) removeKeys(keys []K) int {
+	removed := 0
+	for _, key := range keys {
+		// Dependents of earlier keys may already have been invalidated
+		if cache.remove(key) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// InvalidateSubtree removes the items at or beneath the path-like key prefix
+// from all shards. See Cache.InvalidateSubtree.
+func (cache *ShardedCache[K, V]) InvalidateSubtree(prefix K) int {
+	removed := 0
+	for _, shard := range cache.shards {
+		removed += shard.InvalidateSubtree(prefix)
+	}
+	return removed
+}
+
+// RemovePrefix removes every item whose key starts with prefix from all
+// shards. See Cache.RemovePrefix.
+func (cache *ShardedCache[K, V]) RemovePrefix(prefix string) int {
+	removed := 0
+	for _, shard := range cache.shards {
+		removed += shard.RemovePrefix(prefix)
+	}
+	return removed
+}
+
+// RemoveMatch removes every item whose key is matched by match from all
+// shards. See Cache.RemoveMatch.
+func (cache *ShardedCache[K, V]) RemoveMatch(match func(key string) bool) int {
+	removed := 0
+	for _, shard := range cache.shards {
+		removed += shard.RemoveMatch(match)
+	}
+	return removed
+}
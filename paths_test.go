@@ -0,0 +1,124 @@
+package agecache
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidHierarchical(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[int, int]{Capacity: 1, Hierarchical: true})
+	})
+}
+
+func TestInvalidateSubtree(t *testing.T) {
+	type path string
+
+	configs := map[string]Config[path, int]{
+		"indexed":   {Capacity: 10, Hierarchical: true},
+		"unindexed": {Capacity: 10},
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			var invalidated []string
+			config.OnRemoval = func(key path, value int, reason RemovalReason) {
+				if reason == Invalidated {
+					invalidated = append(invalidated, string(key))
+				}
+			}
+			cache := New(config)
+
+			cache.Set("/org/123", 1)
+			cache.Set("/org/123/projects/1", 2)
+			cache.Set("/org/123/projects/2", 3)
+			cache.Set("/org/1234", 4)
+			cache.Set("/org", 5)
+
+			assert.Equal(t, 0, cache.InvalidateSubtree("/org/12"))
+			assert.Equal(t, 2, cache.InvalidateSubtree("/org/123/projects/"))
+			assert.Equal(t, 1, cache.InvalidateSubtree("/org/123"))
+			assert.Equal(t, 0, cache.InvalidateSubtree("/missing"))
+
+			sort.Strings(invalidated)
+			assert.Equal(t, []string{"/org/123", "/org/123/projects/1", "/org/123/projects/2"}, invalidated)
+
+			keys := cache.Keys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+			assert.Equal(t, []path{"/org", "/org/1234"}, keys)
+		})
+	}
+}
+
+func TestPathIndexPruning(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 2, Hierarchical: true})
+	cache.Set("/a/b/c", 1)
+	cache.Set("/a/b", 2)
+	cache.Set("/x/y", 3) // Evicts /a/b/c
+	assert.Len(t, cache.paths.root.children[""].children["a"].children["b"].children, 0)
+
+	cache.Remove("/a/b")
+	assert.Len(t, cache.paths.root.children[""].children, 1)
+
+	cache.Clear()
+	assert.Len(t, cache.paths.root.children, 0)
+	assert.Equal(t, 0, cache.InvalidateSubtree("/"))
+}
+
+func TestRemovePrefix(t *testing.T) {
+	configs := map[string]Config[string, int]{
+		"indexed":   {Capacity: 10, Hierarchical: true, PathSeparator: ":"},
+		"unindexed": {Capacity: 10, PathSeparator: ":"},
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			var removed []string
+			config.OnRemove = func(key string, value int) {
+				removed = append(removed, key)
+			}
+			cache := New(config)
+
+			cache.Set("tenant1", 1)
+			cache.Set("tenant1:users:1", 2)
+			cache.Set("tenant1:users:2", 3)
+			cache.Set("tenant1:posts:1", 4)
+			cache.Set("tenant12:users:1", 5)
+
+			assert.Equal(t, 2, cache.RemovePrefix("tenant1:u"))
+			assert.Equal(t, 1, cache.RemovePrefix("tenant1:"))
+			assert.Equal(t, 0, cache.RemovePrefix("missing:"))
+
+			sort.Strings(removed)
+			assert.Equal(t, []string{"tenant1:posts:1", "tenant1:users:1", "tenant1:users:2"}, removed)
+			assert.ElementsMatch(t, []string{"tenant1", "tenant12:users:1"}, cache.Keys())
+
+			// Prefixes need not end at a separator
+			assert.Equal(t, 2, cache.RemovePrefix("tenant"))
+			assert.Equal(t, 0, cache.Len())
+		})
+	}
+}
+
+func TestRemoveMatch(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	cache.Set("a:1", 1)
+	cache.Set("b:1", 2)
+	cache.Set("a:2", 3)
+
+	assert.Equal(t, 2, cache.RemoveMatch(func(key string) bool {
+		return strings.HasPrefix(key, "a:")
+	}))
+	assert.Equal(t, []string{"b:1"}, cache.Keys())
+
+	// Keys of other types are never matched
+	ints := New(Config[int, int]{Capacity: 10})
+	ints.Set(1, 1)
+	assert.Equal(t, 0, ints.RemoveMatch(func(string) bool { return true }))
+	assert.Equal(t, 0, ints.RemovePrefix(""))
+}
@@ -0,0 +1,97 @@
+package agecache
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Persist saves the cache to Config.PersistPath with SaveTo, replacing the
+// file atomically by renaming a temporary file written alongside it. Errors
+// if no PersistPath is configured.
+func (cache *Cache[K, V]) Persist() error {
+	if cache.persistPath == "" {
+		return errors.New("Must supply a config.PersistPath to Persist")
+	}
+
+	cache.persistMutex.Lock()
+	defer cache.persistMutex.Unlock()
+
+	dir, base := filepath.Split(cache.persistPath)
+	file, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if err := cache.writeTo(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), cache.persistPath); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+// writeTo saves the cache to the file, syncing and closing it.
+func (cache *Cache[K, V]) writeTo(file *os.File) error {
+	w := bufio.NewWriter(file)
+	if err := cache.SaveTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// load loads the cache from Config.PersistPath, if it exists.
+func (cache *Cache[K, V]) load() error {
+	file, err := os.Open(cache.persistPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return cache.LoadFrom(bufio.NewReader(file))
+}
+
+// persistEvery saves the cache every interval until it is closed, as driven
+// by the cache's clock. Close waits for the saves to stop.
+func (cache *Cache[K, V]) persistEvery(interval time.Duration) {
+	cache.persisted = make(chan struct{})
+	ticker := cache.clock.NewTicker(interval)
+
+	go func() {
+		defer close(cache.persisted)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.Chan():
+				if err := cache.Persist(); err != nil {
+					cache.persistError(err)
+				}
+			case <-cache.done:
+				return
+			}
+		}
+	}()
+}
+
+func (cache *Cache[K, V]) persistError(err error) {
+	if cache.onPersistError != nil {
+		cache.onPersistError(err)
+	}
+}
@@ -0,0 +1,131 @@
+package agecache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+
+	cache := New(Config[string, int]{Capacity: 10, PersistPath: path})
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	require.NoError(t, cache.Close())
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are renamed")
+
+	restored := New(Config[string, int]{Capacity: 10, PersistPath: path})
+	defer restored.Close()
+	assert.Equal(t, []string{"foo", "bar"}, restored.OrderedKeys())
+}
+
+func TestPersistInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+
+	cache := New(Config[string, int]{
+		Capacity:        10,
+		PersistPath:     path,
+		PersistInterval: 10 * time.Millisecond,
+		OnPersistError: func(err error) {
+			t.Error(err)
+		},
+	})
+	defer cache.Close()
+	cache.Set("foo", 1)
+
+	assert.Eventually(t, func() bool {
+		restored := New(Config[string, int]{Capacity: 10, PersistPath: path})
+		defer restored.Close()
+		return restored.Has("foo")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPersistIntervalClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	clock := NewManualClock(time.Unix(0, 0))
+
+	cache := New(Config[string, int]{
+		Capacity:        10,
+		PersistPath:     path,
+		PersistInterval: time.Minute,
+		Clock:           clock,
+		OnPersistError: func(err error) {
+			t.Error(err)
+		},
+	})
+	defer cache.Close()
+	cache.Set("foo", 1)
+
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	clock.Advance(time.Minute)
+	assert.Eventually(t, func() bool {
+		restored := New(Config[string, int]{Capacity: 10, PersistPath: path})
+		defer restored.Close()
+		return restored.Has("foo")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPersistErrors(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	assert.Error(t, cache.Persist())
+
+	path := filepath.Join(t.TempDir(), "cache")
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o644))
+
+	var errs []error
+	cache = New(Config[string, int]{
+		Capacity:    10,
+		PersistPath: path,
+		OnPersistError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 0, cache.Len())
+
+	// As is a malformed header, rather than panicking
+	var malformed bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&malformed).Encode(saveHeader{Version: saveVersion, Count: -1}))
+	require.NoError(t, os.WriteFile(path, malformed.Bytes(), 0o644))
+	cache, err := NewWithError(Config[string, int]{Capacity: 10, PersistPath: path, OnPersistError: func(err error) {
+		errs = append(errs, err)
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 0, cache.Len())
+
+	cache = New(Config[string, int]{Capacity: 10, PersistPath: filepath.Join(path, "missing", "cache")})
+	assert.Error(t, cache.Close())
+	assert.NoError(t, cache.Close(), "only the first Close saves")
+
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 10, PersistInterval: -1})
+	})
+}
+
+func TestShardedPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	config := ShardedConfig[int, int]{Config: Config[int, int]{Capacity: 64, PersistPath: path}, Shards: 4}
+
+	cache := NewSharded(config)
+	for i := 0; i < 16; i++ {
+		cache.Set(i, i)
+	}
+	require.NoError(t, cache.Close())
+
+	restored := NewSharded(config)
+	defer restored.Close()
+	assert.Equal(t, 16, restored.Len())
+}
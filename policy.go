@@ -0,0 +1,723 @@
+package agecache
+
+import (
+	"container/list"
+	"sort"
+)
+
+// EvictionPolicy enumerates policies selecting which item to evict once the
+// cache is full.
+type EvictionPolicy int
+
+const (
+	// LRUEviction evicts the least recently used item.
+	LRUEviction EvictionPolicy = iota
+
+	// LFUEviction evicts the least frequently used item, where each Set of
+	// and hit on an item counts as a use. Ties are broken by evicting the
+	// least recently used of the items.
+	LFUEviction
+
+	// ARCEviction implements the Adaptive Replacement Cache policy, which
+	// splits the cache between items used once and items used repeatedly,
+	// and adapts the target size of each based on the recently evicted keys
+	// which are requested again. This balances recency and frequency, and
+	// resists being flushed by scans.
+	ARCEviction
+
+	// SLRUEviction implements a segmented LRU policy. Items are inserted
+	// into a probationary segment, and promoted to a protected segment when
+	// used again. Items are evicted from the probationary segment first, so
+	// keys used only once cannot flush the protected segment, whose share of
+	// the capacity is set by Config.ProtectedRatio.
+	SLRUEviction
+)
+
+// Share of the capacity reserved for the protected segment of SLRUEviction
+// when Config.ProtectedRatio is zero
+const defaultProtectedRatio = 0.8
+
+// policy tracks the entries of a cache to select which to evict. Its methods
+// are invoked with the cache's mutex held.
+type policy[K comparable, V any] interface {
+	// add begins tracking a newly inserted entry
+	add(entry *cacheEntry[K, V])
+	// access records a hit on, or an update of, an entry
+	access(entry *cacheEntry[K, V])
+	// remove stops tracking an entry
+	remove(entry *cacheEntry[K, V])
+	// evict stops tracking an entry removed as the victim of an eviction
+	evict(entry *cacheEntry[K, V])
+	// sink marks an entry as the next to evict, as far as the policy allows
+	sink(entry *cacheEntry[K, V])
+	// victim returns the entry to evict next, or nil if there are none
+	victim() *cacheEntry[K, V]
+	// walk visits entries in eviction order, starting with the victim, until
+	// fn returns false
+	walk(fn func(entry *cacheEntry[K, V]) bool)
+	// clear stops tracking all entries
+	clear()
+}
+
+// resizablePolicy is implemented by policies which depend on the capacity of
+// the cache.
+type resizablePolicy interface {
+	resize(capacity int)
+}
+
+// frequencyPolicy is implemented by policies which count the uses of entries,
+// so that the counts may be saved and restored.
+type frequencyPolicy[K comparable, V any] interface {
+	uses(entry *cacheEntry[K, V]) int
+	setUses(entry *cacheEntry[K, V], uses int)
+}
+
+func newPolicy[K comparable, V any](config Config[K, V]) policy[K, V] {
+	if config.CustomPolicy != nil {
+		return newCustomPolicy[K, V](config.CustomPolicy())
+	}
+
+	switch config.Policy {
+	case LFUEviction:
+		return newLFUPolicy[K, V]()
+	case ARCEviction:
+		return newARCPolicy[K, V](config.Capacity)
+	case SLRUEviction:
+		ratio := config.ProtectedRatio
+		if ratio == 0 {
+			ratio = defaultProtectedRatio
+		}
+		return newSLRUPolicy[K, V](config.Capacity, ratio)
+	default:
+		return newLRUPolicy[K, V]()
+	}
+}
+
+// lruPolicy orders entries in a list from most to least recently used.
+type lruPolicy[K comparable, V any] struct {
+	entries *list.List
+}
+
+func newLRUPolicy[K comparable, V any]() *lruPolicy[K, V] {
+	return &lruPolicy[K, V]{entries: list.New()}
+}
+
+func (policy *lruPolicy[K, V]) add(entry *cacheEntry[K, V]) {
+	entry.element = policy.entries.PushFront(entry)
+}
+
+func (policy *lruPolicy[K, V]) access(entry *cacheEntry[K, V]) {
+	policy.entries.MoveToFront(entry.element)
+}
+
+func (policy *lruPolicy[K, V]) remove(entry *cacheEntry[K, V]) {
+	policy.entries.Remove(entry.element)
+	entry.element = nil
+}
+
+func (policy *lruPolicy[K, V]) evict(entry *cacheEntry[K, V]) {
+	policy.remove(entry)
+}
+
+func (policy *lruPolicy[K, V]) sink(entry *cacheEntry[K, V]) {
+	policy.entries.MoveToBack(entry.element)
+}
+
+func (policy *lruPolicy[K, V]) victim() *cacheEntry[K, V] {
+	if element := policy.entries.Back(); element != nil {
+		return element.Value.(*cacheEntry[K, V])
+	}
+	return nil
+}
+
+func (policy *lruPolicy[K, V]) walk(fn func(entry *cacheEntry[K, V]) bool) {
+	for element := policy.entries.Back(); element != nil; element = element.Prev() {
+		if !fn(element.Value.(*cacheEntry[K, V])) {
+			return
+		}
+	}
+}
+
+func (policy *lruPolicy[K, V]) clear() {
+	policy.entries.Init()
+}
+
+// lfuPolicy groups entries into nodes of equal use count, kept in a list
+// ordered from least to most frequently used. Each node orders its entries
+// from most to least recently used.
+type lfuPolicy[K comparable, V any] struct {
+	nodes *list.List
+}
+
+type lfuNode struct {
+	count   int
+	entries *list.List
+}
+
+func newLFUPolicy[K comparable, V any]() *lfuPolicy[K, V] {
+	return &lfuPolicy[K, V]{nodes: list.New()}
+}
+
+func (policy *lfuPolicy[K, V]) add(entry *cacheEntry[K, V]) {
+	node := policy.nodes.Front()
+	if node == nil || node.Value.(*lfuNode).count != 1 {
+		node = policy.nodes.PushFront(&lfuNode{count: 1, entries: list.New()})
+	}
+
+	entry.node = node
+	entry.element = node.Value.(*lfuNode).entries.PushFront(entry)
+}
+
+func (policy *lfuPolicy[K, V]) access(entry *cacheEntry[K, V]) {
+	current := entry.node
+	count := current.Value.(*lfuNode).count + 1
+
+	next := current.Next()
+	if next == nil || next.Value.(*lfuNode).count != count {
+		next = policy.nodes.InsertAfter(&lfuNode{count: count, entries: list.New()}, current)
+	}
+
+	policy.remove(entry)
+	entry.node = next
+	entry.element = next.Value.(*lfuNode).entries.PushFront(entry)
+}
+
+func (policy *lfuPolicy[K, V]) uses(entry *cacheEntry[K, V]) int {
+	return entry.node.Value.(*lfuNode).count
+}
+
+// setUses moves an entry to the node of the given use count, as its most
+// recently used entry.
+func (policy *lfuPolicy[K, V]) setUses(entry *cacheEntry[K, V], uses int) {
+	policy.remove(entry)
+
+	node := policy.nodes.Front()
+	for node != nil && node.Value.(*lfuNode).count < uses {
+		node = node.Next()
+	}
+	if node == nil {
+		node = policy.nodes.PushBack(&lfuNode{count: uses, entries: list.New()})
+	} else if node.Value.(*lfuNode).count != uses {
+		node = policy.nodes.InsertBefore(&lfuNode{count: uses, entries: list.New()}, node)
+	}
+
+	entry.node = node
+	entry.element = node.Value.(*lfuNode).entries.PushFront(entry)
+}
+
+func (policy *lfuPolicy[K, V]) remove(entry *cacheEntry[K, V]) {
+	node := entry.node.Value.(*lfuNode)
+	node.entries.Remove(entry.element)
+	if node.entries.Len() == 0 {
+		policy.nodes.Remove(entry.node)
+	}
+
+	entry.node = nil
+	entry.element = nil
+}
+
+func (policy *lfuPolicy[K, V]) evict(entry *cacheEntry[K, V]) {
+	policy.remove(entry)
+}
+
+// sink moves an entry to the back of the least frequently used node, taking
+// on its use count.
+func (policy *lfuPolicy[K, V]) sink(entry *cacheEntry[K, V]) {
+	front := policy.nodes.Front()
+	if front == entry.node {
+		front.Value.(*lfuNode).entries.MoveToBack(entry.element)
+		return
+	}
+
+	policy.remove(entry)
+	entry.node = front
+	entry.element = front.Value.(*lfuNode).entries.PushBack(entry)
+}
+
+func (policy *lfuPolicy[K, V]) victim() *cacheEntry[K, V] {
+	if node := policy.nodes.Front(); node != nil {
+		return node.Value.(*lfuNode).entries.Back().Value.(*cacheEntry[K, V])
+	}
+	return nil
+}
+
+func (policy *lfuPolicy[K, V]) walk(fn func(entry *cacheEntry[K, V]) bool) {
+	for node := policy.nodes.Front(); node != nil; node = node.Next() {
+		entries := node.Value.(*lfuNode).entries
+		for element := entries.Back(); element != nil; element = element.Prev() {
+			if !fn(element.Value.(*cacheEntry[K, V])) {
+				return
+			}
+		}
+	}
+}
+
+func (policy *lfuPolicy[K, V]) clear() {
+	policy.nodes.Init()
+}
+
+// Segments of the arcPolicy
+const (
+	arcRecent uint8 = iota
+	arcFrequent
+)
+
+// arcPolicy implements ARC, as described by Megiddo and Modha. Entries used
+// once are held in the recent list, and entries used repeatedly in the
+// frequent list, each ordered from most to least recently used. The keys of
+// entries evicted from each list are remembered in a corresponding ghost
+// list. A ghost hit on a key evicted from the recent list grows its target
+// size, and a ghost hit on a key evicted from the frequent list shrinks it.
+type arcPolicy[K comparable, V any] struct {
+	capacity int
+	target   int // Target size of the recent list
+
+	recent   *list.List
+	frequent *list.List
+
+	recentGhosts   *list.List
+	frequentGhosts *list.List
+	ghosts         map[K]*list.Element
+
+	// State of the most recent insertion, consulted while evicting to make
+	// room for it
+	added              *cacheEntry[K, V]
+	addedFrequentGhost bool
+}
+
+// Ghost list entry
+type arcGhost[K comparable] struct {
+	key      K
+	frequent bool
+}
+
+func newARCPolicy[K comparable, V any](capacity int) *arcPolicy[K, V] {
+	return &arcPolicy[K, V]{
+		capacity:       capacity,
+		recent:         list.New(),
+		frequent:       list.New(),
+		recentGhosts:   list.New(),
+		frequentGhosts: list.New(),
+		ghosts:         make(map[K]*list.Element),
+	}
+}
+
+func (policy *arcPolicy[K, V]) add(entry *cacheEntry[K, V]) {
+	policy.added = entry
+	policy.addedFrequentGhost = false
+
+	ghost, ok := policy.ghosts[entry.key]
+	if !ok {
+		entry.segment = arcRecent
+		entry.element = policy.recent.PushFront(entry)
+		policy.trim()
+		return
+	}
+
+	recentGhosts := policy.recentGhosts.Len()
+	frequentGhosts := policy.frequentGhosts.Len()
+
+	if ghost.Value.(*arcGhost[K]).frequent {
+		policy.target -= adaptation(recentGhosts, frequentGhosts)
+		if policy.target < 0 {
+			policy.target = 0
+		}
+		policy.addedFrequentGhost = true
+		policy.frequentGhosts.Remove(ghost)
+	} else {
+		policy.target += adaptation(frequentGhosts, recentGhosts)
+		if policy.target > policy.capacity {
+			policy.target = policy.capacity
+		}
+		policy.recentGhosts.Remove(ghost)
+	}
+
+	delete(policy.ghosts, entry.key)
+	entry.segment = arcFrequent
+	entry.element = policy.frequent.PushFront(entry)
+}
+
+func (policy *arcPolicy[K, V]) access(entry *cacheEntry[K, V]) {
+	policy.list(entry).Remove(entry.element)
+	entry.segment = arcFrequent
+	entry.element = policy.frequent.PushFront(entry)
+}
+
+func (policy *arcPolicy[K, V]) remove(entry *cacheEntry[K, V]) {
+	policy.list(entry).Remove(entry.element)
+	entry.element = nil
+	if policy.added == entry {
+		policy.added = nil
+	}
+}
+
+func (policy *arcPolicy[K, V]) evict(entry *cacheEntry[K, V]) {
+	ghosts := policy.recentGhosts
+	if entry.segment == arcFrequent {
+		ghosts = policy.frequentGhosts
+	}
+
+	policy.remove(entry)
+	policy.ghosts[entry.key] = ghosts.PushFront(&arcGhost[K]{entry.key, entry.segment == arcFrequent})
+	policy.trim()
+}
+
+// sink moves an entry to the back of the recent list. It is the next victim
+// unless the frequent list is over its target size.
+func (policy *arcPolicy[K, V]) sink(entry *cacheEntry[K, V]) {
+	policy.list(entry).Remove(entry.element)
+	entry.segment = arcRecent
+	entry.element = policy.recent.PushBack(entry)
+}
+
+func (policy *arcPolicy[K, V]) victim() *cacheEntry[K, V] {
+	recent := policy.recent.Len()
+	if policy.added != nil && policy.added.segment == arcRecent {
+		recent--
+	}
+
+	fromRecent := recent > 0 &&
+		(recent > policy.target || (policy.addedFrequentGhost && recent == policy.target))
+
+	if (fromRecent || policy.frequent.Len() == 0) && policy.recent.Len() > 0 {
+		return policy.recent.Back().Value.(*cacheEntry[K, V])
+	} else if policy.frequent.Len() > 0 {
+		return policy.frequent.Back().Value.(*cacheEntry[K, V])
+	}
+	return nil
+}
+
+// walk visits the recent list before the frequent list. As the list evicted
+// from depends on the keys inserted, this approximates the eviction order.
+func (policy *arcPolicy[K, V]) walk(fn func(entry *cacheEntry[K, V]) bool) {
+	for _, entries := range []*list.List{policy.recent, policy.frequent} {
+		for element := entries.Back(); element != nil; element = element.Prev() {
+			if !fn(element.Value.(*cacheEntry[K, V])) {
+				return
+			}
+		}
+	}
+}
+
+func (policy *arcPolicy[K, V]) clear() {
+	policy.recent.Init()
+	policy.frequent.Init()
+	policy.recentGhosts.Init()
+	policy.frequentGhosts.Init()
+	policy.ghosts = make(map[K]*list.Element)
+	policy.target = 0
+	policy.added = nil
+}
+
+func (policy *arcPolicy[K, V]) resize(capacity int) {
+	policy.capacity = capacity
+	if policy.target > capacity {
+		policy.target = capacity
+	}
+	policy.trim()
+}
+
+// trim forgets the oldest ghosts so that the recent list and its ghosts hold
+// at most capacity keys, and all lists at most twice the capacity.
+func (policy *arcPolicy[K, V]) trim() {
+	for policy.recent.Len()+policy.recentGhosts.Len() > policy.capacity && policy.recentGhosts.Len() > 0 {
+		policy.forget(policy.recentGhosts)
+	}
+
+	total := policy.recent.Len() + policy.frequent.Len() + policy.recentGhosts.Len() + policy.frequentGhosts.Len()
+	for ; total > 2*policy.capacity && policy.frequentGhosts.Len() > 0; total-- {
+		policy.forget(policy.frequentGhosts)
+	}
+}
+
+// forget drops the oldest ghost from the given ghost list.
+func (policy *arcPolicy[K, V]) forget(ghosts *list.List) {
+	ghost := ghosts.Remove(ghosts.Back()).(*arcGhost[K])
+	delete(policy.ghosts, ghost.key)
+}
+
+// adaptation returns the amount by which to adjust the target size of the
+// recent list on a ghost hit in the list of size hits, given the size of the
+// other ghost list.
+func adaptation(others, hits int) int {
+	if others > hits {
+		return others / hits
+	}
+	return 1
+}
+
+func (policy *arcPolicy[K, V]) list(entry *cacheEntry[K, V]) *list.List {
+	if entry.segment == arcFrequent {
+		return policy.frequent
+	}
+	return policy.recent
+}
+
+// Segments of the slruPolicy
+const (
+	slruProbation uint8 = iota
+	slruProtected
+)
+
+// slruPolicy orders the entries of each segment in a list from most to least
+// recently used. Entries demoted from the protected segment to make room for
+// a promotion become the most recently used probationary entry.
+type slruPolicy[K comparable, V any] struct {
+	ratio     float64
+	protected int // Capacity of the protected segment
+
+	probation *list.List
+	promoted  *list.List
+}
+
+func newSLRUPolicy[K comparable, V any](capacity int, ratio float64) *slruPolicy[K, V] {
+	policy := &slruPolicy[K, V]{
+		ratio:     ratio,
+		probation: list.New(),
+		promoted:  list.New(),
+	}
+	policy.resize(capacity)
+	return policy
+}
+
+func (policy *slruPolicy[K, V]) add(entry *cacheEntry[K, V]) {
+	entry.segment = slruProbation
+	entry.element = policy.probation.PushFront(entry)
+}
+
+func (policy *slruPolicy[K, V]) access(entry *cacheEntry[K, V]) {
+	if entry.segment == slruProtected {
+		policy.promoted.MoveToFront(entry.element)
+		return
+	}
+
+	policy.probation.Remove(entry.element)
+	entry.segment = slruProtected
+	entry.element = policy.promoted.PushFront(entry)
+	policy.demote()
+}
+
+func (policy *slruPolicy[K, V]) remove(entry *cacheEntry[K, V]) {
+	if entry.segment == slruProtected {
+		policy.promoted.Remove(entry.element)
+	} else {
+		policy.probation.Remove(entry.element)
+	}
+	entry.element = nil
+}
+
+func (policy *slruPolicy[K, V]) evict(entry *cacheEntry[K, V]) {
+	policy.remove(entry)
+}
+
+func (policy *slruPolicy[K, V]) sink(entry *cacheEntry[K, V]) {
+	policy.remove(entry)
+	entry.segment = slruProbation
+	entry.element = policy.probation.PushBack(entry)
+}
+
+func (policy *slruPolicy[K, V]) victim() *cacheEntry[K, V] {
+	if element := policy.probation.Back(); element != nil {
+		return element.Value.(*cacheEntry[K, V])
+	} else if element := policy.promoted.Back(); element != nil {
+		return element.Value.(*cacheEntry[K, V])
+	}
+	return nil
+}
+
+func (policy *slruPolicy[K, V]) walk(fn func(entry *cacheEntry[K, V]) bool) {
+	for _, entries := range []*list.List{policy.probation, policy.promoted} {
+		for element := entries.Back(); element != nil; element = element.Prev() {
+			if !fn(element.Value.(*cacheEntry[K, V])) {
+				return
+			}
+		}
+	}
+}
+
+func (policy *slruPolicy[K, V]) clear() {
+	policy.probation.Init()
+	policy.promoted.Init()
+}
+
+func (policy *slruPolicy[K, V]) resize(capacity int) {
+	policy.protected = int(float64(capacity) * policy.ratio)
+	policy.demote()
+}
+
+// demote moves the least recently used protected entries to the probationary
+// segment until the protected segment fits its capacity.
+func (policy *slruPolicy[K, V]) demote() {
+	for policy.promoted.Len() > policy.protected {
+		entry := policy.promoted.Remove(policy.promoted.Back()).(*cacheEntry[K, V])
+		entry.segment = slruProbation
+		entry.element = policy.probation.PushFront(entry)
+	}
+}
+
+// Policy is implemented by custom eviction policies, configured with
+// Config.CustomPolicy. A policy tracks the keys stored in the cache, and
+// selects which to evict once it is full. Its methods are invoked with the
+// cache's mutex held, so need not be thread-safe, but must not call back into
+// the cache.
+//
+// A policy may also implement Walk, Resize and Demote methods, as implemented
+// by LRUPolicy. Walk visits keys in eviction order, starting with the victim,
+// until fn returns false, and defines the order of OrderedKeys and Export.
+// Without it, keys are visited in insertion order. Resize is invoked with the
+// new capacity of the cache when it is resized. Demote marks a tracked key as
+// the next to evict, for Cache.Demote, which otherwise has no effect.
+type Policy[K comparable] interface {
+	// Record begins tracking a newly stored key
+	Record(key K)
+	// Touch records a hit on, or an update of, a tracked key
+	Touch(key K)
+	// Remove stops tracking a key, whether evicted, expired or removed
+	Remove(key K)
+	// Victim returns the tracked key to evict next, reporting false if there
+	// are none
+	Victim() (key K, ok bool)
+}
+
+// LRUPolicy is an implementation of Policy evicting the least recently used
+// key, equivalent to LRUEviction. It is intended as a starting point for
+// custom policies; caches are better served by LRUEviction itself, which
+// avoids the key lookups of a Policy.
+type LRUPolicy[K comparable] struct {
+	keys     *list.List
+	elements map[K]*list.Element
+}
+
+// NewLRUPolicy constructs an empty LRUPolicy.
+func NewLRUPolicy[K comparable]() *LRUPolicy[K] {
+	return &LRUPolicy[K]{
+		keys:     list.New(),
+		elements: make(map[K]*list.Element),
+	}
+}
+
+// Record begins tracking key as the most recently used.
+func (policy *LRUPolicy[K]) Record(key K) {
+	policy.elements[key] = policy.keys.PushFront(key)
+}
+
+// Touch marks key as the most recently used.
+func (policy *LRUPolicy[K]) Touch(key K) {
+	if element, ok := policy.elements[key]; ok {
+		policy.keys.MoveToFront(element)
+	}
+}
+
+// Remove stops tracking key.
+func (policy *LRUPolicy[K]) Remove(key K) {
+	if element, ok := policy.elements[key]; ok {
+		policy.keys.Remove(element)
+		delete(policy.elements, key)
+	}
+}
+
+// Victim returns the least recently used key.
+func (policy *LRUPolicy[K]) Victim() (key K, ok bool) {
+	if element := policy.keys.Back(); element != nil {
+		return element.Value.(K), true
+	}
+	return key, false
+}
+
+// Demote marks key as the least recently used.
+func (policy *LRUPolicy[K]) Demote(key K) {
+	if element, ok := policy.elements[key]; ok {
+		policy.keys.MoveToBack(element)
+	}
+}
+
+// Walk visits keys from least to most recently used, until fn returns false.
+func (policy *LRUPolicy[K]) Walk(fn func(key K) bool) {
+	for element := policy.keys.Back(); element != nil; element = element.Prev() {
+		if !fn(element.Value.(K)) {
+			return
+		}
+	}
+}
+
+// customPolicy adapts a Policy, tracking the entries of its keys.
+type customPolicy[K comparable, V any] struct {
+	policy  Policy[K]
+	entries map[K]*cacheEntry[K, V]
+}
+
+func newCustomPolicy[K comparable, V any](policy Policy[K]) *customPolicy[K, V] {
+	return &customPolicy[K, V]{
+		policy:  policy,
+		entries: make(map[K]*cacheEntry[K, V]),
+	}
+}
+
+func (policy *customPolicy[K, V]) add(entry *cacheEntry[K, V]) {
+	policy.entries[entry.key] = entry
+	policy.policy.Record(entry.key)
+}
+
+func (policy *customPolicy[K, V]) access(entry *cacheEntry[K, V]) {
+	policy.policy.Touch(entry.key)
+}
+
+func (policy *customPolicy[K, V]) remove(entry *cacheEntry[K, V]) {
+	delete(policy.entries, entry.key)
+	policy.policy.Remove(entry.key)
+}
+
+func (policy *customPolicy[K, V]) evict(entry *cacheEntry[K, V]) {
+	policy.remove(entry)
+}
+
+func (policy *customPolicy[K, V]) sink(entry *cacheEntry[K, V]) {
+	if demoter, ok := policy.policy.(interface{ Demote(key K) }); ok {
+		demoter.Demote(entry.key)
+	}
+}
+
+// victim returns nil if the Policy names a key it is not tracking.
+func (policy *customPolicy[K, V]) victim() *cacheEntry[K, V] {
+	key, ok := policy.policy.Victim()
+	if !ok {
+		return nil
+	}
+	return policy.entries[key]
+}
+
+func (policy *customPolicy[K, V]) walk(fn func(entry *cacheEntry[K, V]) bool) {
+	if walker, ok := policy.policy.(interface{ Walk(fn func(key K) bool) }); ok {
+		walker.Walk(func(key K) bool {
+			if entry, ok := policy.entries[key]; ok {
+				return fn(entry)
+			}
+			return true
+		})
+		return
+	}
+
+	entries := make([]*cacheEntry[K, V], 0, len(policy.entries))
+	for _, entry := range policy.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	for _, entry := range entries {
+		if !fn(entry) {
+			return
+		}
+	}
+}
+
+func (policy *customPolicy[K, V]) clear() {
+	for key := range policy.entries {
+		policy.policy.Remove(key)
+	}
+	policy.entries = make(map[K]*cacheEntry[K, V])
+}
+
+func (policy *customPolicy[K, V]) resize(capacity int) {
+	if resizer, ok := policy.policy.(interface{ Resize(capacity int) }); ok {
+		resizer.Resize(capacity)
+	}
+}
@@ -0,0 +1,297 @@
+package agecache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLFUEviction(t *testing.T) {
+	var evicted []string
+
+	cache := New(Config[string, int]{
+		Capacity: 3,
+		Policy:   LFUEviction,
+		OnEviction: func(key string, value int) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("c")
+
+	// b is the least frequently used
+	cache.Set("d", 4)
+	assert.Equal(t, []string{"b"}, evicted)
+
+	// d has been used once, fewer times than c or a
+	cache.Set("e", 5)
+	assert.Equal(t, []string{"b", "d"}, evicted)
+	assert.Equal(t, []string{"e", "c", "a"}, cache.OrderedKeys())
+}
+
+func TestLFUTies(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 3, Policy: LFUEviction})
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Get("b")
+	cache.Get("a")
+
+	// a and b are tied, with b the least recently used of them
+	assert.Equal(t, []string{"c", "b", "a"}, cache.OrderedKeys())
+
+	cache.EvictOldest()
+	cache.EvictOldest()
+	assert.Equal(t, []string{"a"}, cache.OrderedKeys())
+}
+
+func TestLFURemove(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 3, Policy: LFUEviction})
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("b")
+
+	cache.Remove("b")
+	cache.Remove("a")
+	assert.Empty(t, cache.OrderedKeys())
+
+	cache.Set("c", 3)
+	cache.Touch("c")
+	cache.Set("d", 4)
+	assert.Equal(t, []string{"d", "c"}, cache.OrderedKeys())
+
+	cache.Clear()
+	assert.Empty(t, cache.OrderedKeys())
+	cache.Set("e", 5)
+	assert.Equal(t, []string{"e"}, cache.OrderedKeys())
+}
+
+func TestARCScanResistance(t *testing.T) {
+	cache := New(Config[int, int]{Capacity: 4, Policy: ARCEviction})
+	cache.Set(1, 1)
+	cache.Set(2, 2)
+	cache.Get(1)
+	cache.Get(2)
+
+	for i := 100; i < 110; i++ {
+		cache.Set(i, i)
+	}
+
+	assert.True(t, cache.Has(1))
+	assert.True(t, cache.Has(2))
+	assert.Equal(t, 4, cache.Len())
+}
+
+func TestARCGhostHits(t *testing.T) {
+	cache := New(Config[int, int]{Capacity: 2, Policy: ARCEviction})
+	policy := cache.policy.(*arcPolicy[int, int])
+
+	cache.Set(1, 1)
+	cache.Set(2, 2)
+	cache.Get(2)
+	cache.Set(3, 3) // evicts 1 into the recent ghosts
+	assert.False(t, cache.Has(1))
+	assert.Equal(t, 0, policy.target)
+
+	// A recent ghost hit grows the recent target, and is held as frequent
+	cache.Set(1, 1)
+	assert.Equal(t, 1, policy.target)
+	assert.Equal(t, arcFrequent, cache.items[1].segment)
+	assert.True(t, cache.Has(3))
+	assert.False(t, cache.Has(2))
+
+	// A frequent ghost hit shrinks it
+	cache.Set(2, 2)
+	assert.Equal(t, 0, policy.target)
+	assert.Equal(t, arcFrequent, cache.items[2].segment)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestARCResizeAndClear(t *testing.T) {
+	cache := New(Config[int, int]{Capacity: 10, Policy: ARCEviction})
+	for i := 0; i < 20; i++ {
+		cache.Set(i, i)
+		cache.Get(i % 3)
+	}
+
+	cache.Resize(2)
+	policy := cache.policy.(*arcPolicy[int, int])
+	assert.Equal(t, 2, cache.Len())
+	assert.LessOrEqual(t, len(policy.ghosts), 2)
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Len())
+	assert.Empty(t, cache.OrderedKeys())
+
+	cache.Set(1, 1)
+	assert.Equal(t, []int{1}, cache.OrderedKeys())
+}
+
+func TestSLRUScanResistance(t *testing.T) {
+	cache := New(Config[string, int]{
+		Capacity:       4,
+		Policy:         SLRUEviction,
+		ProtectedRatio: 0.5,
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("b")
+
+	// Keys used once only displace each other in the probationary segment
+	for i, key := range []string{"c", "d", "e", "f"} {
+		cache.Set(key, i)
+	}
+	assert.Equal(t, []string{"e", "f", "a", "b"}, cache.OrderedKeys())
+}
+
+func TestSLRUDemotion(t *testing.T) {
+	var evicted []string
+
+	cache := New(Config[string, int]{
+		Capacity:       4,
+		Policy:         SLRUEviction,
+		ProtectedRatio: 0.5,
+		OnEviction: func(key string, value int) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Get("a")
+	cache.Get("b")
+
+	// Promoting c demotes a, the least recently used protected key
+	cache.Get("c")
+	assert.Equal(t, []string{"a", "b", "c"}, cache.OrderedKeys())
+
+	cache.Set("d", 4)
+	cache.Set("e", 5)
+	assert.Equal(t, []string{"a"}, evicted)
+
+	// Shrinking the cache demotes b ahead of the probationary keys
+	cache.Resize(2)
+	assert.Equal(t, []string{"b", "c"}, cache.OrderedKeys())
+}
+
+func TestInvalidProtectedRatio(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, Policy: SLRUEviction, ProtectedRatio: 1.5})
+	})
+}
+
+// smallestPolicy is a custom Policy evicting the smallest key.
+type smallestPolicy struct {
+	keys    map[int]struct{}
+	touches int
+}
+
+func (policy *smallestPolicy) Record(key int) { policy.keys[key] = struct{}{} }
+func (policy *smallestPolicy) Touch(key int)  { policy.touches++ }
+func (policy *smallestPolicy) Remove(key int) { delete(policy.keys, key) }
+
+func (policy *smallestPolicy) Victim() (int, bool) {
+	victim, ok := 0, false
+	for key := range policy.keys {
+		if !ok || key < victim {
+			victim, ok = key, true
+		}
+	}
+	return victim, ok
+}
+
+func TestCustomPolicy(t *testing.T) {
+	policy := &smallestPolicy{keys: make(map[int]struct{})}
+	var evicted []int
+
+	cache := New(Config[int, int]{
+		Capacity:     3,
+		CustomPolicy: func() Policy[int] { return policy },
+		OnEviction: func(key int, value int) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	cache.Set(5, 5)
+	cache.Set(1, 1)
+	cache.Set(9, 9)
+	cache.Get(1)
+	cache.Set(7, 7)
+	assert.Equal(t, []int{1}, evicted)
+	assert.Equal(t, 1, policy.touches)
+
+	// Without a Walk method, keys are ordered by insertion
+	assert.Equal(t, []int{5, 9, 7}, cache.OrderedKeys())
+
+	cache.Remove(5)
+	cache.Set(3, 3)
+	cache.Set(8, 8)
+	assert.Equal(t, []int{1, 3}, evicted)
+
+	cache.Clear()
+	assert.Empty(t, policy.keys)
+	assert.False(t, cache.EvictOldest())
+}
+
+func TestLRUPolicy(t *testing.T) {
+	cache := New(Config[string, int]{
+		Capacity:     3,
+		CustomPolicy: func() Policy[string] { return NewLRUPolicy[string]() },
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	cache.Get("a")
+	cache.Set("d", 4)
+
+	assert.False(t, cache.Has("b"))
+	assert.Equal(t, []string{"c", "a", "d"}, cache.OrderedKeys())
+
+	cache.Resize(1)
+	assert.Equal(t, []string{"d"}, cache.OrderedKeys())
+}
+
+func TestDemotePromote(t *testing.T) {
+	configs := map[string]Config[string, int]{
+		"lru":  {Capacity: 3},
+		"lfu":  {Capacity: 3, Policy: LFUEviction},
+		"arc":  {Capacity: 3, Policy: ARCEviction},
+		"slru": {Capacity: 3, Policy: SLRUEviction},
+		"custom": {Capacity: 3, CustomPolicy: func() Policy[string] {
+			return NewLRUPolicy[string]()
+		}},
+	}
+
+	for name, config := range configs {
+		var evicted []string
+		config.OnEviction = func(key string, value int) {
+			evicted = append(evicted, key)
+		}
+		cache := New(config)
+
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+		cache.Set("c", 3)
+		cache.Get("b")
+
+		assert.True(t, cache.Demote("b"), name)
+		assert.False(t, cache.Demote("x"), name)
+		cache.Set("d", 4)
+		assert.Equal(t, []string{"b"}, evicted, name)
+
+		assert.True(t, cache.Promote("a"), name)
+		assert.False(t, cache.Promote("x"), name)
+		cache.Set("e", 5)
+		assert.Equal(t, []string{"b", "c"}, evicted, name)
+		assert.Equal(t, int64(1), cache.Stats().Hits, name)
+	}
+}
@@ -0,0 +1,71 @@
+package agecache
+
+import "time"
+
+// SmallHotsetConfig returns the Config of a cache holding a small set of
+// frequently used keys. The LFU policy keeps the hottest keys resident
+// despite bursts of keys used once, and jitter staggers their expiration so
+// that they are not all reloaded at once.
+func SmallHotsetConfig[K comparable, V any]() Config[K, V] {
+	return Config[K, V]{
+		Capacity: 1000,
+		MaxAge:   10 * time.Minute,
+		MinAge:   8 * time.Minute,
+		Policy:   LFUEviction,
+	}
+}
+
+// LargeScanResistantConfig returns the Config of a large cache whose workload
+// mixes a working set with scans of keys used once. The ARC policy and
+// TinyLFU admission keep scans from flushing the working set.
+func LargeScanResistantConfig[K comparable, V any]() Config[K, V] {
+	return Config[K, V]{
+		Capacity:  100000,
+		MaxAge:    time.Hour,
+		MinAge:    50 * time.Minute,
+		Policy:    ARCEviction,
+		Admission: TinyLFUAdmission,
+	}
+}
+
+// SessionStoreConfig returns the Config of a cache of user sessions. Sessions
+// expire once idle for 30 minutes, without jitter so that their lifetime is
+// predictable, and are actively expired every minute so that OnExpiration is
+// invoked promptly. Caches constructed with it should be closed with Close.
+func SessionStoreConfig[K comparable, V any]() Config[K, V] {
+	return Config[K, V]{
+		Capacity:           10000,
+		MaxAge:             30 * time.Minute,
+		ExpirationType:     ActiveExpiration,
+		ExpirationPolicy:   SlidingExpiration,
+		ExpirationInterval: time.Minute,
+	}
+}
+
+// NewSmallHotset constructs a Cache with SmallHotsetConfig, after applying
+// each of the overrides to it in order. Panics given an invalid configuration,
+// as with New.
+func NewSmallHotset[K comparable, V any](overrides ...func(config *Config[K, V])) *Cache[K, V] {
+	return newPreset(SmallHotsetConfig[K, V](), overrides)
+}
+
+// NewLargeScanResistant constructs a Cache with LargeScanResistantConfig,
+// after applying each of the overrides to it in order. Panics given an invalid
+// configuration, as with New.
+func NewLargeScanResistant[K comparable, V any](overrides ...func(config *Config[K, V])) *Cache[K, V] {
+	return newPreset(LargeScanResistantConfig[K, V](), overrides)
+}
+
+// NewSessionStore constructs a Cache with SessionStoreConfig, after applying
+// each of the overrides to it in order. Panics given an invalid configuration,
+// as with New.
+func NewSessionStore[K comparable, V any](overrides ...func(config *Config[K, V])) *Cache[K, V] {
+	return newPreset(SessionStoreConfig[K, V](), overrides)
+}
+
+func newPreset[K comparable, V any](config Config[K, V], overrides []func(config *Config[K, V])) *Cache[K, V] {
+	for _, override := range overrides {
+		override(&config)
+	}
+	return New(config)
+}
@@ -0,0 +1,45 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresets(t *testing.T) {
+	hotset := NewSmallHotset[string, int]()
+	assert.Equal(t, int64(1000), hotset.Stats().Capacity)
+
+	scans := NewLargeScanResistant[string, int]()
+	assert.Equal(t, int64(100000), scans.Stats().Capacity)
+
+	sessions := NewSessionStore[string, int]()
+	defer sessions.Close()
+	assert.Equal(t, SlidingExpiration, sessions.expirationPolicy)
+}
+
+func TestPresetOverrides(t *testing.T) {
+	cache := NewSmallHotset(func(config *Config[string, int]) {
+		config.Capacity = 2
+		config.Policy = LRUEviction
+	}, func(config *Config[string, int]) {
+		config.MinAge = config.MaxAge
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("b")
+	cache.Set("c", 3)
+
+	assert.Equal(t, []string{"b", "c"}, cache.OrderedKeys())
+	assert.Equal(t, 10*time.Minute, cache.minAge)
+
+	assert.Panics(t, func() {
+		NewSessionStore(func(config *Config[string, int]) {
+			config.Capacity = 0
+		})
+	})
+}
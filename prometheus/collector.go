@@ -0,0 +1,92 @@
+// Package prometheus exposes the Stats of agecache caches as Prometheus
+// metrics. It is a module of its own, so that importers of agecache alone do
+// not depend on the Prometheus client.
+package prometheus
+
+import (
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/agecache"
+)
+
+// Namespace of the metrics exposed by a Collector
+const namespace = "agecache"
+
+// Source is implemented by caches whose stats a Collector exposes, such as
+// *agecache.Cache and *agecache.ShardedCache.
+type Source interface {
+	Stats() agecache.Stats
+}
+
+// Collector implements prometheus.Collector, exposing each of the stats of a
+// cache as a metric labelled with the name of the cache. Gauges are named
+// after the metric tag of their Stats field, such as agecache_count, and
+// counters have a _total suffix, such as agecache_hits_total.
+type Collector struct {
+	source  Source
+	metrics []metric
+}
+
+type metric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	field     int
+}
+
+// NewCollector constructs a Collector exposing the stats of the cache with a
+// cache label of the given name. Panics given an empty name or a nil cache.
+func NewCollector(name string, cache Source) *Collector {
+	if name == "" {
+		panic("Must supply a name")
+	}
+
+	if cache == nil {
+		panic("Must supply a cache")
+	}
+
+	collector := &Collector{source: cache}
+
+	t := reflect.TypeOf(agecache.Stats{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		metricName, ok := field.Tag.Lookup("metric")
+		if !ok {
+			continue
+		}
+
+		valueType := prometheus.GaugeValue
+		if field.Tag.Get("type") == "counter" {
+			valueType = prometheus.CounterValue
+			metricName += "_total"
+		}
+
+		collector.metrics = append(collector.metrics, metric{
+			desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "", metricName),
+				"The "+field.Name+" stat of the cache.",
+				nil,
+				prometheus.Labels{"cache": name},
+			),
+			valueType: valueType,
+			field:     i,
+		})
+	}
+
+	return collector
+}
+
+// Describe implements prometheus.Collector.
+func (collector *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range collector.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (collector *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := reflect.ValueOf(collector.source.Stats())
+	for _, m := range collector.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, float64(stats.Field(m.field).Int()))
+	}
+}
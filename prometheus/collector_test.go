@@ -0,0 +1,70 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/agecache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidCollector(t *testing.T) {
+	assert.Panics(t, func() {
+		NewCollector("", agecache.New(agecache.Config[string, int]{Capacity: 1}))
+	})
+	assert.Panics(t, func() {
+		NewCollector("users", nil)
+	})
+}
+
+func TestCollector(t *testing.T) {
+	cache := agecache.New(agecache.Config[string, int]{Capacity: 1})
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("b")
+
+	collector := NewCollector("users", cache)
+
+	expected := `
+# HELP agecache_capacity The Capacity stat of the cache.
+# TYPE agecache_capacity gauge
+agecache_capacity{cache="users"} 1
+# HELP agecache_count The Count stat of the cache.
+# TYPE agecache_count gauge
+agecache_count{cache="users"} 1
+# HELP agecache_evictions_total The Evictions stat of the cache.
+# TYPE agecache_evictions_total counter
+agecache_evictions_total{cache="users"} 1
+# HELP agecache_hits_total The Hits stat of the cache.
+# TYPE agecache_hits_total counter
+agecache_hits_total{cache="users"} 1
+# HELP agecache_misses_total The Misses stat of the cache.
+# TYPE agecache_misses_total counter
+agecache_misses_total{cache="users"} 1
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"agecache_capacity", "agecache_count", "agecache_evictions_total", "agecache_hits_total", "agecache_misses_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 12, testutil.CollectAndCount(collector))
+}
+
+func TestCollectorRegistry(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	users := agecache.New(agecache.Config[string, int]{Capacity: 1})
+	sessions := agecache.NewSharded(agecache.ShardedConfig[string, int]{
+		Config: agecache.Config[string, int]{Capacity: 4},
+		Shards: 2,
+	})
+
+	assert.NoError(t, registry.Register(NewCollector("users", users)))
+	assert.NoError(t, registry.Register(NewCollector("sessions", sessions)))
+	assert.Error(t, registry.Register(NewCollector("users", users)))
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, families, 12)
+	assert.Len(t, families[0].GetMetric(), 2)
+}
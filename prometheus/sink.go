@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/agecache"
+)
+
+// Sink implements agecache.MetricsSink, exporting the metrics reported by a
+// cache labelled with the name of the cache. Metrics are named as by a
+// Collector, and histograms after their metric name, such as
+// agecache_load_seconds. Sinks of several caches may share a registerer.
+type Sink struct {
+	counters   map[string]prometheus.Counter
+	gauges     map[string]prometheus.Gauge
+	histograms map[string]prometheus.Observer
+}
+
+// NewSink constructs a Sink for the cache of the given name, registering each
+// of agecache.Metrics with the registerer. Panics given an empty name.
+func NewSink(name string, registerer prometheus.Registerer) (*Sink, error) {
+	if name == "" {
+		panic("Must supply a name")
+	}
+
+	sink := &Sink{
+		counters:   make(map[string]prometheus.Counter),
+		gauges:     make(map[string]prometheus.Gauge),
+		histograms: make(map[string]prometheus.Observer),
+	}
+
+	labels := []string{"cache"}
+	for _, metric := range agecache.Metrics() {
+		switch metric.Kind {
+		case agecache.CounterMetric:
+			vec, err := register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      metric.Name + "_total",
+				Help:      metric.Help,
+			}, labels))
+			if err != nil {
+				return nil, err
+			}
+			sink.counters[metric.Name] = vec.WithLabelValues(name)
+		case agecache.GaugeMetric:
+			vec, err := register(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      metric.Name,
+				Help:      metric.Help,
+			}, labels))
+			if err != nil {
+				return nil, err
+			}
+			sink.gauges[metric.Name] = vec.WithLabelValues(name)
+		case agecache.HistogramMetric:
+			vec, err := register(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      metric.Name,
+				Help:      metric.Help,
+			}, labels))
+			if err != nil {
+				return nil, err
+			}
+			sink.histograms[metric.Name] = vec.WithLabelValues(name)
+		}
+	}
+
+	return sink, nil
+}
+
+// register registers the collector, or returns the equivalent collector
+// already registered by the Sink of another cache.
+func register[C prometheus.Collector](registerer prometheus.Registerer, collector C) (C, error) {
+	err := registerer.Register(collector)
+
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		if existing, ok := registered.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+
+	return collector, err
+}
+
+// Count implements agecache.MetricsSink.
+func (sink *Sink) Count(name string, delta int64) {
+	if counter, ok := sink.counters[name]; ok {
+		counter.Add(float64(delta))
+	}
+}
+
+// Gauge implements agecache.MetricsSink.
+func (sink *Sink) Gauge(name string, value int64) {
+	if gauge, ok := sink.gauges[name]; ok {
+		gauge.Set(float64(value))
+	}
+}
+
+// Observe implements agecache.MetricsSink.
+func (sink *Sink) Observe(name string, value float64) {
+	if histogram, ok := sink.histograms[name]; ok {
+		histogram.Observe(value)
+	}
+}
@@ -0,0 +1,55 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/agecache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+
+	users, err := NewSink("users", registry)
+	assert.NoError(t, err)
+	sessions, err := NewSink("sessions", registry)
+	assert.NoError(t, err)
+
+	users.Count("hits", 2)
+	users.Count("hits", 1)
+	users.Gauge("count", 5)
+	users.Observe("load_seconds", 0.5)
+	sessions.Gauge("count", 7)
+	sessions.Count("unknown", 1)
+
+	expected := `
+# HELP agecache_count The Count stat of the cache.
+# TYPE agecache_count gauge
+agecache_count{cache="sessions"} 7
+agecache_count{cache="users"} 5
+# HELP agecache_hits_total The Hits stat of the cache.
+# TYPE agecache_hits_total counter
+agecache_hits_total{cache="sessions"} 0
+agecache_hits_total{cache="users"} 3
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "agecache_count", "agecache_hits_total"))
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, families, len(agecache.Metrics()))
+
+	assert.Panics(t, func() {
+		NewSink("", registry)
+	})
+}
+
+func TestSinkConflict(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "agecache_hits_total"}))
+
+	_, err := NewSink("users", registry)
+	assert.Error(t, err)
+}
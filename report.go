@@ -0,0 +1,126 @@
+package agecache
+
+import (
+	"sort"
+	"time"
+)
+
+// Number of keys listed in Report.TopKeys, and of origins in
+// Report.TopOrigins
+const reportTopKeys = 10
+
+// Report summarizes the efficiency of a cache, and is intended to be logged
+// periodically as a health check. Ratios are computed from the cumulative
+// counters in Stats, and are zero until the counters they are derived from
+// are non-zero.
+type Report[K comparable] struct {
+	Stats Stats
+
+	HitRatio float64 // Hits per get
+	Churn    float64 // Evictions per set
+
+	// Distribution of the age of the items in the cache, zero when empty
+	MedianAge time.Duration
+	P90Age    time.Duration
+	MaxAge    time.Duration
+
+	// Keys with the most hits since they were last stored, most hit first.
+	// Keys which have not been hit are omitted.
+	TopKeys []K
+
+	// Recorded origins of the items in the cache with the greatest total cost,
+	// or the most items when Cost is nil. Items without an origin are omitted.
+	TopOrigins []OriginStats
+
+	// Estimate of wasted capacity: the number of free slots, and the number of
+	// items which have not been hit since they were last stored.
+	Unused int64
+	Idle   int64
+}
+
+// OriginStats summarizes the items in a cache stored from a single origin.
+type OriginStats struct {
+	Origin string
+	Count  int64
+	Cost   int64
+}
+
+// Report returns an efficiency summary of the cache. It visits every item, so
+// is intended to be called infrequently.
+func (cache *Cache[K, V]) Report() Report[K] {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	report := Report[K]{
+		Stats:  cache.stats(),
+		Unused: int64(cache.capacity - len(cache.items)),
+	}
+
+	if cache.gets > 0 {
+		report.HitRatio = float64(cache.hits) / float64(cache.gets)
+	}
+	if cache.sets > 0 {
+		report.Churn = float64(cache.evictions) / float64(cache.sets)
+	}
+
+	now := cache.clock.Now()
+	ages := make([]time.Duration, 0, len(cache.items))
+	hit := make([]*cacheEntry[K, V], 0, len(cache.items))
+	origins := map[string]*OriginStats{}
+	for _, entry := range cache.items {
+		ages = append(ages, now.Sub(entry.timestamp))
+		if entry.origin != "" {
+			stats, ok := origins[entry.origin]
+			if !ok {
+				stats = &OriginStats{Origin: entry.origin}
+				origins[entry.origin] = stats
+			}
+			stats.Count++
+			stats.Cost += entry.cost
+		}
+		if entry.hits > 0 {
+			hit = append(hit, entry)
+		} else {
+			report.Idle++
+		}
+	}
+
+	if len(ages) > 0 {
+		sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+		report.MedianAge = ages[len(ages)/2]
+		report.P90Age = ages[len(ages)*9/10]
+		report.MaxAge = ages[len(ages)-1]
+	}
+
+	sort.Slice(hit, func(i, j int) bool {
+		if hit[i].hits != hit[j].hits {
+			return hit[i].hits > hit[j].hits
+		}
+		return hit[i].seq < hit[j].seq
+	})
+	if len(hit) > reportTopKeys {
+		hit = hit[:reportTopKeys]
+	}
+	for _, entry := range hit {
+		report.TopKeys = append(report.TopKeys, entry.key)
+	}
+
+	for _, stats := range origins {
+		report.TopOrigins = append(report.TopOrigins, *stats)
+	}
+	sort.Slice(report.TopOrigins, func(i, j int) bool {
+		a, b := report.TopOrigins[i], report.TopOrigins[j]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Origin < b.Origin
+	})
+	if len(report.TopOrigins) > reportTopKeys {
+		report.TopOrigins = report.TopOrigins[:reportTopKeys]
+	}
+
+	return report
+}
@@ -0,0 +1,47 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 4})
+
+	report := cache.Report()
+	assert.Equal(t, 0.0, report.HitRatio)
+	assert.Equal(t, time.Duration(0), report.MaxAge)
+	assert.Equal(t, int64(4), report.Unused)
+
+	cache.Set("a", 1)
+	<-time.After(20 * time.Millisecond)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+
+	cache.Get("b")
+	cache.Get("b")
+	cache.Get("a")
+	cache.Get("missing")
+
+	report = cache.Report()
+	assert.Equal(t, int64(3), report.Stats.Count)
+	assert.Equal(t, 0.75, report.HitRatio)
+	assert.Equal(t, 0.0, report.Churn)
+	assert.Equal(t, []string{"b", "a"}, report.TopKeys)
+	assert.Equal(t, int64(1), report.Unused)
+	assert.Equal(t, int64(1), report.Idle)
+	assert.True(t, report.MaxAge >= 20*time.Millisecond)
+	assert.True(t, report.MedianAge < report.MaxAge)
+
+	// Replacing an item resets its hits
+	cache.Set("b", 4)
+	cache.Set("d", 5)
+	cache.Set("e", 6)
+
+	report = cache.Report()
+	assert.Equal(t, 1.0/6, report.Churn)
+	assert.Equal(t, []string{"a"}, report.TopKeys)
+	assert.Equal(t, int64(3), report.Idle)
+}
@@ -0,0 +1,167 @@
+package agecache
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version of the format written by Cache.SaveTo. Version 2 adds the
+// frequencies of items and of the TinyLFUAdmission sketch, and LoadFrom still
+// reads version 1.
+const saveVersion = 2
+
+// Header and records written by SaveTo.
+type saveHeader struct {
+	Version int
+	Count   int
+	Sketch  *saveSketch // Nil unless using TinyLFUAdmission
+}
+
+type saveRecord[K comparable, V any] struct {
+	Key       K
+	Value     V
+	Timestamp time.Time
+	TTL       time.Duration
+	Uses      int // Zero unless counted by the eviction policy
+}
+
+// Counters of a tinyLFU, without its dimensions, which are implied by the
+// length of its rows and doorkeeper.
+type saveSketch struct {
+	Rows      [sketchDepth][]uint8
+	Door      []uint64
+	Additions int
+}
+
+// SaveTo writes all items in the cache to w with encoding/gob, ordered from
+// oldest to newest, without updating how recently they were accessed. Items
+// are copied with the cache's lock held, but are encoded once it is released.
+// Keys and values of interface types must be registered with gob.Register.
+// Use a SerializableCache to encode values with encoding.BinaryMarshaler.
+//
+// The use counts of LFUEviction and the frequency sketch of TinyLFUAdmission
+// are saved alongside the items, so that a cache loading them does not start
+// out without knowledge of which keys are popular.
+func (cache *Cache[K, V]) SaveTo(w io.Writer) error {
+	cache.mutex.RLock()
+	counter, _ := cache.policy.(frequencyPolicy[K, V])
+	records := make([]saveRecord[K, V], 0, len(cache.items))
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		record := saveRecord[K, V]{Key: entry.key, Value: entry.value, Timestamp: entry.timestamp, TTL: entry.ttl}
+		if counter != nil {
+			record.Uses = counter.uses(entry)
+		}
+		records = append(records, record)
+		return true
+	})
+	var sketch *saveSketch
+	if cache.filter != nil {
+		sketch = cache.filter.save()
+	}
+	cache.mutex.RUnlock()
+
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(saveHeader{saveVersion, len(records), sketch}); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadFrom reads items written by SaveTo from r, storing them with the age
+// they had when saved, and from oldest to newest so that their recency is
+// preserved. Items which have since expired are skipped, and when more items
+// were saved than the cache's capacity, only the newest are stored. Nothing is
+// stored if the items cannot be decoded.
+//
+// With LFUEviction, the stored items keep their saved use counts, and with
+// TinyLFUAdmission, the saved frequency sketch replaces that of the cache if
+// it was saved by a cache of the same capacity.
+func (cache *Cache[K, V]) LoadFrom(r io.Reader) error {
+	decoder := gob.NewDecoder(r)
+
+	var header saveHeader
+	if err := decoder.Decode(&header); err != nil {
+		return err
+	} else if header.Version < 1 || header.Version > saveVersion {
+		return fmt.Errorf("agecache: unsupported save version %d", header.Version)
+	}
+
+	if header.Count < 0 {
+		return fmt.Errorf("agecache: invalid save item count %d", header.Count)
+	}
+
+	entries := make([]cacheEntry[K, V], 0, cache.preallocated(header.Count))
+	uses := make(map[K]int)
+	for i := 0; i < header.Count; i++ {
+		var record saveRecord[K, V]
+		if err := decoder.Decode(&record); err != nil {
+			return err
+		}
+		entries = append(entries, cacheEntry[K, V]{key: record.Key, value: record.Value, timestamp: record.Timestamp, ttl: record.TTL})
+		if record.Uses > 0 {
+			uses[record.Key] = record.Uses
+		}
+	}
+
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	cache.restore(entries)
+
+	if counter, ok := cache.policy.(frequencyPolicy[K, V]); ok {
+		for key, n := range uses {
+			if entry, ok := cache.items[key]; ok {
+				counter.setUses(entry, n)
+			}
+		}
+	}
+
+	// Loaded last, so that the sketch does not count the stores of restore
+	if cache.filter != nil && header.Sketch != nil {
+		cache.filter.load(header.Sketch)
+	}
+
+	return nil
+}
+
+// restore stores the entries, ordered from oldest to newest, with the mutex
+// held. Expired entries are skipped, and only the newest which fit the
+// capacity are stored. Returns the number of entries stored.
+func (cache *Cache[K, V]) restore(entries []cacheEntry[K, V]) int {
+	unexpired := entries[:0]
+	for i := range entries {
+		if !cache.expired(&entries[i]) {
+			unexpired = append(unexpired, entries[i])
+		}
+	}
+	if len(unexpired) > cache.capacity {
+		unexpired = unexpired[len(unexpired)-cache.capacity:]
+	}
+
+	for i := range unexpired {
+		cache.set(unexpired[i].key, unexpired[i].value, unexpired[i].timestamp, unexpired[i].ttl)
+	}
+
+	return len(unexpired)
+}
+
+// preallocated returns the number of entries to allocate up front for count
+// items read from an untrusted stream, at most the capacity of the cache.
+func (cache *Cache[K, V]) preallocated(count int) int {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	if count > cache.capacity {
+		return cache.capacity
+	}
+	return count
+}
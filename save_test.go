@@ -0,0 +1,106 @@
+package agecache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type point struct {
+	X, Y int
+}
+
+func TestSaveToLoadFrom(t *testing.T) {
+	cache := New(Config[string, point]{Capacity: 4, MaxAge: time.Hour})
+	cache.SetWithTTL("expiring", point{0, 0}, 10*time.Millisecond)
+	cache.Set("a", point{1, 2})
+	cache.Set("b", point{3, 4})
+	cache.Set("c", point{5, 6})
+	cache.Get("a")
+	<-time.After(20 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.SaveTo(&buf))
+
+	loaded := New(Config[string, point]{Capacity: 2, MaxAge: time.Hour})
+	assert.NoError(t, loaded.LoadFrom(&buf))
+
+	// The newest unexpired items which fit are loaded, oldest first
+	assert.Equal(t, []string{"c", "a"}, loaded.OrderedKeys())
+	value, ok := loaded.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, point{1, 2}, value)
+
+	// Items keep their age
+	original, _ := cache.TTL("c")
+	ttl, _ := loaded.TTL("c")
+	assert.InDelta(t, original, ttl, float64(time.Second))
+	assert.True(t, ttl < time.Hour-20*time.Millisecond)
+}
+
+func TestLoadFromErrors(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 4})
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.SaveTo(&buf))
+
+	truncated := New(Config[string, int]{Capacity: 4})
+	assert.Error(t, truncated.LoadFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-2])))
+	assert.Equal(t, 0, truncated.Len())
+
+	mismatched := New(Config[string, string]{Capacity: 4})
+	assert.Error(t, mismatched.LoadFrom(&buf))
+
+	// Malformed headers are rejected, or read without trusting their count
+	for _, count := range []int{-1, math.MaxInt} {
+		var malformed bytes.Buffer
+		assert.NoError(t, gob.NewEncoder(&malformed).Encode(saveHeader{Version: saveVersion, Count: count}))
+		assert.Error(t, cache.LoadFrom(&malformed))
+	}
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestSaveToFrequencies(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 3, Policy: LFUEviction, Admission: TinyLFUAdmission})
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+	for i := 0; i < 3; i++ {
+		cache.Get("a")
+	}
+	cache.Get("c")
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.SaveTo(&buf))
+
+	loaded := New(Config[string, int]{Capacity: 3, Policy: LFUEviction, Admission: TinyLFUAdmission})
+	assert.NoError(t, loaded.LoadFrom(&buf))
+
+	// Items keep their use counts, so the least used is evicted first
+	assert.Equal(t, []string{"b", "c", "a"}, loaded.OrderedKeys())
+
+	// The sketch remembers how often keys were used
+	assert.Equal(t, cache.filter.estimate(hashKey("a")), loaded.filter.estimate(hashKey("a")))
+	assert.False(t, loaded.filter.allow("new", "a"))
+	assert.True(t, loaded.filter.allow("a", "new"))
+}
+
+func TestLoadFromMismatchedSketch(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 3, Admission: TinyLFUAdmission})
+	cache.Set("a", 1)
+	cache.Get("a")
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.SaveTo(&buf))
+
+	// The items load, but the sketch of a larger cache is not replaced
+	loaded := New(Config[string, int]{Capacity: 1024, Admission: TinyLFUAdmission})
+	assert.NoError(t, loaded.LoadFrom(&buf))
+	assert.Equal(t, []string{"a"}, loaded.OrderedKeys())
+}
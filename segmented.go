@@ -0,0 +1,199 @@
+package agecache
+
+import (
+	"sort"
+	"time"
+)
+
+// SegmentConfig configures one segment of a SegmentedCache.
+type SegmentConfig struct {
+	// Share of the cache's Capacity and MaxCost given to the segment, relative
+	// to the weights of the other segments. Defaults to 1
+	Weight float64
+	// Optional max age of the segment's items, overriding the MaxAge of the
+	// cache
+	TTL time.Duration
+}
+
+// SegmentedConfig configures a segmented cache.
+type SegmentedConfig[K comparable, V any] struct {
+	// Configuration shared by all segments. Capacity and MaxCost apply to the
+	// segmented cache as a whole, and are divided between the segments by
+	// weight.
+	Config[K, V]
+	// Names and configurations of the segments, such as regions or locales.
+	Segments map[string]SegmentConfig
+}
+
+// SegmentedCache implements a thread-safe cache of variants of the same keys,
+// such as per-region or per-locale values of one logical object. Each segment
+// is an independent Cache, with its own share of the capacity and its own max
+// age, so that one busy segment cannot evict the items of the others.
+type SegmentedCache[K comparable, V any] struct {
+	segments map[string]*Cache[K, V]
+}
+
+// NewSegmented constructs a SegmentedCache with the given SegmentedConfig
+// object. Panics given an invalid configuration, as with New.
+func NewSegmented[K comparable, V any](config SegmentedConfig[K, V]) *SegmentedCache[K, V] {
+	if len(config.Segments) == 0 {
+		panic("Must supply at least one config.Segments")
+	}
+
+	if config.MaxCost < 0 {
+		panic("Must supply a zero or positive config.MaxCost")
+	}
+
+	total := 0.0
+	for _, segment := range config.Segments {
+		if segment.Weight < 0 {
+			panic("Must supply a zero or positive segment Weight")
+		}
+		if segment.TTL < 0 {
+			panic("Must supply a zero or positive segment TTL")
+		}
+		total += weight(segment)
+	}
+
+	cache := &SegmentedCache[K, V]{
+		segments: make(map[string]*Cache[K, V], len(config.Segments)),
+	}
+
+	for name, segment := range config.Segments {
+		share := weight(segment) / total
+
+		segmentConfig := config.Config
+		segmentConfig.Capacity = int(float64(config.Capacity) * share)
+		if segmentConfig.Capacity < 1 {
+			panic("config.Capacity must be large enough to give each segment an item")
+		}
+		segmentConfig.MaxCost = int64(float64(config.MaxCost) * share)
+		if config.MaxCost > 0 && segmentConfig.MaxCost < 1 {
+			panic("config.MaxCost must be large enough to give each segment a cost")
+		}
+		if segment.TTL > 0 {
+			segmentConfig.MaxAge = segment.TTL
+		}
+		if config.PersistPath != "" {
+			segmentConfig.PersistPath = config.PersistPath + "." + name
+		}
+		cache.segments[name] = New(segmentConfig)
+	}
+
+	return cache
+}
+
+// weight returns the Weight of the segment, or its default.
+func weight(segment SegmentConfig) float64 {
+	if segment.Weight == 0 {
+		return 1
+	}
+	return segment.Weight
+}
+
+// Set updates the variant of `key` in the given segment. Returns true if an
+// eviction occurred, and subsequently invokes the OnEviction callback. Items of
+// undeclared segments are not stored.
+func (cache *SegmentedCache[K, V]) Set(key K, segment string, value V) bool {
+	if s, ok := cache.segments[segment]; ok {
+		return s.Set(key, value)
+	}
+	return false
+}
+
+// Get returns the variant of `key` in the given segment. The boolean value
+// reports whether the value was found.
+func (cache *SegmentedCache[K, V]) Get(key K, segment string) (value V, found bool) {
+	if s, ok := cache.segments[segment]; ok {
+		return s.Get(key)
+	}
+	return value, false
+}
+
+// Variants returns the values of `key` in every segment it is stored in,
+// keyed by segment, without updating how recently they were accessed.
+func (cache *SegmentedCache[K, V]) Variants(key K) map[string]V {
+	variants := make(map[string]V)
+	for name, s := range cache.segments {
+		if value, ok := s.Peek(key); ok {
+			variants[name] = value
+		}
+	}
+	return variants
+}
+
+// Remove removes the variant of `key` in the given segment, returning a bool
+// indicating whether it existed.
+func (cache *SegmentedCache[K, V]) Remove(key K, segment string) bool {
+	if s, ok := cache.segments[segment]; ok {
+		return s.Remove(key)
+	}
+	return false
+}
+
+// RemoveAll removes the variants of `key` in every segment, for when the
+// logical object changes. Returns the number of variants removed.
+func (cache *SegmentedCache[K, V]) RemoveAll(key K) int {
+	removed := 0
+	for _, s := range cache.segments {
+		if s.Remove(key) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// FlushSegment empties the given segment, invoking the OnRemoval callback for
+// each item. Returns the number of items removed.
+func (cache *SegmentedCache[K, V]) FlushSegment(segment string) int {
+	if s, ok := cache.segments[segment]; ok {
+		return s.Clear()
+	}
+	return 0
+}
+
+// Segment returns the Cache holding the given segment, or nil if it was not
+// declared.
+func (cache *SegmentedCache[K, V]) Segment(segment string) *Cache[K, V] {
+	return cache.segments[segment]
+}
+
+// Segments returns the sorted names of all segments.
+func (cache *SegmentedCache[K, V]) Segments() []string {
+	names := make([]string, 0, len(cache.segments))
+	for name := range cache.segments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Len returns the number of items in all segments.
+func (cache *SegmentedCache[K, V]) Len() int {
+	n := 0
+	for _, s := range cache.segments {
+		n += s.Len()
+	}
+	return n
+}
+
+// Stats returns cache stats, aggregated across all segments.
+func (cache *SegmentedCache[K, V]) Stats() Stats {
+	caches := make([]*Cache[K, V], 0, len(cache.segments))
+	for _, s := range cache.segments {
+		caches = append(caches, s)
+	}
+	return sumStats(caches)
+}
+
+// Close stops active expiration in all segments, returning the first error of
+// saving them. See Cache.Close.
+func (cache *SegmentedCache[K, V]) Close() error {
+	var err error
+	for _, s := range cache.segments {
+		if closeErr := s.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
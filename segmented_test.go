@@ -0,0 +1,83 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidSegments(t *testing.T) {
+	assert.Panics(t, func() {
+		NewSegmented(SegmentedConfig[string, int]{
+			Config: Config[string, int]{Capacity: 10},
+		})
+	})
+
+	assert.Panics(t, func() {
+		NewSegmented(SegmentedConfig[string, int]{
+			Config:   Config[string, int]{Capacity: 10},
+			Segments: map[string]SegmentConfig{"us": {Weight: -1}},
+		})
+	})
+
+	assert.Panics(t, func() {
+		NewSegmented(SegmentedConfig[string, int]{
+			Config:   Config[string, int]{Capacity: 10},
+			Segments: map[string]SegmentConfig{"us": {Weight: 100}, "eu": {}},
+		})
+	})
+}
+
+func TestSegmentedSetGet(t *testing.T) {
+	cache := NewSegmented(SegmentedConfig[string, int]{
+		Config:   Config[string, int]{Capacity: 10},
+		Segments: map[string]SegmentConfig{"us": {}, "eu": {}},
+	})
+
+	cache.Set("foo", "us", 1)
+	cache.Set("foo", "eu", 2)
+	assert.False(t, cache.Set("foo", "apac", 3))
+
+	val, ok := cache.Get("foo", "eu")
+	assert.True(t, ok)
+	assert.Equal(t, 2, val)
+
+	_, ok = cache.Get("foo", "apac")
+	assert.False(t, ok)
+
+	assert.Equal(t, map[string]int{"us": 1, "eu": 2}, cache.Variants("foo"))
+	assert.Equal(t, []string{"eu", "us"}, cache.Segments())
+	assert.Equal(t, 2, cache.Len())
+
+	assert.True(t, cache.Remove("foo", "us"))
+	assert.False(t, cache.Remove("foo", "us"))
+	cache.Set("foo", "us", 1)
+	assert.Equal(t, 2, cache.RemoveAll("foo"))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestSegmentedWeights(t *testing.T) {
+	cache := NewSegmented(SegmentedConfig[int, int]{
+		Config:   Config[int, int]{Capacity: 12, MaxAge: time.Hour},
+		Segments: map[string]SegmentConfig{"us": {Weight: 3}, "eu": {TTL: time.Minute}},
+	})
+	defer cache.Close()
+
+	assert.Equal(t, int64(9), cache.Segment("us").Stats().Capacity)
+	assert.Equal(t, int64(3), cache.Segment("eu").Stats().Capacity)
+	assert.Equal(t, time.Hour, cache.Segment("us").maxAge)
+	assert.Equal(t, time.Minute, cache.Segment("eu").maxAge)
+	assert.Nil(t, cache.Segment("apac"))
+
+	for i := 0; i < 20; i++ {
+		cache.Set(i, "us", i)
+		cache.Set(i, "eu", i)
+	}
+	assert.Equal(t, 12, cache.Len())
+	assert.Equal(t, int64(12), cache.Stats().Capacity)
+
+	assert.Equal(t, 3, cache.FlushSegment("eu"))
+	assert.Equal(t, 0, cache.FlushSegment("apac"))
+	assert.Equal(t, 9, cache.Len())
+}
@@ -0,0 +1,163 @@
+package agecache
+
+import (
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// Version of the snapshot format written by SerializableCache.Snapshot
+const snapshotVersion = 1
+
+// Serializable constrains the values of a SerializableCache to types which
+// can be encoded to and decoded from bytes, so that a cache of values which
+// cannot be encoded fails to compile. It is satisfied by *V when V implements
+// encoding.BinaryMarshaler, and *V implements encoding.BinaryUnmarshaler.
+type Serializable[V any] interface {
+	*V
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// SerializableCache is a Cache whose values can be encoded, supporting
+// snapshots of its contents. Keys are encoded with encoding/gob.
+type SerializableCache[K comparable, V any, PV Serializable[V]] struct {
+	*Cache[K, V]
+}
+
+// NewSerializable constructs a SerializableCache with the given Config
+// object. The pointer type PV is inferred, e.g.
+//
+//	cache := agecache.NewSerializable[string, Session](config)
+//
+// Panics given an invalid configuration, as with New.
+func NewSerializable[K comparable, V any, PV Serializable[V]](config Config[K, V]) *SerializableCache[K, V, PV] {
+	return &SerializableCache[K, V, PV]{New(config)}
+}
+
+// Header and records of a snapshot.
+type snapshotHeader struct {
+	Version   int
+	Count     int
+	Checksums bool
+}
+
+type snapshotRecord[K comparable] struct {
+	Key       K
+	Value     []byte
+	Timestamp time.Time
+	TTL       time.Duration
+	Checksum  uint32 // CRC-32 of Value, if the header enables Checksums
+}
+
+// Snapshot writes all items in the cache to w, ordered from oldest to newest,
+// without updating how recently they were accessed. Items are copied with the
+// cache's lock held, but are encoded once it is released. Encoded values are
+// passed through the Config.Transformers, and with Config.Checksums, a
+// checksum of each transformed value is also written.
+func (cache *SerializableCache[K, V, PV]) Snapshot(w io.Writer) error {
+	transforms := newPipeline(cache.transforms.stages)
+	defer cache.addTransformStats(transforms)
+
+	entries := cache.Export()
+
+	encoder := gob.NewEncoder(w)
+	if err := encoder.Encode(snapshotHeader{snapshotVersion, len(entries), cache.checksums}); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		value, err := PV(&entries[i].Value).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if value, err = transforms.encode(value); err != nil {
+			return err
+		}
+
+		record := snapshotRecord[K]{Key: entries[i].Key, Value: value, Timestamp: entries[i].Timestamp, TTL: entries[i].TTL}
+		if cache.checksums {
+			record.Checksum = crc32.ChecksumIEEE(value)
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Restore reads a snapshot written by Snapshot from r, storing its items with
+// the age they had when the snapshot was taken. Items which have since
+// expired are skipped, and when the snapshot holds more items than the
+// cache's capacity, only the newest are stored. Items whose value does not
+// match its checksum, if the snapshot has checksums, are skipped and counted
+// in Stats.Corruptions. Returns the number of items stored. Nothing is stored
+// if the snapshot cannot be decoded.
+func (cache *SerializableCache[K, V, PV]) Restore(r io.Reader) (int, error) {
+	transforms := newPipeline(cache.transforms.stages)
+	defer cache.addTransformStats(transforms)
+
+	decoder := gob.NewDecoder(r)
+
+	var header snapshotHeader
+	if err := decoder.Decode(&header); err != nil {
+		return 0, err
+	} else if header.Version != snapshotVersion {
+		return 0, fmt.Errorf("agecache: unsupported snapshot version %d", header.Version)
+	}
+
+	if header.Count < 0 {
+		return 0, fmt.Errorf("agecache: invalid snapshot item count %d", header.Count)
+	}
+
+	entries := make([]cacheEntry[K, V], 0, cache.preallocated(header.Count))
+	corrupted := int64(0)
+	for i := 0; i < header.Count; i++ {
+		var record snapshotRecord[K]
+		if err := decoder.Decode(&record); err != nil {
+			return 0, err
+		}
+		if header.Checksums && crc32.ChecksumIEEE(record.Value) != record.Checksum {
+			corrupted++
+			continue
+		}
+
+		value, err := transforms.decode(record.Value)
+		if err != nil {
+			return 0, err
+		}
+
+		entry := cacheEntry[K, V]{key: record.Key, timestamp: record.Timestamp, ttl: record.TTL}
+		if err := PV(&entry.value).UnmarshalBinary(value); err != nil {
+			return 0, err
+		}
+		entries = append(entries, entry)
+	}
+
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	cache.corrupted += corrupted
+
+	return cache.restore(entries), nil
+}
+
+// TransformStats returns the stats of each of the Config.Transformers, in
+// order.
+func (cache *SerializableCache[K, V, PV]) TransformStats() []TransformStats {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	return append([]TransformStats(nil), cache.transforms.stats...)
+}
+
+func (cache *SerializableCache[K, V, PV]) addTransformStats(transforms *pipeline) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.transforms.add(transforms)
+}
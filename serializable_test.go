@@ -0,0 +1,104 @@
+package agecache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type session struct {
+	user string
+}
+
+func (s session) MarshalBinary() ([]byte, error) {
+	if s.user == "" {
+		return nil, errors.New("missing user")
+	}
+	return []byte(s.user), nil
+}
+
+func (s *session) UnmarshalBinary(data []byte) error {
+	s.user = string(data)
+	return nil
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	cache := NewSerializable[string, session](Config[string, session]{
+		Capacity: 3,
+		MaxAge:   time.Hour,
+	})
+
+	cache.Set("a", session{"alice"})
+	cache.Set("b", session{"bob"})
+	cache.SetWithTTL("c", session{"carol"}, time.Millisecond)
+	cache.Get("a")
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.Snapshot(&buf))
+	<-time.After(2 * time.Millisecond)
+
+	// Only the newest items fit, and expired items are skipped
+	restored := NewSerializable[string, session](Config[string, session]{
+		Capacity: 2,
+		MaxAge:   time.Hour,
+	})
+	n, err := restored.Restore(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"b", "a"}, restored.OrderedKeys())
+
+	value, _ := restored.Get("a")
+	assert.Equal(t, session{"alice"}, value)
+
+	ttl, _ := restored.TTL("b")
+	assert.True(t, ttl > 59*time.Minute && ttl <= time.Hour)
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	cache := NewSerializable[string, session](Config[string, session]{Capacity: 1})
+
+	cache.Set("a", session{})
+	assert.EqualError(t, cache.Snapshot(&bytes.Buffer{}), "missing user")
+
+	_, err := cache.Restore(bytes.NewReader([]byte("garbage")))
+	assert.Error(t, err)
+	assert.Equal(t, 1, cache.Len())
+
+	// Malformed headers are rejected, or read without trusting their count
+	for _, count := range []int{-1, math.MaxInt} {
+		var malformed bytes.Buffer
+		assert.NoError(t, gob.NewEncoder(&malformed).Encode(snapshotHeader{Version: snapshotVersion, Count: count}))
+		_, err = cache.Restore(&malformed)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestSnapshotChecksums(t *testing.T) {
+	config := Config[string, session]{Capacity: 2, Checksums: true}
+	cache := NewSerializable[string, session](config)
+	cache.Set("a", session{"alice"})
+	cache.Set("b", session{"bob"})
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.Snapshot(&buf))
+
+	// Flip a bit of the value of b
+	data := buf.Bytes()
+	i := bytes.LastIndex(data, []byte("bob"))
+	data[i] ^= 1
+
+	restored := NewSerializable[string, session](config)
+	n, err := restored.Restore(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, restored.Has("a"))
+	assert.False(t, restored.Has("b"))
+	assert.Equal(t, int64(1), restored.Stats().Corruptions)
+	assert.Equal(t, int64(0), restored.Stats().Delta(restored.Stats()).Corruptions)
+}
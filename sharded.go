@@ -0,0 +1,698 @@
+package agecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Number of shards used when ShardedConfig.Shards is zero
+const defaultShards = 16
+
+// ShardedConfig configures a sharded cache.
+type ShardedConfig[K comparable, V any] struct {
+	// Configuration shared by all shards. Capacity, MaxCost, MaxMemory,
+	// MinCapacity and MaxCapacity apply to the sharded cache as a whole, and
+	// are divided evenly between the shards.
+	Config[K, V]
+	// Number of independently locked shards. Defaults to 16.
+	Shards int
+	// Optional function hashing a key to select its shard. Defaults to a hash
+	// of the key's string and integer kinds, and of its fmt.Sprint formatting
+	// for all other types.
+	Hash func(key K) uint64
+	// For ActiveExpiration, the maximum number of shards whose expired items
+	// are removed at once. The sweeps of the shards are staggered across the
+	// ExpirationInterval, rather than all starting together. Defaults to
+	// GOMAXPROCS
+	SweepConcurrency int
+}
+
+// ShardedCache implements a thread-safe fixed-capacity LRU cache which
+// partitions its keyspace across a number of independently locked Cache
+// shards, reducing lock contention under heavily parallel workloads. Items
+// are evicted according to the LRU policy of their shard.
+type ShardedCache[K comparable, V any] struct {
+	shards []*Cache[K, V]
+	hash   func(key K) uint64
+
+	done      chan struct{} // Closed by Close to stop active expiration
+	closeOnce sync.Once
+}
+
+// NewSharded constructs a ShardedCache with the given ShardedConfig object.
+// config.Capacity must be at least the number of shards. Panics given an
+// invalid configuration, as with New.
+func NewSharded[K comparable, V any](config ShardedConfig[K, V]) *ShardedCache[K, V] {
+	if config.Shards < 0 {
+		panic("Must supply a zero or positive config.Shards")
+	}
+
+	n := config.Shards
+	if n == 0 {
+		n = defaultShards
+	}
+
+	if config.Capacity < n {
+		panic("config.Capacity must be greater than or equal to the number of shards")
+	}
+
+	if config.MaxCost < 0 {
+		panic("Must supply a zero or positive config.MaxCost")
+	}
+
+	if config.SweepConcurrency < 0 {
+		panic("Must supply a zero or positive config.SweepConcurrency")
+	}
+
+	hash := config.Hash
+	if hash == nil {
+		hash = hashKey[K]
+	}
+
+	cache := &ShardedCache[K, V]{
+		shards: make([]*Cache[K, V], n),
+		hash:   hash,
+		done:   make(chan struct{}),
+	}
+
+	for i := range cache.shards {
+		shardConfig := config.Config
+		shardConfig.Capacity = int(split(int64(config.Capacity), n, i))
+		shardConfig.MaxCost = split(config.MaxCost, n, i)
+		shardConfig.MaxMemory = split(config.MaxMemory, n, i)
+		shardConfig.MinCapacity = int(split(int64(config.MinCapacity), n, i))
+		shardConfig.MaxCapacity = int(split(int64(config.MaxCapacity), n, i))
+		if config.PersistPath != "" {
+			shardConfig.PersistPath = fmt.Sprintf("%s.%d", config.PersistPath, i)
+		}
+		shard, err := newCache(shardConfig)
+		if err != nil {
+			panic(err.Error())
+		}
+		cache.shards[i] = shard
+	}
+
+	if shard := cache.shards[0]; shard.expirationType == ActiveExpiration && shard.expirationInterval > 0 {
+		concurrency := config.SweepConcurrency
+		if concurrency == 0 {
+			concurrency = runtime.GOMAXPROCS(0)
+		}
+		cache.sweep(shard.clock, shard.expirationInterval, concurrency)
+	}
+
+	if shard := cache.shards[0]; shard.metrics != nil {
+		reportMetrics(shard.metrics, shard.metricsInterval, shard.clock, cache.Stats, cache.done)
+	}
+
+	return cache
+}
+
+// sweep removes the expired items of each shard every interval, until the
+// cache is closed. A shard is swept every interval divided by the number of
+// shards, in turn, by one of concurrency goroutines, so that sweeps of large
+// caches are spread out rather than pausing every shard at once.
+func (cache *ShardedCache[K, V]) sweep(clock Clock, interval time.Duration, concurrency int) {
+	if concurrency > len(cache.shards) {
+		concurrency = len(cache.shards)
+	}
+
+	sweeps := make(chan *Cache[K, V])
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for {
+				select {
+				case shard := <-sweeps:
+					shard.deleteExpired()
+				case <-cache.done:
+					return
+				}
+			}
+		}()
+	}
+
+	stagger := interval / time.Duration(len(cache.shards))
+	if stagger <= 0 {
+		stagger = interval
+	}
+
+	ticker := clock.NewTicker(stagger)
+	go func() {
+		defer ticker.Stop()
+		for next := 0; ; next = (next + 1) % len(cache.shards) {
+			select {
+			case <-ticker.Chan():
+			case <-cache.done:
+				return
+			}
+
+			// Blocks while every goroutine is sweeping
+			select {
+			case sweeps <- cache.shards[next]:
+			case <-cache.done:
+				return
+			}
+		}
+	}()
+}
+
+// Set updates a key:value pair in the cache. Returns true if an eviction
+// occurred, and subsequently invokes the OnEviction callback.
+func (cache *ShardedCache[K, V]) Set(key K, value V) bool {
+	return cache.shard(key).Set(key, value)
+}
+
+// TrySet updates a key:value pair in the cache, returning a *RejectedError if
+// the item could not be stored. See Cache.TrySet.
+func (cache *ShardedCache[K, V]) TrySet(key K, value V) error {
+	return cache.shard(key).TrySet(key, value)
+}
+
+// SetWithTTL updates a key:value pair in the cache, expiring it once ttl has
+// elapsed rather than after the cache's MaxAge. See Cache.SetWithTTL.
+func (cache *ShardedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) bool {
+	return cache.shard(key).SetWithTTL(key, value, ttl)
+}
+
+// SetMulti updates all of the key:value pairs in the cache, taking the lock
+// of each shard only once. See Cache.SetMulti.
+func (cache *ShardedCache[K, V]) SetMulti(items map[K]V) bool {
+	shards := make(map[int]map[K]V)
+	for key, value := range items {
+		i := cache.index(key)
+		if shards[i] == nil {
+			shards[i] = make(map[K]V)
+		}
+		shards[i][key] = value
+	}
+
+	evict := false
+	for i, items := range shards {
+		if cache.shards[i].SetMulti(items) {
+			evict = true
+		}
+	}
+	return evict
+}
+
+// SetWithOrigin updates a key:value pair in the cache, recording where it came
+// from. See Cache.SetWithOrigin.
+func (cache *ShardedCache[K, V]) SetWithOrigin(key K, value V, origin string) bool {
+	return cache.shard(key).SetWithOrigin(key, value, origin)
+}
+
+// Get returns the value stored at `key`. The boolean value reports whether
+// the value was found. The OnExpiration callback is invoked if the value
+// had expired on access
+func (cache *ShardedCache[K, V]) Get(key K) (value V, found bool) {
+	return cache.shard(key).Get(key)
+}
+
+// GetMulti returns the values stored at each of the keys which were found,
+// taking the lock of each shard only once. See Cache.GetMulti.
+func (cache *ShardedCache[K, V]) GetMulti(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for i, keys := range cache.partition(keys) {
+		for key, value := range cache.shards[i].GetMulti(keys) {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// GetOrLoad returns the value stored at `key`, invoking loader to produce and
+// store it on a miss. See Cache.GetOrLoad.
+func (cache *ShardedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error)) (V, error) {
+	return cache.shard(key).GetOrLoad(ctx, key, loader)
+}
+
+// SetWithTags updates a key:value pair in the cache, tagging the item with
+// each of tags. See Cache.SetWithTags.
+func (cache *ShardedCache[K, V]) SetWithTags(key K, value V, tags ...string) bool {
+	return cache.shard(key).SetWithTags(key, value, tags...)
+}
+
+// InvalidateTag removes every item tagged with tag from all shards, returning
+// the number of items removed. See Cache.InvalidateTag.
+func (cache *ShardedCache[K, V]) InvalidateTag(tag string) int {
+	removed := 0
+	for _, shard := range cache.shards {
+		removed += shard.InvalidateTag(tag)
+	}
+	return removed
+}
+
+// GetStale returns the value stored at `key`, and whether it is stale. See
+// Cache.GetStale.
+func (cache *ShardedCache[K, V]) GetStale(key K) (value V, stale bool, found bool) {
+	return cache.shard(key).GetStale(key)
+}
+
+// Touch resets the age of the item at `key` and marks it as the most recently
+// accessed. See Cache.Touch.
+func (cache *ShardedCache[K, V]) Touch(key K) bool {
+	return cache.shard(key).Touch(key)
+}
+
+// Promote marks the item at `key` as the most recently accessed in its shard.
+// See Cache.Promote.
+func (cache *ShardedCache[K, V]) Promote(key K) bool {
+	return cache.shard(key).Promote(key)
+}
+
+// Demote marks the item at `key` as the next to evict from its shard. See
+// Cache.Demote.
+func (cache *ShardedCache[K, V]) Demote(key K) bool {
+	return cache.shard(key).Demote(key)
+}
+
+// Has returns whether the `key` is in the cache without updating
+// how recently it was accessed or deleting it for having expired.
+func (cache *ShardedCache[K, V]) Has(key K) bool {
+	return cache.shard(key).Has(key)
+}
+
+// Peek returns the value at the specified key and a boolean specifying whether
+// it was found, without updating how recently it was accessed or
+// deleting it for having expired.
+func (cache *ShardedCache[K, V]) Peek(key K) (value V, found bool) {
+	return cache.shard(key).Peek(key)
+}
+
+// PeekEntry returns a copy of the item at `key`, including its metadata. See
+// Cache.PeekEntry.
+func (cache *ShardedCache[K, V]) PeekEntry(key K) (Entry[K, V], bool) {
+	return cache.shard(key).PeekEntry(key)
+}
+
+// TTL returns the remaining lifetime of the item at `key`. See Cache.TTL.
+func (cache *ShardedCache[K, V]) TTL(key K) (time.Duration, bool) {
+	return cache.shard(key).TTL(key)
+}
+
+// Remove removes the provided key from the cache, returning a bool indicating
+// whether it existed. The OnRemove callback is invoked if it existed.
+func (cache *ShardedCache[K, V]) Remove(key K) bool {
+	return cache.shard(key).Remove(key)
+}
+
+// Pop atomically removes the item at `key` and returns its value. See
+// Cache.Pop.
+func (cache *ShardedCache[K, V]) Pop(key K) (value V, found bool) {
+	return cache.shard(key).Pop(key)
+}
+
+// RemoveMulti removes each of the provided keys from the cache, taking the lock
+// of each shard only once. Returns the number of keys which existed.
+func (cache *ShardedCache[K, V]) RemoveMulti(keys []K) int {
+	removed := 0
+	for i, keys := range cache.partition(keys) {
+		removed += cache.shards[i].RemoveMulti(keys)
+	}
+	return removed
+}
+
+// RemoveFunc removes every item for which pred returns true from all shards,
+// taking the lock of each shard only once. See Cache.RemoveFunc.
+func (cache *ShardedCache[K, V]) RemoveFunc(pred func(key K, value V) bool) int {
+	removed := 0
+	for _, shard := range cache.shards {
+		removed += shard.RemoveFunc(pred)
+	}
+	return removed
+}
+
+// RemoveExpired removes all expired items from the cache, invoking the
+// OnExpiration callback for each of them. Returns the number of items
+// removed.
+func (cache *ShardedCache[K, V]) RemoveExpired() int {
+	removed := 0
+	for _, shard := range cache.shards {
+		removed += shard.RemoveExpired()
+	}
+	return removed
+}
+
+// Len returns the number of items in the cache. It is approximate under
+// concurrent updates, as the shards are counted one after another, each under
+// its own lock, so that the total may not match the number of items at any
+// single point in time. See LenConsistent.
+func (cache *ShardedCache[K, V]) Len() int {
+	n := 0
+	for _, shard := range cache.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// LenConsistent returns the number of items in the cache, as at a single
+// point in time. It holds the locks of all shards at once while counting,
+// briefly pausing updates of the whole cache, and is thus slower than Len.
+func (cache *ShardedCache[K, V]) LenConsistent() int {
+	cache.rlockAll()
+	defer cache.runlockAll()
+
+	n := 0
+	for _, shard := range cache.shards {
+		n += len(shard.items)
+	}
+	return n
+}
+
+// rlockAll acquires the read locks of all shards, in order.
+func (cache *ShardedCache[K, V]) rlockAll() {
+	for _, shard := range cache.shards {
+		shard.mutex.RLock()
+	}
+}
+
+// runlockAll releases the read locks acquired by rlockAll.
+func (cache *ShardedCache[K, V]) runlockAll() {
+	for i := len(cache.shards) - 1; i >= 0; i-- {
+		cache.shards[i].mutex.RUnlock()
+	}
+}
+
+// Clear empties the cache, invoking the OnRemoval callback for each item.
+// Returns the number of items removed.
+func (cache *ShardedCache[K, V]) Clear() int {
+	n := 0
+	for _, shard := range cache.shards {
+		n += shard.Clear()
+	}
+	return n
+}
+
+// Keys returns all keys in the cache.
+func (cache *ShardedCache[K, V]) Keys() []K {
+	var keys []K
+	for _, shard := range cache.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Items returns a copy of all key:value pairs in the cache. Each shard is
+// copied in turn, so the copy is only point-in-time per shard.
+func (cache *ShardedCache[K, V]) Items() map[K]V {
+	items := make(map[K]V)
+	for _, shard := range cache.shards {
+		for key, value := range shard.Items() {
+			items[key] = value
+		}
+	}
+	return items
+}
+
+// Values returns a copy of all values in the cache, one shard at a time.
+func (cache *ShardedCache[K, V]) Values() []V {
+	var values []V
+	for _, shard := range cache.shards {
+		values = append(values, shard.Values()...)
+	}
+	return values
+}
+
+// Range invokes fn for each item in the cache, one shard at a time, until fn
+// returns false. See Cache.Range.
+func (cache *ShardedCache[K, V]) Range(fn func(key K, value V) bool) {
+	more := true
+	for _, shard := range cache.shards {
+		shard.Range(func(key K, value V) bool {
+			more = fn(key, value)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// SetMaxAge updates the max age for items in all shards. See Cache.SetMaxAge.
+func (cache *ShardedCache[K, V]) SetMaxAge(maxAge time.Duration) error {
+	for _, shard := range cache.shards {
+		if err := shard.SetMaxAge(maxAge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetMinAge updates the min age for items in all shards. See Cache.SetMinAge.
+func (cache *ShardedCache[K, V]) SetMinAge(minAge time.Duration) error {
+	for _, shard := range cache.shards {
+		if err := shard.SetMinAge(minAge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnEviction sets the eviction callback.
+func (cache *ShardedCache[K, V]) OnEviction(callback func(key K, value V)) {
+	for _, shard := range cache.shards {
+		shard.OnEviction(callback)
+	}
+}
+
+// OnExpiration sets the expiration callback.
+func (cache *ShardedCache[K, V]) OnExpiration(callback func(key K, value V)) {
+	for _, shard := range cache.shards {
+		shard.OnExpiration(callback)
+	}
+}
+
+// OnRemove sets the removal callback.
+func (cache *ShardedCache[K, V]) OnRemove(callback func(key K, value V)) {
+	for _, shard := range cache.shards {
+		shard.OnRemove(callback)
+	}
+}
+
+// OnRemoval sets the callback invoked whenever an item leaves the cache.
+func (cache *ShardedCache[K, V]) OnRemoval(callback func(key K, value V, reason RemovalReason)) {
+	for _, shard := range cache.shards {
+		shard.OnRemoval(callback)
+	}
+}
+
+// OnSet sets the callback invoked when an item is stored.
+func (cache *ShardedCache[K, V]) OnSet(callback func(key K, value V, replaced bool)) {
+	for _, shard := range cache.shards {
+		shard.OnSet(callback)
+	}
+}
+
+// OnHit sets the callback invoked when Get finds an item.
+func (cache *ShardedCache[K, V]) OnHit(callback func(key K, value V)) {
+	for _, shard := range cache.shards {
+		shard.OnHit(callback)
+	}
+}
+
+// SetBypass updates the predicate reporting keys which must not be cached in
+// all shards. See Cache.SetBypass.
+func (cache *ShardedCache[K, V]) SetBypass(bypass func(key K) bool) {
+	for _, shard := range cache.shards {
+		shard.SetBypass(bypass)
+	}
+}
+
+// OnThrash sets the callback invoked with keys whose stores are repeatedly
+// wasted.
+func (cache *ShardedCache[K, V]) OnThrash(callback func(key K, value V)) {
+	for _, shard := range cache.shards {
+		shard.OnThrash(callback)
+	}
+}
+
+// Stats returns cache stats, aggregated across all shards. As with Len, the
+// stats of each shard are read under its own lock, one after another, so that
+// under concurrent updates, the aggregate may not match the cache at any
+// single point in time, such as a Count differing from the Sets minus the
+// removals. See StatsConsistent.
+func (cache *ShardedCache[K, V]) Stats() Stats {
+	return sumStats(cache.shards)
+}
+
+// StatsConsistent returns cache stats, aggregated across all shards as at a
+// single point in time. As with LenConsistent, it holds the locks of all
+// shards at once, and is thus slower than Stats.
+func (cache *ShardedCache[K, V]) StatsConsistent() Stats {
+	cache.rlockAll()
+	defer cache.runlockAll()
+
+	var stats Stats
+	for _, shard := range cache.shards {
+		addStats(&stats, shard.stats())
+	}
+	return stats
+}
+
+// sumStats returns the stats of all caches added together.
+func sumStats[K comparable, V any](caches []*Cache[K, V]) Stats {
+	var stats Stats
+	for _, cache := range caches {
+		addStats(&stats, cache.Stats())
+	}
+	return stats
+}
+
+// addStats adds s to stats.
+func addStats(stats *Stats, s Stats) {
+	stats.Capacity += s.Capacity
+	stats.Count += s.Count
+	stats.Sets += s.Sets
+	stats.Gets += s.Gets
+	stats.Hits += s.Hits
+	stats.Misses += s.Misses
+	stats.Evictions += s.Evictions
+	stats.Cost += s.Cost
+	stats.Corruptions += s.Corruptions
+	stats.Expirations += s.Expirations
+	stats.Thrashes += s.Thrashes
+	// Shards are requested disjoint keys
+	stats.Cardinality += s.Cardinality
+}
+
+// StartStatsReporter invokes report every interval with the Stats aggregated
+// across all shards, and their Delta since the previous report, until the
+// returned stop function is called or the cache is closed. See
+// Cache.StartStatsReporter.
+func (cache *ShardedCache[K, V]) StartStatsReporter(interval time.Duration, report func(stats, delta Stats)) (stop func()) {
+	return startStatsReporter(interval, cache.shards[0].clock, cache.Stats, cache.done, report)
+}
+
+// WatchSLO checks the hit ratio aggregated across all shards against slo,
+// invoking onViolation once it falls below the objective. See Cache.WatchSLO.
+func (cache *ShardedCache[K, V]) WatchSLO(slo SLO, onViolation func(window Stats)) (stop func()) {
+	return watchSLO(slo, cache.shards[0].clock, cache.Stats, cache.done, onViolation)
+}
+
+// Resize the cache to hold at most n entries, divided evenly between the
+// shards. Entries are evicted from each shard to fit its new size. It errors
+// if n is less than the number of shards.
+func (cache *ShardedCache[K, V]) Resize(n int) error {
+	if n < len(cache.shards) {
+		return errors.New("must supply a capacity of at least the number of shards to Resize")
+	}
+
+	for i, shard := range cache.shards {
+		shard.Resize(int(split(int64(n), len(cache.shards), i)))
+	}
+	return nil
+}
+
+// WithKeyLock invokes fn while holding an exclusive lock on `key`. See
+// Cache.WithKeyLock.
+func (cache *ShardedCache[K, V]) WithKeyLock(key K, fn func()) {
+	cache.shard(key).WithKeyLock(key, fn)
+}
+
+// WithKeyRLock invokes fn while holding a shared lock on `key`. See
+// Cache.WithKeyRLock.
+func (cache *ShardedCache[K, V]) WithKeyRLock(key K, fn func()) {
+	cache.shard(key).WithKeyRLock(key, fn)
+}
+
+// Sync waits for the callbacks queued in all shards. See Cache.Sync.
+func (cache *ShardedCache[K, V]) Sync() {
+	for _, shard := range cache.shards {
+		shard.Sync()
+	}
+}
+
+// Close stops active expiration in all shards, returning the first error of
+// saving them. See Cache.Close.
+func (cache *ShardedCache[K, V]) Close() error {
+	cache.closeOnce.Do(func() { close(cache.done) })
+
+	var err error
+	for _, shard := range cache.shards {
+		if closeErr := shard.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (cache *ShardedCache[K, V]) shard(key K) *Cache[K, V] {
+	return cache.shards[cache.index(key)]
+}
+
+func (cache *ShardedCache[K, V]) index(key K) int {
+	return int(cache.hash(key) % uint64(len(cache.shards)))
+}
+
+// partition groups keys by the index of their shard.
+func (cache *ShardedCache[K, V]) partition(keys []K) map[int][]K {
+	shards := make(map[int][]K)
+	for _, key := range keys {
+		i := cache.index(key)
+		shards[i] = append(shards[i], key)
+	}
+	return shards
+}
+
+// split returns the share of total assigned to the ith of n parts, such that
+// the shares differ by at most one and sum to total.
+func split(total int64, n, i int) int64 {
+	share := total / int64(n)
+	if int64(i) < total%int64(n) {
+		share++
+	}
+	return share
+}
+
+// hashKey is the default ShardedConfig.Hash.
+func hashKey[K comparable](key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return hashString(k)
+	case int:
+		return hashInt(uint64(k))
+	case int8:
+		return hashInt(uint64(k))
+	case int16:
+		return hashInt(uint64(k))
+	case int32:
+		return hashInt(uint64(k))
+	case int64:
+		return hashInt(uint64(k))
+	case uint:
+		return hashInt(uint64(k))
+	case uint8:
+		return hashInt(uint64(k))
+	case uint16:
+		return hashInt(uint64(k))
+	case uint32:
+		return hashInt(uint64(k))
+	case uint64:
+		return hashInt(k)
+	case uintptr:
+		return hashInt(uint64(k))
+	default:
+		return hashString(fmt.Sprint(key))
+	}
+}
+
+// hashString returns the 64-bit FNV-1a hash of s.
+func hashString(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// hashInt mixes the bits of n, so that sequential keys spread across shards.
+func hashInt(n uint64) uint64 {
+	n ^= n >> 33
+	n *= 0xff51afd7ed558ccd
+	n ^= n >> 33
+	return n
+}
@@ -0,0 +1,336 @@
+package agecache
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidShards(t *testing.T) {
+	assert.Panics(t, func() {
+		NewSharded(ShardedConfig[string, int]{
+			Config: Config[string, int]{Capacity: 10},
+			Shards: -1,
+		})
+	})
+
+	assert.Panics(t, func() {
+		NewSharded(ShardedConfig[string, int]{
+			Config: Config[string, int]{Capacity: 2},
+			Shards: 4,
+		})
+	})
+
+	assert.Panics(t, func() {
+		NewSharded(ShardedConfig[string, int]{
+			Config:           Config[string, int]{Capacity: 10},
+			SweepConcurrency: -1,
+		})
+	})
+}
+
+func TestShardedSetGet(t *testing.T) {
+	cache := NewSharded(ShardedConfig[string, int]{
+		Config: Config[string, int]{Capacity: 100},
+		Shards: 4,
+	})
+
+	for i := 0; i < 100; i++ {
+		cache.Set(strconv.Itoa(i), i)
+	}
+
+	for i := 0; i < 100; i++ {
+		val, ok := cache.Get(strconv.Itoa(i))
+		if ok {
+			assert.Equal(t, i, val)
+		}
+	}
+
+	assert.True(t, cache.Len() <= 100)
+	assert.Equal(t, cache.Len(), len(cache.Keys()))
+
+	cache.Set("foo", 1)
+	assert.True(t, cache.Remove("foo"))
+	assert.False(t, cache.Has("foo"))
+
+	n := cache.Len()
+	assert.Equal(t, n, cache.Clear())
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestShardedMulti(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 100},
+		Shards: 4,
+	})
+
+	items := make(map[int]int)
+	keys := make([]int, 0, 20)
+	for i := 0; i < 20; i++ {
+		items[i] = i
+		keys = append(keys, i)
+	}
+
+	assert.False(t, cache.SetMulti(items))
+	assert.Equal(t, items, cache.GetMulti(append(keys, 100)))
+	assert.Equal(t, 20, cache.RemoveMulti(append(keys, 100)))
+	assert.Equal(t, 0, cache.Len())
+
+	assert.False(t, cache.SetMulti(items))
+	assert.Equal(t, 10, cache.RemoveFunc(func(key, value int) bool {
+		return key%2 == 0
+	}))
+	assert.Equal(t, 10, cache.Len())
+}
+
+func TestShardedItemsValues(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 100},
+		Shards: 4,
+	})
+	for i := 0; i < 20; i++ {
+		cache.Set(i, i*2)
+	}
+
+	items := cache.Items()
+	assert.Len(t, items, 20)
+	assert.Equal(t, 10, items[5])
+
+	values := cache.Values()
+	sort.Ints(values)
+	assert.Len(t, values, 20)
+	assert.Equal(t, 38, values[19])
+}
+
+func TestShardedRange(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 100},
+		Shards: 4,
+	})
+	for i := 0; i < 20; i++ {
+		cache.Set(i, i)
+	}
+
+	seen := make(map[int]int)
+	cache.Range(func(key, value int) bool {
+		seen[key] = value
+		return true
+	})
+	assert.Len(t, seen, 20)
+
+	n := 0
+	cache.Range(func(key, value int) bool {
+		n++
+		return n < 5
+	})
+	assert.Equal(t, 5, n)
+}
+
+func TestShardedCapacity(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 10},
+		Shards: 3,
+	})
+	assert.Equal(t, int64(10), cache.Stats().Capacity)
+
+	for i := 0; i < 1000; i++ {
+		cache.Set(i, i)
+	}
+	assert.Equal(t, 10, cache.Len())
+
+	err := cache.Resize(2)
+	assert.Error(t, err)
+
+	err = cache.Resize(5)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, cache.Len())
+	assert.Equal(t, int64(5), cache.Stats().Capacity)
+}
+
+func TestShardedHash(t *testing.T) {
+	cache := NewSharded(ShardedConfig[string, int]{
+		Config: Config[string, int]{Capacity: 4},
+		Shards: 2,
+		Hash: func(key string) uint64 {
+			return 0
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Set("c", 3)
+
+	// All keys land in the first shard, which holds 2 items
+	keys := cache.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"b", "c"}, keys)
+}
+
+func TestShardedStats(t *testing.T) {
+	cache := NewSharded(ShardedConfig[string, int]{
+		Config: Config[string, int]{Capacity: 100, MaxAge: time.Second},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Get("c")
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(100), stats.Capacity)
+	assert.Equal(t, int64(2), stats.Count)
+	assert.Equal(t, int64(2), stats.Sets)
+	assert.Equal(t, int64(2), stats.Gets)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+
+	assert.Equal(t, stats, cache.StatsConsistent())
+	assert.Equal(t, 2, cache.LenConsistent())
+}
+
+func TestShardedConsistent(t *testing.T) {
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{Capacity: 1000},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				cache.Set(i*1000+j, j)
+				cache.Get(j)
+			}
+		}(i)
+	}
+
+	for i := 0; i < 100; i++ {
+		stats := cache.StatsConsistent()
+		assert.Equal(t, stats.Gets, stats.Hits+stats.Misses)
+		assert.True(t, cache.LenConsistent() <= 1000)
+	}
+	wg.Wait()
+
+	assert.Equal(t, cache.Len(), cache.LenConsistent())
+	assert.Equal(t, int64(4000), cache.StatsConsistent().Sets)
+}
+
+func TestShardedStatsReporter(t *testing.T) {
+	cache := NewSharded(ShardedConfig[string, int]{
+		Config: Config[string, int]{Capacity: 100},
+	})
+	cache.Set("a", 1)
+
+	var reports int32
+	cache.StartStatsReporter(time.Millisecond, func(stats, delta Stats) {
+		assert.Equal(t, int64(1), stats.Count)
+		atomic.AddInt32(&reports, 1)
+	})
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&reports) > 0
+	}, time.Second, time.Millisecond)
+
+	// Stopped by Close
+	cache.Close()
+	<-time.After(5 * time.Millisecond)
+	n := atomic.LoadInt32(&reports)
+	<-time.After(20 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&reports))
+}
+
+func TestShardedExpiration(t *testing.T) {
+	var mutex sync.Mutex
+	var expired []string
+
+	cache := NewSharded(ShardedConfig[string, int]{
+		Config: Config[string, int]{
+			Capacity: 100,
+			MaxAge:   time.Millisecond,
+			OnExpiration: func(key string, value int) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				expired = append(expired, key)
+			},
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.SetWithTTL("b", 2, time.Hour)
+	<-time.After(time.Millisecond * 2)
+
+	assert.Equal(t, 1, cache.RemoveExpired())
+	assert.Equal(t, []string{"a"}, expired)
+
+	ttl, ok := cache.TTL("b")
+	assert.True(t, ok)
+	assert.True(t, ttl > time.Minute)
+}
+
+func TestShardedActiveExpiration(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := NewSharded(ShardedConfig[int, int]{
+		Config: Config[int, int]{
+			Capacity:           100,
+			MaxAge:             30 * time.Second,
+			ExpirationType:     ActiveExpiration,
+			ExpirationInterval: 4 * time.Minute,
+			Clock:              clock,
+		},
+		Shards:           4,
+		Hash:             func(key int) uint64 { return uint64(key) },
+		SweepConcurrency: 1,
+	})
+	defer cache.Close()
+
+	for key := 0; key < 8; key++ {
+		cache.Set(key, key)
+	}
+
+	lens := func() []int {
+		var lens []int
+		for _, shard := range cache.shards {
+			lens = append(lens, shard.Len())
+		}
+		return lens
+	}
+
+	// Shards are swept in turn, each every ExpirationInterval
+	clock.Advance(time.Minute)
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]int{0, 2, 2, 2}, lens())
+	}, time.Second, time.Millisecond)
+
+	clock.Advance(time.Minute)
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]int{0, 0, 2, 2}, lens())
+	}, time.Second, time.Millisecond)
+}
+
+func TestHashKey(t *testing.T) {
+	assert.Equal(t, hashKey("foo"), hashKey("foo"))
+	assert.NotEqual(t, hashKey("foo"), hashKey("bar"))
+	assert.NotEqual(t, hashKey(1), hashKey(2))
+
+	type point struct{ x, y int }
+	assert.Equal(t, hashKey(point{1, 2}), hashKey(point{1, 2}))
+	assert.NotEqual(t, hashKey(point{1, 2}), hashKey(point{2, 1}))
+}
+
+func BenchmarkShardedCache(b *testing.B) {
+	cache := NewSharded(ShardedConfig[string, string]{
+		Config: Config[string, string]{Capacity: 100, MaxAge: time.Second},
+	})
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cache.Set("a", "b")
+			cache.Get("a")
+		}
+	})
+}
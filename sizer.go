@@ -0,0 +1,148 @@
+package agecache
+
+import "reflect"
+
+// Sizer estimates the memory used by values, in bytes, for Config.MaxMemory.
+// Its Size method is invoked with the cache's lock held whenever an item is
+// stored, and so must not call back into the cache.
+type Sizer[V any] interface {
+	Size(value V) int64
+}
+
+// SizerFunc adapts a function to a Sizer.
+type SizerFunc[V any] func(value V) int64
+
+// Size returns f(value).
+func (f SizerFunc[V]) Size(value V) int64 {
+	return f(value)
+}
+
+// StringSizer is a Sizer of string values, counting their header and bytes.
+type StringSizer[V ~string] struct{}
+
+// Size returns the size of the string.
+func (StringSizer[V]) Size(value V) int64 {
+	return stringHeaderSize + int64(len(value))
+}
+
+// BytesSizer is a Sizer of byte slice values, counting their header and the
+// capacity of their backing array.
+type BytesSizer[V ~[]byte] struct{}
+
+// Size returns the size of the byte slice.
+func (BytesSizer[V]) Size(value V) int64 {
+	return sliceHeaderSize + int64(cap(value))
+}
+
+// ReflectSizer is a Sizer of values of any type, walking them with
+// reflection to count the memory they reference through pointers, slices,
+// strings, maps and interfaces. Memory referenced more than once through
+// pointers, slices or maps is counted once per value, such that cyclic values
+// are counted once, and channels and functions are counted by their header
+// alone. It visits all of a value, so is far slower than a Sizer specific to
+// it.
+type ReflectSizer[V any] struct{}
+
+// Size returns an estimate of the memory used by the value.
+func (ReflectSizer[V]) Size(value V) int64 {
+	return sizeOf(reflect.ValueOf(&value).Elem(), map[seenKey]struct{}{})
+}
+
+// Sizes of the headers of strings and slices, on 64-bit platforms
+const (
+	stringHeaderSize = 16
+	sliceHeaderSize  = 24
+)
+
+// Approximate overhead of each entry of a map, beyond its key and element
+const mapEntryOverhead = 8
+
+// sizeOf returns the size of v, including the memory it references which has
+// not been seen.
+func sizeOf(v reflect.Value, seen map[seenKey]struct{}) int64 {
+	return int64(v.Type().Size()) + referenced(v, seen)
+}
+
+// referenced returns the size of the memory referenced by v which has not
+// been seen, excluding v itself.
+func referenced(v reflect.Value, seen map[seenKey]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		// Keyed by the element type too, as a slice of a struct shares the
+		// address of a slice of its first field
+		if v.IsNil() || v.Cap() > 0 && visited(seenKey{v.Pointer(), v.Type().Elem()}, seen) {
+			return 0
+		}
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			size += referenced(v.Index(i), seen)
+		}
+		return size
+	case reflect.Array:
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += referenced(v.Index(i), seen)
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += referenced(v.Field(i), seen)
+		}
+		return size
+	case reflect.Pointer, reflect.Map:
+		if v.IsNil() || visited(seenKey{ptr: v.Pointer()}, seen) {
+			return 0
+		}
+		if v.Kind() == reflect.Pointer {
+			return sizeOf(v.Elem(), seen)
+		}
+		size := int64(v.Len()) * (int64(v.Type().Key().Size()+v.Type().Elem().Size()) + mapEntryOverhead)
+		iter := v.MapRange()
+		for iter.Next() {
+			size += referenced(iter.Key(), seen) + referenced(iter.Value(), seen)
+		}
+		return size
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return sizeOf(v.Elem(), seen)
+	default:
+		return 0
+	}
+}
+
+// seenKey identifies memory referenced by a value, by its address, and for
+// the backing arrays of slices, by their element type.
+type seenKey struct {
+	ptr  uintptr
+	elem reflect.Type
+}
+
+// visited reports whether the memory of key has been seen, marking it seen.
+func visited(key seenKey, seen map[seenKey]struct{}) bool {
+	if _, ok := seen[key]; ok {
+		return true
+	}
+	seen[key] = struct{}{}
+	return false
+}
+
+// memoryCost returns a Config.Cost estimating the memory used by each item,
+// as the size of its value according to sizer, of its key, and of the
+// cache's bookkeeping of the item.
+func memoryCost[K comparable, V any](sizer Sizer[V]) func(key K, value V) int64 {
+	// The entry, excluding its value as counted by the sizer, and its slot in
+	// the map of items, of a key and a pointer
+	entryType := reflect.TypeOf(cacheEntry[K, V]{})
+	keyType := reflect.TypeOf((*K)(nil)).Elem()
+	valueType := reflect.TypeOf((*V)(nil)).Elem()
+	overhead := int64(entryType.Size()-valueType.Size()+keyType.Size()) + 8 + mapEntryOverhead
+
+	return func(key K, value V) int64 {
+		return overhead + referenced(reflect.ValueOf(&key).Elem(), map[seenKey]struct{}{}) + sizer.Size(value)
+	}
+}
@@ -0,0 +1,86 @@
+package agecache
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizers(t *testing.T) {
+	assert.Equal(t, int64(16+3), StringSizer[string]{}.Size("foo"))
+	assert.Equal(t, int64(24+8), BytesSizer[[]byte]{}.Size(make([]byte, 2, 8)))
+	assert.Equal(t, int64(5), SizerFunc[int](func(value int) int64 { return int64(value) }).Size(5))
+
+	type node struct {
+		Name string
+		Next *node
+	}
+
+	sizer := ReflectSizer[*node]{}
+	assert.Equal(t, int64(8), sizer.Size(nil))
+
+	// The header and bytes of the string, and the pointer to the node
+	single := &node{Name: "foo"}
+	assert.Equal(t, int64(8+24+3), sizer.Size(single))
+
+	// Cycles are counted once
+	cycle := &node{Name: "foo"}
+	cycle.Next = cycle
+	assert.Equal(t, int64(8+24+3), sizer.Size(cycle))
+
+	// Including cycles through slices, whose interface holds another header
+	slice := make([]interface{}, 1)
+	slice[0] = slice
+	assert.Equal(t, int64(24+16+24), ReflectSizer[[]interface{}]{}.Size(slice))
+
+	// Shared backing arrays are counted once
+	shared := []int{1, 2}
+	assert.Equal(t, int64(24+2*24+2*8), ReflectSizer[[][]int]{}.Size([][]int{shared, shared}))
+
+	assert.Equal(t, int64(24+4*16+6), ReflectSizer[[]string]{}.Size(append(make([]string, 0, 4), "foo", "bar")))
+	assert.Equal(t, int64(16+16+3), ReflectSizer[interface{}]{}.Size("foo"))
+	assert.Equal(t, int64(8+(16+8+8)+3), ReflectSizer[map[string]int]{}.Size(map[string]int{"foo": 1}))
+}
+
+func TestMaxMemory(t *testing.T) {
+	var evicted []string
+
+	cache := New(Config[string, string]{
+		Capacity:  100,
+		MaxMemory: 1000,
+		Sizer:     StringSizer[string]{},
+		OnEviction: func(key, value string) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	cost := memoryCost[string](Sizer[string](StringSizer[string]{}))
+	size := cost("a", strings.Repeat("x", 100))
+
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	for _, key := range keys {
+		cache.Set(key, strings.Repeat("x", 100))
+	}
+
+	// Evicted to fit the estimate, oldest first
+	fits := int(1000 / size)
+	assert.Equal(t, fits, cache.Len())
+	assert.Equal(t, keys[:len(keys)-fits], evicted)
+	assert.Equal(t, int64(fits)*size, cache.Stats().Cost)
+
+	// Values larger than the limit are rejected
+	assert.Error(t, cache.TrySet("big", strings.Repeat("x", 1000)))
+
+	// Defaults to a ReflectSizer
+	cache = New(Config[string, string]{Capacity: 100, MaxMemory: 1000})
+	cache.Set("a", strings.Repeat("x", 100))
+	assert.Equal(t, size, cache.Stats().Cost)
+
+	assert.Panics(t, func() {
+		New(Config[string, string]{Capacity: 100, MaxMemory: 1000, MaxCost: 10})
+	})
+	assert.Panics(t, func() {
+		New(Config[string, string]{Capacity: 100, Sizer: StringSizer[string]{}})
+	})
+}
@@ -0,0 +1,102 @@
+package agecache
+
+import (
+	"sync"
+	"time"
+)
+
+// SLO is an objective for the hit ratio of a cache over a sliding window, as
+// watched by WatchSLO.
+type SLO struct {
+	// Minimum hit ratio, between 0 and 1
+	HitRatio float64
+	// Duration over which the hit ratio is measured, such as 5 minutes
+	Window time.Duration
+	// How often the window slides, and the objective is checked. Defaults to
+	// a tenth of the Window
+	Interval time.Duration
+	// Minimum number of hits and misses within the window for the objective to
+	// be checked, so that a nearly idle cache does not violate it. Defaults to
+	// zero, checking every window with at least one hit or miss
+	MinGets int64
+}
+
+// WatchSLO checks the hit ratio of the cache against slo every slo.Interval,
+// invoking onViolation with the Stats of the window once it falls below the
+// objective. onViolation is not invoked again until the objective has been
+// met, so that a sustained violation raises a single alert. The first check
+// is made once a full window has elapsed. Watches until the returned stop
+// function is called or the cache is closed. Panics given an invalid SLO.
+func (cache *Cache[K, V]) WatchSLO(slo SLO, onViolation func(window Stats)) (stop func()) {
+	return watchSLO(slo, cache.clock, cache.Stats, cache.done, onViolation)
+}
+
+// watchSLO checks the stats returned by stats against slo, until stopped or
+// done is closed.
+func watchSLO(slo SLO, clock Clock, stats func() Stats, done <-chan struct{}, onViolation func(window Stats)) (stop func()) {
+	if slo.HitRatio < 0 || slo.HitRatio > 1 {
+		panic("Must supply an slo.HitRatio between 0 and 1")
+	}
+	if slo.Window <= 0 {
+		panic("Must supply a positive slo.Window")
+	}
+	if slo.Interval == 0 {
+		slo.Interval = slo.Window / 10
+	}
+	if slo.Interval <= 0 || slo.Interval > slo.Window {
+		panic("Must supply a positive slo.Interval no longer than the slo.Window")
+	}
+	if slo.MinGets < 0 {
+		panic("Must supply a zero or positive slo.MinGets")
+	}
+
+	stopped := make(chan struct{})
+	var once sync.Once
+
+	// Samples of the stats, the oldest of which is a window ago once full
+	n := int((slo.Window + slo.Interval - 1) / slo.Interval)
+	samples := make([]Stats, 1, n+1)
+	samples[0] = stats()
+
+	ticker := clock.NewTicker(slo.Interval)
+	go func() {
+		defer ticker.Stop()
+
+		violated := false
+		for {
+			select {
+			case <-ticker.Chan():
+			case <-stopped:
+				return
+			case <-done:
+				return
+			}
+
+			if len(samples) == n+1 {
+				samples = append(samples[:0], samples[1:]...)
+			}
+			samples = append(samples, stats())
+			if len(samples) < n+1 {
+				continue
+			}
+
+			window := samples[n].Delta(samples[0])
+			if gets := window.Hits + window.Misses; gets == 0 || gets < slo.MinGets {
+				continue
+			}
+
+			if window.HitRatio() >= slo.HitRatio {
+				violated = false
+			} else if !violated {
+				violated = true
+				onViolation(window)
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(stopped)
+		})
+	}
+}
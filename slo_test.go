@@ -0,0 +1,119 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchSLO(t *testing.T) {
+	clock := NewManualClock(time.Now())
+
+	// Cumulative hits and misses sampled at each interval
+	script := []Stats{
+		{}, {Hits: 90, Misses: 10}, {Hits: 180, Misses: 20}, {Hits: 200, Misses: 60},
+		{Hits: 210, Misses: 100}, {Hits: 400, Misses: 110}, {Hits: 400, Misses: 200},
+		{Hits: 400, Misses: 200},
+	}
+	sampled := make(chan struct{}, len(script))
+	i := 0
+	stats := func() Stats {
+		s := script[i]
+		i++
+		sampled <- struct{}{}
+		return s
+	}
+
+	var violations []Stats
+	stop := watchSLO(SLO{HitRatio: 0.8, Window: 2 * time.Minute, Interval: time.Minute}, clock, stats, nil, func(window Stats) {
+		violations = append(violations, window)
+	})
+	defer stop()
+	<-sampled
+
+	// Once sampled, the previous window has been checked
+	tick := func() {
+		clock.Advance(time.Minute)
+		<-sampled
+	}
+
+	tick()
+	tick()
+	tick()
+	tick()
+	assert.Equal(t, []Stats{{Hits: 110, Misses: 50}}, violations)
+
+	// A sustained violation alerts once, until the objective is met again
+	tick()
+	tick()
+	tick()
+	assert.Equal(t, []Stats{{Hits: 110, Misses: 50}, {Hits: 190, Misses: 100}}, violations)
+}
+
+func TestWatchSLOMinGets(t *testing.T) {
+	clock := NewManualClock(time.Now())
+
+	script := []Stats{{}, {Misses: 5}, {Misses: 15}, {Misses: 15}}
+	sampled := make(chan struct{}, len(script))
+	i := 0
+	stats := func() Stats {
+		s := script[i]
+		i++
+		sampled <- struct{}{}
+		return s
+	}
+
+	var violations []Stats
+	stop := watchSLO(SLO{HitRatio: 0.8, Window: time.Minute, Interval: time.Minute, MinGets: 10}, clock, stats, nil, func(window Stats) {
+		violations = append(violations, window)
+	})
+	defer stop()
+	<-sampled
+
+	// Windows with too few gets are not checked
+	for range script[1:] {
+		clock.Advance(time.Minute)
+		<-sampled
+	}
+	assert.Equal(t, []Stats{{Misses: 10}}, violations)
+}
+
+func TestCacheWatchSLO(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[string, int]{Capacity: 10, Clock: clock})
+	defer cache.Close()
+
+	violated := make(chan Stats, 1)
+	stop := cache.WatchSLO(SLO{HitRatio: 0.5, Window: time.Minute, Interval: time.Minute, MinGets: 2}, func(window Stats) {
+		violated <- window
+	})
+	defer stop()
+
+	cache.Get("foo")
+	cache.Get("bar")
+
+	assert.Eventually(t, func() bool {
+		clock.Advance(time.Minute)
+		select {
+		case window := <-violated:
+			return assert.Equal(t, int64(2), window.Misses)
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWatchSLOInvalid(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	defer cache.Close()
+
+	for _, slo := range []SLO{
+		{HitRatio: 1.5, Window: time.Minute},
+		{HitRatio: 0.5},
+		{HitRatio: 0.5, Window: time.Minute, Interval: time.Hour},
+		{HitRatio: 0.5, Window: time.Minute, MinGets: -1},
+	} {
+		assert.Panics(t, func() { cache.WatchSLO(slo, func(Stats) {}) })
+	}
+}
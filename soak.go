@@ -0,0 +1,68 @@
+package agecache
+
+import "time"
+
+const defaultSoakStep = time.Minute
+
+// SoakConfig configures a Soak through simulated time.
+type SoakConfig struct {
+	// Simulated duration of the soak, such as a week
+	Duration time.Duration
+	// Simulated time elapsed between expiration cycles. Defaults to one minute
+	Step time.Duration
+	// Optional workload replayed against the cache, storing each key missing
+	// from the cache. Defaults to nil, only letting time pass
+	Workload *Workload
+	// For a Workload, the number of keys requested every Step
+	Requests int
+	// Optional callback invoked after every Step with the time of the clock
+	// and the Stats of the step, such as to chart the expirations of each
+	// step for storms
+	OnStep func(now time.Time, window Stats)
+}
+
+// Soak drives a cache constructed with a ManualClock through the Duration of
+// the SoakConfig in seconds of real time. Every Step, it replays the Requests
+// of the Workload, advances the clock, delivering the ticks and timers due,
+// and then removes the expired items, as active expiration would. Returns the
+// Stats accumulated meanwhile. Panics given an invalid configuration, or a
+// cache without a ManualClock.
+func Soak(cache *Cache[uint64, uint64], config SoakConfig) Stats {
+	clock, ok := cache.clock.(*ManualClock)
+	if !ok {
+		panic("Must supply a cache with a ManualClock to Soak")
+	}
+
+	if config.Duration <= 0 {
+		panic("Must supply a positive config.Duration")
+	}
+
+	if config.Step == 0 {
+		config.Step = defaultSoakStep
+	} else if config.Step < 0 {
+		panic("Must supply a positive config.Step")
+	}
+
+	if config.Requests < 0 || config.Workload != nil && config.Requests == 0 {
+		panic("Must supply a positive config.Requests with a config.Workload")
+	}
+
+	start := cache.Stats()
+	previous := start
+
+	for elapsed := time.Duration(0); elapsed < config.Duration; elapsed += config.Step {
+		if config.Workload != nil {
+			config.Workload.Replay(cache, config.Requests)
+		}
+		clock.Advance(config.Step)
+		cache.RemoveExpired()
+
+		if config.OnStep != nil {
+			current := cache.Stats()
+			config.OnStep(clock.Now(), current.Delta(previous))
+			previous = current
+		}
+	}
+
+	return cache.Stats().Delta(start)
+}
@@ -0,0 +1,83 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoakJitter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[uint64, uint64]{Capacity: 1000, MaxAge: time.Hour, MinAge: 50 * time.Minute, Clock: clock})
+	for key := uint64(0); key < 1000; key++ {
+		cache.Set(key, key)
+	}
+
+	// Expirations are spread across the jitter, rather than all at once
+	start := clock.Now()
+	var steps []time.Duration
+	stats := Soak(cache, SoakConfig{
+		Duration: 2 * time.Hour,
+		OnStep: func(now time.Time, window Stats) {
+			if window.Expirations > 0 {
+				steps = append(steps, now.Sub(start))
+				assert.Less(t, window.Expirations, int64(200))
+			}
+		},
+	})
+
+	assert.Equal(t, int64(1000), stats.Expirations)
+	assert.Equal(t, 0, cache.Len())
+	assert.Greater(t, len(steps), 5)
+	for _, elapsed := range steps {
+		assert.GreaterOrEqual(t, elapsed, 50*time.Minute)
+		assert.LessOrEqual(t, elapsed, time.Hour)
+	}
+}
+
+func TestSoakWorkload(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	cache := New(Config[uint64, uint64]{
+		Capacity:           100,
+		MaxAge:             time.Hour,
+		MinAge:             30 * time.Minute,
+		ExpirationType:     ActiveExpiration,
+		ExpirationInterval: time.Minute,
+		Clock:              clock,
+	})
+	defer cache.Close()
+
+	steps := 0
+	stats := Soak(cache, SoakConfig{
+		Duration: 7 * 24 * time.Hour,
+		Workload: NewWorkload(WorkloadConfig{Seed: 1, Keys: 500}),
+		Requests: 10,
+		OnStep: func(now time.Time, window Stats) {
+			steps++
+		},
+	})
+
+	assert.Equal(t, 7*24*60, steps)
+	assert.Equal(t, int64(7*24*60*10), stats.Hits+stats.Misses)
+	assert.Greater(t, stats.Expirations, int64(0))
+	assert.Greater(t, stats.Evictions, int64(0))
+	checkInvariants(t, cache)
+}
+
+func TestSoakInvalid(t *testing.T) {
+	assert.Panics(t, func() {
+		Soak(New(Config[uint64, uint64]{Capacity: 1}), SoakConfig{Duration: time.Hour})
+	})
+
+	cache := New(Config[uint64, uint64]{Capacity: 1, Clock: NewManualClock(time.Now())})
+	assert.Panics(t, func() {
+		Soak(cache, SoakConfig{})
+	})
+	assert.Panics(t, func() {
+		Soak(cache, SoakConfig{Duration: time.Hour, Step: -1})
+	})
+	assert.Panics(t, func() {
+		Soak(cache, SoakConfig{Duration: time.Hour, Workload: NewWorkload(WorkloadConfig{Keys: 1})})
+	})
+}
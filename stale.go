@@ -0,0 +1,79 @@
+package agecache
+
+// GetStale returns the value stored at `key` as with Get, but with a
+// StaleWhileRevalidate, also returns the values of items which expired within
+// it. Such values are reported as stale, and their keys are passed to
+// Revalidate in the background, so that later calls find a fresh value.
+// Serving a stale value counts as a hit.
+func (cache *Cache[K, V]) GetStale(key K) (value V, stale bool, found bool) {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	entry, ok := cache.items[key]
+	if !ok || !cache.expired(entry) || !cache.stale(entry) || cache.bypassed(key) {
+		value, found = cache.get(key)
+		return value, false, found
+	}
+
+	cache.gets++
+	if cache.requested != nil {
+		cache.requested.record(hashKey(key), cache.clock.Now())
+	}
+	if cache.filter != nil {
+		cache.filter.record(key)
+	}
+	cache.policy.access(entry)
+	cache.hits++
+	entry.hits++
+	cache.accessed(entry)
+	cache.notifyHit(entry)
+	cache.startRevalidate(key)
+
+	return entry.value, true, true
+}
+
+// stale returns whether the expired entry should be kept for GetStale.
+func (cache *Cache[K, V]) stale(entry *cacheEntry[K, V]) bool {
+	return cache.staleWindow > 0 && cache.clock.Now().Sub(entry.timestamp) <= cache.lifetime(entry)+cache.staleWindow
+}
+
+// refreshAhead revalidates the entry found by Get if it is older than the
+// RefreshAfter.
+func (cache *Cache[K, V]) refreshAhead(entry *cacheEntry[K, V]) {
+	if cache.refreshAfter > 0 && cache.clock.Now().Sub(entry.timestamp) >= cache.refreshAfter {
+		cache.startRevalidate(entry.key)
+	}
+}
+
+// startRevalidate invokes Revalidate for key in a new goroutine, unless a
+// load of the key is already in flight, with a context cancelled by Close.
+func (cache *Cache[K, V]) startRevalidate(key K) {
+	cache.loadMutex.Lock()
+	defer cache.loadMutex.Unlock()
+
+	if _, ok := cache.loads[key]; ok {
+		return
+	}
+
+	call := &load[V]{done: make(chan struct{}), err: errLoaderPanicked}
+	cache.loads[key] = call
+
+	go func() {
+		defer func() {
+			cache.loadMutex.Lock()
+			delete(cache.loads, key)
+			cache.loadMutex.Unlock()
+			close(call.done)
+		}()
+
+		ctx := cache.revalidateCtx
+		start := cache.clock.Now()
+		call.value, call.err = cache.revalidate(ctx, key)
+		cache.observeLoad(start)
+		if call.err == nil {
+			cache.Set(key, call.value)
+		} else if cache.onRevalidateError != nil && ctx.Err() == nil {
+			cache.onRevalidateError(key, call.err)
+		}
+	}()
+}
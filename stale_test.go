@@ -0,0 +1,161 @@
+package agecache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStale(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	loaded := make(chan string)
+	release := make(chan error)
+	cache := New(Config[string, int]{
+		Capacity:             4,
+		MaxAge:               time.Minute,
+		Clock:                clock,
+		StaleWhileRevalidate: time.Minute,
+		Revalidate: func(ctx context.Context, key string) (int, error) {
+			loaded <- key
+			return 2, <-release
+		},
+	})
+	cache.Set("foo", 1)
+
+	value, stale, found := cache.GetStale("foo")
+	assert.Equal(t, 1, value)
+	assert.False(t, stale)
+	assert.True(t, found)
+
+	// Once expired, the value is served stale while it is revalidated
+	clock.Advance(90 * time.Second)
+	cache.RemoveExpired()
+	_, ok := cache.Get("foo")
+	assert.False(t, ok)
+
+	value, stale, found = cache.GetStale("foo")
+	assert.Equal(t, 1, value)
+	assert.True(t, stale)
+	assert.True(t, found)
+	assert.Equal(t, "foo", <-loaded)
+
+	// Revalidations of a key are shared
+	cache.GetStale("foo")
+	release <- errors.New("unavailable")
+
+	// Failed revalidations are retried by later calls
+	assert.Eventually(t, func() bool {
+		_, stale, _ := cache.GetStale("foo")
+		select {
+		case <-loaded:
+			return stale
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	release <- nil
+
+	assert.Eventually(t, func() bool {
+		value, stale, _ := cache.GetStale("foo")
+		return value == 2 && !stale
+	}, time.Second, time.Millisecond)
+
+	// Past the window, items are deleted
+	clock.Advance(3 * time.Minute)
+	assert.Equal(t, 1, cache.RemoveExpired())
+	_, _, found = cache.GetStale("foo")
+	assert.False(t, found)
+}
+
+func TestRevalidateRequired(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, MaxAge: time.Minute, StaleWhileRevalidate: time.Minute})
+	})
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, MaxAge: time.Minute, RefreshAfter: time.Second})
+	})
+}
+
+func TestRefreshAfter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	loaded := make(chan string, 1)
+	cache := New(Config[string, int]{
+		Capacity:     4,
+		MaxAge:       time.Minute,
+		Clock:        clock,
+		RefreshAfter: 45 * time.Second,
+		Revalidate: func(ctx context.Context, key string) (int, error) {
+			loaded <- key
+			return 2, nil
+		},
+	})
+	cache.Set("foo", 1)
+
+	cache.Get("foo")
+	select {
+	case key := <-loaded:
+		t.Fatalf("refreshed %s before RefreshAfter", key)
+	default:
+	}
+
+	// Hits past RefreshAfter still return the current value
+	clock.Advance(50 * time.Second)
+	value, ok := cache.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, "foo", <-loaded)
+
+	assert.Eventually(t, func() bool {
+		value, _ := cache.Peek("foo")
+		return value == 2
+	}, time.Second, time.Millisecond)
+
+	// The reloaded item is stored afresh, living past the original MaxAge
+	clock.Advance(30 * time.Second)
+	value, ok = cache.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+}
+
+func TestRevalidateErrors(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	errs := make(chan error, 1)
+	cancelled := make(chan error, 1)
+	cache := New(Config[string, int]{
+		Capacity:             4,
+		MaxAge:               time.Minute,
+		Clock:                clock,
+		StaleWhileRevalidate: time.Minute,
+		Revalidate: func(ctx context.Context, key string) (int, error) {
+			if key == "foo" {
+				return 0, errors.New("unavailable")
+			}
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return 0, ctx.Err()
+		},
+		OnRevalidateError: func(key string, err error) {
+			assert.Equal(t, "foo", key)
+			errs <- err
+		},
+	})
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+	clock.Advance(90 * time.Second)
+
+	cache.GetStale("foo")
+	assert.EqualError(t, <-errs, "unavailable")
+
+	// Revalidations in flight are cancelled by Close, without an error
+	cache.GetStale("bar")
+	cache.Close()
+	assert.Equal(t, context.Canceled, <-cancelled)
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected error %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
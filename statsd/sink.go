@@ -0,0 +1,69 @@
+// Package statsd writes the metrics of agecache caches in the StatsD line
+// protocol.
+package statsd
+
+import (
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Sink implements agecache.MetricsSink, writing each metric reported by a
+// cache as a line of the StatsD protocol, such as "users.hits:3|c". Counters
+// are written as counts of their delta, gauges as gauges, and histograms as
+// histograms, with a type of "h". Each line is written by a separate call to
+// Write, such that a sink writing to a UDP connection sends a datagram per
+// metric. Errors writing metrics are ignored, as is usual for StatsD.
+type Sink struct {
+	writer io.Writer
+	prefix string
+
+	mutex sync.Mutex // Serializes writes, and guards buf
+	buf   []byte
+}
+
+// NewSink constructs a Sink writing to w the metrics of a cache, each named
+// after prefix and a dot, such as the name of the cache. Panics given a nil
+// writer.
+func NewSink(w io.Writer, prefix string) *Sink {
+	if w == nil {
+		panic("Must supply a writer")
+	}
+
+	if prefix != "" {
+		prefix += "."
+	}
+
+	return &Sink{writer: w, prefix: prefix}
+}
+
+// Count implements agecache.MetricsSink.
+func (sink *Sink) Count(name string, delta int64) {
+	sink.write(name, strconv.FormatInt(delta, 10), "c")
+}
+
+// Gauge implements agecache.MetricsSink.
+func (sink *Sink) Gauge(name string, value int64) {
+	sink.write(name, strconv.FormatInt(value, 10), "g")
+}
+
+// Observe implements agecache.MetricsSink.
+func (sink *Sink) Observe(name string, value float64) {
+	sink.write(name, strconv.FormatFloat(value, 'f', -1, 64), "h")
+}
+
+func (sink *Sink) write(name, value, kind string) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	buf := append(sink.buf[:0], sink.prefix...)
+	buf = append(buf, name...)
+	buf = append(buf, ':')
+	buf = append(buf, value...)
+	buf = append(buf, '|')
+	buf = append(buf, kind...)
+	buf = append(buf, '\n')
+	sink.buf = buf
+
+	sink.writer.Write(sink.buf)
+}
@@ -0,0 +1,26 @@
+package statsd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewSink(&buf, "users")
+
+	sink.Count("hits", 3)
+	sink.Gauge("count", 5)
+	sink.Observe("load_seconds", 0.25)
+	assert.Equal(t, "users.hits:3|c\nusers.count:5|g\nusers.load_seconds:0.25|h\n", buf.String())
+
+	buf.Reset()
+	NewSink(&buf, "").Count("hits", 1)
+	assert.Equal(t, "hits:1|c\n", buf.String())
+
+	assert.Panics(t, func() {
+		NewSink(nil, "users")
+	})
+}
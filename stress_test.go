@@ -0,0 +1,271 @@
+package agecache
+
+import (
+	"context"
+	"flag"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var stressDuration = flag.Duration("stress", 200*time.Millisecond, "how long TestStress hammers each cache")
+
+// checkInvariants fails the test if the bookkeeping of the cache disagrees
+// with its items.
+func checkInvariants[K comparable, V any](t testing.TB, cache *Cache[K, V]) {
+	t.Helper()
+
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	tracked := 0
+	cache.policy.walk(func(entry *cacheEntry[K, V]) bool {
+		if cache.items[entry.key] != entry {
+			t.Errorf("policy tracks an entry missing from the cache: %v", entry.key)
+		}
+		tracked++
+		return true
+	})
+	if tracked != len(cache.items) {
+		t.Errorf("policy tracks %d entries, cache has %d", tracked, len(cache.items))
+	}
+
+	if len(cache.items) > cache.capacity {
+		t.Errorf("cache has %d entries, capacity is %d", len(cache.items), cache.capacity)
+	}
+
+	if cache.expiry.len() > len(cache.items) {
+		t.Errorf("%d entries scheduled to expire, cache has %d", cache.expiry.len(), len(cache.items))
+	}
+
+	var cost int64
+	for _, entry := range cache.items {
+		cost += entry.cost
+		if entry.hasAlt && cache.alts[entry.alt] != entry.key {
+			t.Errorf("alternate key %v of %v is not indexed", entry.alt, entry.key)
+		}
+		for _, tag := range entry.tags {
+			if _, ok := cache.tagged[tag][entry.key]; !ok {
+				t.Errorf("tag %s of %v is not indexed", tag, entry.key)
+			}
+		}
+		for _, dep := range entry.deps {
+			if _, ok := cache.dependents[dep][entry.key]; !ok {
+				t.Errorf("dependency of %v on %v is not indexed", entry.key, dep)
+			}
+		}
+	}
+	for dep, dependents := range cache.dependents {
+		for key := range dependents {
+			if _, ok := cache.items[key]; !ok {
+				t.Errorf("dependent %v of %v is missing from the cache", key, dep)
+			}
+		}
+	}
+	for tag, keys := range cache.tagged {
+		for key := range keys {
+			if _, ok := cache.items[key]; !ok {
+				t.Errorf("item %v tagged %s is missing from the cache", key, tag)
+			}
+		}
+	}
+	for alt, key := range cache.alts {
+		if entry, ok := cache.items[key]; !ok || !entry.hasAlt || entry.alt != alt {
+			t.Errorf("alternate key %v of %v is not registered", alt, key)
+		}
+	}
+	if cost != cache.totalCost {
+		t.Errorf("entries cost %d, cache reports %d", cost, cache.totalCost)
+	}
+	if cache.maxCost > 0 && cache.totalCost > cache.maxCost {
+		t.Errorf("cache costs %d, max cost is %d", cache.totalCost, cache.maxCost)
+	}
+}
+
+// hammer invokes a random public method of the cache, deriving its arguments
+// from op.
+func hammer(cache *Cache[int, int], op, key int) {
+	switch op % 30 {
+	case 0:
+		cache.Set(key, key)
+	case 1:
+		cache.TrySet(key, key)
+	case 2:
+		cache.SetWithTTL(key, key, time.Duration(key%5)*time.Millisecond)
+	case 3:
+		cache.SetAll(map[int]int{key: key, key + 1: key})
+	case 4:
+		cache.Get(key)
+	case 5:
+		cache.GetOrLoad(context.Background(), key, func(ctx context.Context, key int) (int, error) {
+			return key, nil
+		})
+	case 6:
+		cache.Touch(key)
+	case 7:
+		cache.Has(key)
+	case 8:
+		cache.Peek(key)
+	case 9:
+		cache.TTL(key)
+	case 10:
+		cache.Remove(key)
+		cache.RemoveFunc(func(k, v int) bool { return k == key+1 })
+	case 11:
+		cache.EvictOldest()
+		cache.RemoveOldest()
+	case 12:
+		cache.Len()
+		cache.Keys()
+		cache.OrderedKeys()
+		cache.OrderedEntriesInfo()
+	case 13:
+		cache.Scan(Cursor(key), 4)
+	case 14:
+		cache.Export()
+	case 15:
+		cache.Resize(1 + key%32)
+	case 16:
+		cache.SetMaxAge(time.Duration(1+key%10) * time.Millisecond)
+	case 17:
+		cache.SetMinAge(time.Duration(key%10) * time.Millisecond)
+	case 18:
+		cache.ScaleMaxAge(float64(1+key%4) / 4)
+	case 19:
+		cache.RemoveExpired()
+	case 20:
+		cache.SetMaxCost(int64(key % 64))
+	case 21:
+		cache.Stats()
+		cache.Report()
+	case 22:
+		if key%8 == 0 {
+			cache.Clear()
+		}
+	case 23:
+		if key%64 == 0 {
+			cache.Close()
+		}
+	case 24:
+		cache.SetWithDeps(key, key, key/2, key+1)
+	case 25:
+		cache.SetMulti(map[int]int{key: key, key + 2: key})
+		cache.GetMulti([]int{key, key + 1})
+		cache.RemoveMulti([]int{key + 2})
+		cache.Txn(func(tx *Txn[int, int]) error {
+			value, _ := tx.Get(key)
+			tx.Set(key+1, value)
+			tx.Remove(key + 3)
+			return nil
+		})
+	case 26:
+		cache.Demote(key)
+		cache.Promote(key + 1)
+		cache.Pop(key + 2)
+	case 27:
+		cache.SetWithAlt(key, key+64, key)
+		cache.GetByAlt(key + 63)
+		cache.RemoveByAlt(key + 65)
+	case 28:
+		cache.GetStale(key)
+	case 29:
+		cache.SetWithTags(key, key, strconv.Itoa(key%4), "all")
+		cache.InvalidateTag(strconv.Itoa(key % 8))
+	}
+}
+
+func stressConfigs() map[string]Config[int, int] {
+	cost := func(key, value int) int64 { return int64(key % 4) }
+
+	return map[string]Config[int, int]{
+		"lru":     {Capacity: 16, MaxAge: 5 * time.Millisecond, ExpirationType: ActiveExpiration, ExpirationInterval: time.Millisecond},
+		"lfu":     {Capacity: 16, Policy: LFUEviction, Cost: cost, MaxCost: 24},
+		"arc":     {Capacity: 16, Policy: ARCEviction, MaxAge: 5 * time.Millisecond},
+		"slru":    {Capacity: 16, Policy: SLRUEviction, Admission: TinyLFUAdmission},
+		"wheel":   {Capacity: 16, MaxAge: 5 * time.Millisecond, ExpirationType: ActiveExpiration, ExpirationQueue: TimingWheelQueue, WheelTick: time.Millisecond},
+		"workers": {Capacity: 16, MaxAge: 5 * time.Millisecond, CallbackWorkers: 2},
+		"jitter":  {Capacity: 16, MaxAge: 5 * time.Millisecond, AdaptiveJitter: 2 * time.Millisecond, JitterThreshold: 2},
+		"stale": {Capacity: 16, MaxAge: 2 * time.Millisecond, StaleWhileRevalidate: 2 * time.Millisecond, RefreshAfter: time.Millisecond, Revalidate: func(ctx context.Context, key int) (int, error) {
+			return key, nil
+		}},
+		"window": {Capacity: 16, Policy: ARCEviction, InsertionWindow: time.Millisecond},
+		"tuned":  {Capacity: 16, MinCapacity: 4, MaxCapacity: 32, CapacityInterval: time.Millisecond},
+		"memory": {Capacity: 16, MaxMemory: 2048},
+		"custom": {Capacity: 16, CustomPolicy: func() Policy[int] {
+			return NewLRUPolicy[int]()
+		}},
+	}
+}
+
+// TestStress hammers the public API of each cache from many goroutines while
+// checking its invariants. Run for longer with, for example:
+//
+//	go test -race -run Stress -stress 1m
+func TestStress(t *testing.T) {
+	for name, config := range stressConfigs() {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			cache := New(config)
+			cache.OnRemoval(func(key, value int, reason RemovalReason) {
+				cache.Has(key)
+			})
+			cache.Subscribe(func(event Event[int, int]) {
+				cache.Peek(event.Key)
+			})
+			cache.AddListener(Listener[int, int]{
+				Keys: func(key int) bool { return key%2 == 0 },
+				OnEviction: func(key, value int) {
+					cache.Has(key)
+				},
+			})
+
+			deadline := time.Now().Add(*stressDuration)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(seed int64) {
+					defer wg.Done()
+					rng := rand.New(rand.NewSource(seed))
+					for time.Now().Before(deadline) {
+						hammer(cache, rng.Int(), rng.Intn(64))
+					}
+				}(int64(i))
+			}
+
+			for time.Now().Before(deadline) {
+				checkInvariants(t, cache)
+				<-time.After(time.Millisecond)
+			}
+			wg.Wait()
+
+			cache.Close()
+			checkInvariants(t, cache)
+		})
+	}
+}
+
+// FuzzCache checks the invariants of each cache after every operation in a
+// sequence, decoded from pairs of an operation and a key.
+func FuzzCache(f *testing.F) {
+	f.Add([]byte{0, 1, 4, 1, 10, 1, 15, 0})
+	f.Add([]byte{3, 7, 11, 0, 16, 2, 19, 0, 22, 8})
+	f.Add([]byte{20, 3, 0, 5, 0, 6, 0, 7, 12, 0})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		for name, config := range stressConfigs() {
+			config.ExpirationType = PassiveExpration
+			cache := New(config)
+			for i := 0; i+1 < len(ops); i += 2 {
+				hammer(cache, int(ops[i]), int(ops[i+1]))
+				checkInvariants(t, cache)
+				if t.Failed() {
+					t.Fatalf("%s: invariants broken by op %d", name, i/2)
+				}
+			}
+			cache.Close()
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package agecache
+
+// SetWithTags updates a key:value pair in the cache as with Set, tagging the
+// item with each of tags, such as the type of the entity it holds, so that
+// all items carrying a tag may be removed together with InvalidateTag. Tags
+// are replaced by each Set of the item.
+func (cache *Cache[K, V]) SetWithTags(key K, value V, tags ...string) bool {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	evict := cache.set(key, value, cache.getTimestamp(), 0)
+	if entry, ok := cache.items[key]; ok {
+		cache.label(entry, tags)
+	}
+
+	return evict
+}
+
+// InvalidateTag removes every item tagged with tag by SetWithTags, invoking
+// OnRemoval with the Invalidated reason for each of them. Returns the number of
+// items removed, not counting those invalidated in turn by depending on them.
+func (cache *Cache[K, V]) InvalidateTag(tag string) int {
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	keys := cache.tagged[tag]
+	delete(cache.tagged, tag)
+
+	removed := 0
+	for key := range keys {
+		if entry, ok := cache.items[key]; ok {
+			cache.deleteEntry(entry)
+			cache.notify(nil, entry, Invalidated)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// Tags returns the tags of the item at `key`, and whether it was found,
+// without updating how recently it was accessed.
+func (cache *Cache[K, V]) Tags(key K) ([]string, bool) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	entry, ok := cache.items[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]string(nil), entry.tags...), true
+}
+
+// label tags the entry with each of tags, ignoring duplicates.
+func (cache *Cache[K, V]) label(entry *cacheEntry[K, V], tags []string) {
+	for _, tag := range tags {
+		if cache.tagged == nil {
+			cache.tagged = make(map[string]map[K]struct{})
+		}
+
+		keys, ok := cache.tagged[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			cache.tagged[tag] = keys
+		}
+		if _, ok := keys[entry.key]; ok {
+			continue
+		}
+		keys[entry.key] = struct{}{}
+		entry.tags = append(entry.tags, tag)
+	}
+}
+
+// unlabel removes the tags of the entry.
+func (cache *Cache[K, V]) unlabel(entry *cacheEntry[K, V]) {
+	for _, tag := range entry.tags {
+		keys := cache.tagged[tag]
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(cache.tagged, tag)
+		}
+	}
+	entry.tags = nil
+}
@@ -0,0 +1,45 @@
+package agecache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	var reasons []RemovalReason
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		OnRemoval: func(key string, value int, reason RemovalReason) {
+			reasons = append(reasons, reason)
+		},
+	})
+
+	cache.SetWithTags("user:1", 1, "user")
+	cache.SetWithTags("user:2", 2, "user", "admin", "user")
+	cache.SetWithTags("post:1", 3, "post")
+	cache.Set("other", 4)
+
+	tags, ok := cache.Tags("user:2")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"user", "admin"}, tags)
+
+	assert.Equal(t, 2, cache.InvalidateTag("user"))
+	assert.Equal(t, []RemovalReason{Invalidated, Invalidated}, reasons)
+	assert.ElementsMatch(t, []string{"post:1", "other"}, cache.Keys())
+	assert.Zero(t, cache.InvalidateTag("admin"))
+	assert.Zero(t, cache.InvalidateTag("missing"))
+}
+
+func TestSetWithTagsReplaces(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+
+	cache.SetWithTags("foo", 1, "a")
+	cache.SetWithTags("foo", 2, "b")
+	cache.SetWithTags("bar", 3, "a")
+	cache.Remove("bar")
+
+	assert.Zero(t, cache.InvalidateTag("a"))
+	assert.Equal(t, 1, cache.InvalidateTag("b"))
+	assert.Empty(t, cache.tagged)
+}
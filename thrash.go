@@ -0,0 +1,41 @@
+package agecache
+
+// Number of consecutive wasted stores of a key after which OnThrash is
+// invoked when ThrashThreshold is zero
+const defaultThrashThreshold = 3
+
+// wasted records an entry removed by the cache before reaching an age of
+// ThrashWindow without having been hit, queueing OnThrash once its key has
+// wasted ThrashThreshold consecutive stores. The counts of consecutive wasted
+// stores are discarded once they track as many keys as the cache's capacity,
+// bounding their memory.
+func (cache *Cache[K, V]) wasted(entry *cacheEntry[K, V]) {
+	if cache.thrashWindow == 0 || entry.hits > 0 || cache.clock.Now().Sub(entry.timestamp) >= cache.thrashWindow {
+		return
+	}
+
+	cache.thrashes++
+
+	count, ok := cache.thrashing[entry.key]
+	if !ok && len(cache.thrashing) >= cache.capacity {
+		cache.thrashing = nil
+	}
+	if cache.thrashing == nil {
+		cache.thrashing = make(map[K]int)
+	}
+
+	count++
+	if count < cache.thrashThreshold {
+		cache.thrashing[entry.key] = count
+		return
+	}
+
+	delete(cache.thrashing, entry.key)
+	if cache.onThrash != nil {
+		cache.pending = append(cache.pending, notification[K, V]{
+			callback: cache.onThrash,
+			key:      entry.key,
+			value:    entry.value,
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package agecache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidThrashWindow(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[string, int]{Capacity: 1, ThrashWindow: -time.Second})
+	})
+}
+
+func TestThrashEvictions(t *testing.T) {
+	var thrashed []string
+
+	cache := New(Config[string, int]{
+		Capacity:        1,
+		ThrashWindow:    time.Minute,
+		ThrashThreshold: 2,
+		OnThrash: func(key string, value int) {
+			thrashed = append(thrashed, key)
+		},
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2) // a evicted unread
+	cache.Get("b")
+	cache.Set("a", 3) // b evicted, but was hit
+	cache.Set("b", 4) // a evicted unread again
+	assert.Equal(t, int64(2), cache.Stats().Thrashes)
+	assert.Equal(t, []string{"a"}, thrashed)
+
+	// A hit resets the count of consecutive wasted stores
+	cache.Set("c", 5) // b evicted unread
+	cache.Set("b", 6) // c evicted unread
+	cache.Get("b")
+	cache.Set("c", 7) // b evicted, but was hit
+	cache.Set("b", 8) // c evicted unread
+	assert.Equal(t, int64(5), cache.Stats().Thrashes)
+	assert.Equal(t, []string{"a", "c"}, thrashed)
+}
+
+func TestThrashExpirations(t *testing.T) {
+	cache := New(Config[string, int]{
+		Capacity:     4,
+		MaxAge:       10 * time.Millisecond,
+		ThrashWindow: time.Minute,
+	})
+
+	var thrashed []string
+	cache.OnThrash(func(key string, value int) {
+		thrashed = append(thrashed, key)
+	})
+
+	for i := 0; i < 3; i++ {
+		cache.Set("a", i)
+		cache.Set("b", i)
+		cache.Get("b")
+		<-time.After(20 * time.Millisecond)
+		cache.RemoveExpired()
+	}
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(6), stats.Expirations)
+	assert.Equal(t, int64(3), stats.Thrashes)
+	assert.Equal(t, []string{"a"}, thrashed)
+}
+
+func TestThrashWindow(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 1, ThrashWindow: 10 * time.Millisecond})
+
+	cache.Set("a", 1)
+	<-time.After(20 * time.Millisecond)
+	cache.Set("b", 2)
+	assert.Equal(t, int64(0), cache.Stats().Thrashes)
+
+	disabled := New(Config[string, int]{Capacity: 1})
+	disabled.Set("a", 1)
+	disabled.Set("b", 2)
+	assert.Equal(t, int64(0), disabled.Stats().Thrashes)
+}
@@ -0,0 +1,155 @@
+package agecache
+
+import (
+	"context"
+	"errors"
+)
+
+// Backend is the second tier (L2) of a Tiered cache, such as a client of
+// Redis or memcached shared by many processes. Its methods may be invoked
+// concurrently.
+type Backend[K comparable, V any] interface {
+	// Get returns the value stored at key, and whether it was found. A
+	// missing key is not an error
+	Get(ctx context.Context, key K) (value V, found bool, err error)
+	// Set stores the key:value pair
+	Set(ctx context.Context, key K, value V) error
+	// Remove removes the value stored at key, if any
+	Remove(ctx context.Context, key K) error
+}
+
+// Returned by the loader of Tiered.Get for keys missing from the Backend
+var errBackendMiss = errors.New("agecache: key not found in backend")
+
+// BackendWriteError is returned by Tiered.GetOrLoad along with a loaded value
+// which was stored in the cache, but which the Backend failed to store.
+type BackendWriteError struct {
+	// Error of the Backend
+	Err error
+}
+
+func (err *BackendWriteError) Error() string {
+	return "agecache: failed to store loaded value in backend: " + err.Err.Error()
+}
+
+// Unwrap returns the error of the Backend.
+func (err *BackendWriteError) Unwrap() error {
+	return err.Err
+}
+
+// Tiered layers a Cache (L1) over a Backend (L2). Misses of the cache fall
+// through to the backend, and values found there are promoted to the cache.
+// Concurrent misses of a key share a single Get of the backend, as with
+// GetOrLoad, and with a NegativeTTL, keys missing from the backend are cached
+// as such. Sets and removes are written through to both tiers.
+type Tiered[K comparable, V any] struct {
+	cache   *Cache[K, V]
+	backend Backend[K, V]
+}
+
+// NewTiered constructs a Tiered cache over backend, whose L1 Cache is
+// constructed with the given Config object, as with New. Panics given an
+// invalid configuration, or a nil backend.
+func NewTiered[K comparable, V any](config Config[K, V], backend Backend[K, V]) *Tiered[K, V] {
+	if backend == nil {
+		panic("Must supply a backend")
+	}
+
+	return &Tiered[K, V]{cache: New(config), backend: backend}
+}
+
+// Cache returns the L1 Cache.
+func (tiered *Tiered[K, V]) Cache() *Cache[K, V] {
+	return tiered.cache
+}
+
+// Get returns the value stored at key in the cache, or otherwise in the
+// backend, promoting it to the cache. The boolean value reports whether the
+// value was found in either tier. Returns any error of the backend.
+func (tiered *Tiered[K, V]) Get(ctx context.Context, key K) (value V, found bool, err error) {
+	value, err = tiered.cache.GetOrLoad(ctx, key, tiered.load)
+	if errors.Is(err, errBackendMiss) {
+		return value, false, nil
+	}
+	return value, err == nil, err
+}
+
+// GetOrLoad returns the value stored at key in the cache, or otherwise in the
+// backend, promoting it to the cache. Keys missing from both tiers are loaded
+// by loader, and stored in both. See Cache.GetOrLoad.
+//
+// A loaded value is stored in the cache even if the backend fails to store
+// it, in which case it is returned along with a *BackendWriteError. Such
+// failures are never cached by NegativeTTL.
+func (tiered *Tiered[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error)) (V, error) {
+	var writeErr error
+	value, err := tiered.cache.GetOrLoad(ctx, key, func(ctx context.Context, key K) (V, error) {
+		value, err := tiered.load(ctx, key)
+		if !errors.Is(err, errBackendMiss) {
+			return value, err
+		}
+
+		if value, err = loader(ctx, key); err != nil {
+			return value, err
+		}
+		writeErr = tiered.write(ctx, key, value)
+		return value, nil
+	})
+
+	// Cached as missing from the backend by Get
+	if errors.Is(err, errBackendMiss) {
+		if value, err = loader(ctx, key); err != nil {
+			return value, err
+		}
+		tiered.cache.Set(key, value)
+		return value, tiered.write(ctx, key, value)
+	}
+
+	if err != nil {
+		return value, err
+	}
+	return value, writeErr
+}
+
+// write stores a loaded key:value pair in the backend, returning any error as
+// a *BackendWriteError.
+func (tiered *Tiered[K, V]) write(ctx context.Context, key K, value V) error {
+	if err := tiered.backend.Set(ctx, key, value); err != nil {
+		return &BackendWriteError{Err: err}
+	}
+	return nil
+}
+
+// load returns the value stored at key in the backend, or errBackendMiss.
+func (tiered *Tiered[K, V]) load(ctx context.Context, key K) (V, error) {
+	value, found, err := tiered.backend.Get(ctx, key)
+	if err == nil && !found {
+		err = errBackendMiss
+	}
+	return value, err
+}
+
+// Set stores the key:value pair in the backend, and then in the cache. If the
+// backend returns an error, the key is removed from the cache rather than
+// stored, and the error is returned.
+func (tiered *Tiered[K, V]) Set(ctx context.Context, key K, value V) error {
+	if err := tiered.backend.Set(ctx, key, value); err != nil {
+		tiered.cache.Remove(key)
+		return err
+	}
+
+	tiered.cache.Set(key, value)
+	return nil
+}
+
+// Remove removes the value stored at key from the cache, and then from the
+// backend, returning any error of the backend.
+func (tiered *Tiered[K, V]) Remove(ctx context.Context, key K) error {
+	tiered.cache.Remove(key)
+	return tiered.backend.Remove(ctx, key)
+}
+
+// Close closes the cache, as with Cache.Close. The backend is left open.
+func (tiered *Tiered[K, V]) Close() error {
+	return tiered.cache.Close()
+}
@@ -0,0 +1,168 @@
+package agecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapBackend is a Backend storing values in a map.
+type mapBackend struct {
+	mutex  sync.Mutex
+	values map[string]int
+	gets   int
+	err    error
+	setErr error // Returned by Set alone
+}
+
+func (backend *mapBackend) Get(ctx context.Context, key string) (int, bool, error) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	backend.gets++
+	value, ok := backend.values[key]
+	return value, ok, backend.err
+}
+
+func (backend *mapBackend) Set(ctx context.Context, key string, value int) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	if backend.err != nil {
+		return backend.err
+	}
+	if backend.setErr != nil {
+		return backend.setErr
+	}
+	backend.values[key] = value
+	return nil
+}
+
+func (backend *mapBackend) Remove(ctx context.Context, key string) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	delete(backend.values, key)
+	return backend.err
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+	backend := &mapBackend{values: map[string]int{"foo": 1}}
+	tiered := NewTiered[string, int](Config[string, int]{Capacity: 10}, backend)
+
+	// Misses fall through to the backend, and are promoted
+	value, found, err := tiered.Get(ctx, "foo")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+	assert.True(t, tiered.Cache().Has("foo"))
+
+	value, found, err = tiered.Get(ctx, "foo")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 1, backend.gets)
+
+	_, found, err = tiered.Get(ctx, "bar")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Written through to both tiers
+	assert.NoError(t, tiered.Set(ctx, "bar", 2))
+	assert.Equal(t, 2, backend.values["bar"])
+	value, _ = tiered.Cache().Peek("bar")
+	assert.Equal(t, 2, value)
+
+	assert.NoError(t, tiered.Remove(ctx, "bar"))
+	assert.False(t, tiered.Cache().Has("bar"))
+	assert.NotContains(t, backend.values, "bar")
+
+	// Loaded into both tiers once missing from both
+	value, err = tiered.GetOrLoad(ctx, "baz", func(ctx context.Context, key string) (int, error) {
+		return 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, value)
+	assert.Equal(t, 3, backend.values["baz"])
+	assert.True(t, tiered.Cache().Has("baz"))
+
+	// Errors of the backend are returned, and leave the cache without the key
+	backend.err = errors.New("unavailable")
+	assert.EqualError(t, tiered.Set(ctx, "foo", 10), "unavailable")
+	assert.False(t, tiered.Cache().Has("foo"))
+	_, found, err = tiered.Get(ctx, "foo")
+	assert.EqualError(t, err, "unavailable")
+	assert.False(t, found)
+
+	assert.Panics(t, func() {
+		NewTiered[string, int](Config[string, int]{Capacity: 10}, nil)
+	})
+}
+
+func TestTieredNegativeTTL(t *testing.T) {
+	ctx := context.Background()
+	backend := &mapBackend{values: map[string]int{}}
+	tiered := NewTiered[string, int](Config[string, int]{Capacity: 10, NegativeTTL: time.Minute}, backend)
+
+	// Keys missing from the backend are remembered
+	for i := 0; i < 3; i++ {
+		_, found, err := tiered.Get(ctx, "foo")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	}
+	assert.Equal(t, 1, backend.gets)
+
+	value, err := tiered.GetOrLoad(ctx, "foo", func(ctx context.Context, key string) (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 1, backend.values["foo"])
+
+	value, found, err := tiered.Get(ctx, "foo")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+}
+
+func TestTieredBackendWriteError(t *testing.T) {
+	ctx := context.Background()
+	backend := &mapBackend{values: map[string]int{}, setErr: errors.New("read-only")}
+	tiered := NewTiered[string, int](Config[string, int]{Capacity: 10, NegativeTTL: time.Minute}, backend)
+
+	loads := 0
+	loader := func(ctx context.Context, key string) (int, error) {
+		loads++
+		return 1, nil
+	}
+
+	// The loaded value is returned and cached, along with the failure
+	value, err := tiered.GetOrLoad(ctx, "foo", loader)
+	var writeErr *BackendWriteError
+	assert.True(t, errors.As(err, &writeErr))
+	assert.EqualError(t, writeErr.Err, "read-only")
+	assert.Equal(t, 1, value)
+	assert.NotContains(t, backend.values, "foo")
+
+	// Rather than the failure
+	value, err = tiered.GetOrLoad(ctx, "foo", loader)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 1, loads)
+
+	// Also once cached as missing from the backend
+	_, found, err := tiered.Get(ctx, "bar")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	value, err = tiered.GetOrLoad(ctx, "bar", loader)
+	assert.True(t, errors.As(err, &writeErr))
+	assert.Equal(t, 1, value)
+	value, found = tiered.Cache().Get("bar")
+	assert.True(t, found)
+	assert.Equal(t, 1, value)
+}
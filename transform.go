@@ -0,0 +1,137 @@
+package agecache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+const defaultGzipMaxSize = 64 << 20
+
+// Transformer is a stage of the pipeline applied to encoded values, such as
+// compression or encryption, configured with Config.Transformers. Decode must
+// reverse Encode.
+type Transformer interface {
+	Encode(data []byte) ([]byte, error)
+	Decode(data []byte) ([]byte, error)
+}
+
+// TransformStats counts the work of a Transformer stage.
+type TransformStats struct {
+	Encodes  int64 // Number of values encoded
+	Decodes  int64 // Number of values decoded
+	BytesIn  int64 // Total size of the values passed to Encode
+	BytesOut int64 // Total size of the values returned by Encode
+	Errors   int64 // Number of failed calls to Encode or Decode
+}
+
+// GzipTransformer is a Transformer compressing values with gzip.
+type GzipTransformer struct {
+	// Compression level, as accepted by gzip.NewWriterLevel. Defaults to
+	// gzip.DefaultCompression
+	Level int
+	// Maximum size of a decompressed value, beyond which Decode errors rather
+	// than exhausting memory on a decompression bomb. Defaults to 64MiB
+	MaxSize int64
+}
+
+// Encode compresses data.
+func (transformer GzipTransformer) Encode(data []byte) ([]byte, error) {
+	level := transformer.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode decompresses data, erroring if it exceeds the MaxSize.
+func (transformer GzipTransformer) Decode(data []byte) ([]byte, error) {
+	maxSize := transformer.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultGzipMaxSize
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// Read one byte past the limit to tell a value of exactly the MaxSize
+	// from a larger one
+	out, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > maxSize {
+		return nil, fmt.Errorf("agecache: gzip value exceeds the max size of %d bytes", maxSize)
+	}
+	if err := reader.Close(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// pipeline applies a chain of Transformers, accumulating their stats.
+type pipeline struct {
+	stages []Transformer
+	stats  []TransformStats
+}
+
+func newPipeline(stages []Transformer) *pipeline {
+	return &pipeline{stages: stages, stats: make([]TransformStats, len(stages))}
+}
+
+// encode applies each stage in order.
+func (pipeline *pipeline) encode(data []byte) ([]byte, error) {
+	for i, stage := range pipeline.stages {
+		out, err := stage.Encode(data)
+		if err != nil {
+			pipeline.stats[i].Errors++
+			return nil, err
+		}
+		pipeline.stats[i].Encodes++
+		pipeline.stats[i].BytesIn += int64(len(data))
+		pipeline.stats[i].BytesOut += int64(len(out))
+		data = out
+	}
+	return data, nil
+}
+
+// decode applies each stage in reverse order.
+func (pipeline *pipeline) decode(data []byte) ([]byte, error) {
+	for i := len(pipeline.stages) - 1; i >= 0; i-- {
+		out, err := pipeline.stages[i].Decode(data)
+		if err != nil {
+			pipeline.stats[i].Errors++
+			return nil, err
+		}
+		pipeline.stats[i].Decodes++
+		data = out
+	}
+	return data, nil
+}
+
+// add accumulates the stats of other into the pipeline.
+func (pipeline *pipeline) add(other *pipeline) {
+	for i := range pipeline.stats {
+		pipeline.stats[i].Encodes += other.stats[i].Encodes
+		pipeline.stats[i].Decodes += other.stats[i].Decodes
+		pipeline.stats[i].BytesIn += other.stats[i].BytesIn
+		pipeline.stats[i].BytesOut += other.stats[i].BytesOut
+		pipeline.stats[i].Errors += other.stats[i].Errors
+	}
+}
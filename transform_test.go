@@ -0,0 +1,88 @@
+package agecache
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// xorTransformer obscures values by flipping their bits.
+type xorTransformer struct{}
+
+func (xorTransformer) Encode(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	for i := range data {
+		out[i] = data[i] ^ 0xff
+	}
+	return out, nil
+}
+
+func (transformer xorTransformer) Decode(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return transformer.Encode(data)
+}
+
+func TestTransformers(t *testing.T) {
+	config := Config[string, session]{
+		Capacity:     2,
+		Checksums:    true,
+		Transformers: []Transformer{GzipTransformer{}, xorTransformer{}},
+	}
+	cache := NewSerializable[string, session](config)
+	cache.Set("a", session{strings.Repeat("alice", 100)})
+
+	var buf bytes.Buffer
+	assert.NoError(t, cache.Snapshot(&buf))
+	assert.False(t, bytes.Contains(buf.Bytes(), []byte("alice")))
+
+	stats := cache.TransformStats()
+	assert.Equal(t, int64(1), stats[0].Encodes)
+	assert.Equal(t, int64(500), stats[0].BytesIn)
+	assert.True(t, stats[0].BytesOut < 100)
+	assert.Equal(t, stats[0].BytesOut, stats[1].BytesIn)
+
+	restored := NewSerializable[string, session](config)
+	n, err := restored.Restore(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	value, _ := restored.Get("a")
+	assert.Equal(t, strings.Repeat("alice", 100), value.user)
+	assert.Equal(t, int64(1), restored.TransformStats()[0].Decodes)
+	assert.Equal(t, int64(1), restored.TransformStats()[1].Decodes)
+}
+
+func TestTransformerErrors(t *testing.T) {
+	pipeline := newPipeline([]Transformer{xorTransformer{}})
+
+	_, err := pipeline.decode(nil)
+	assert.Error(t, err)
+	assert.Equal(t, TransformStats{Errors: 1}, pipeline.stats[0])
+
+	_, err = GzipTransformer{Level: 42}.Encode([]byte("a"))
+	assert.Error(t, err)
+
+	encoded, err := GzipTransformer{}.Encode([]byte(strings.Repeat("a", 1000)))
+	require.NoError(t, err)
+
+	// Values larger than the MaxSize are rejected
+	decoded, err := GzipTransformer{MaxSize: 1000}.Decode(encoded)
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 1000)
+	_, err = GzipTransformer{MaxSize: 999}.Decode(encoded)
+	assert.Error(t, err)
+
+	// As are truncated and corrupt values
+	_, err = GzipTransformer{}.Decode(encoded[:len(encoded)-4])
+	assert.Error(t, err)
+	corrupt := append([]byte(nil), encoded...)
+	corrupt[len(corrupt)-5] ^= 0xff
+	_, err = GzipTransformer{}.Decode(corrupt)
+	assert.Error(t, err)
+}
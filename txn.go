@@ -0,0 +1,87 @@
+package agecache
+
+import "time"
+
+// Txn stages updates of several keys of a Cache, applied together by
+// Cache.Txn. A Txn is not safe for concurrent use, and must not be used once
+// its function has returned.
+type Txn[K comparable, V any] struct {
+	cache  *Cache[K, V]
+	ops    []txnOp[K, V]
+	staged map[K]int // Index in ops of the latest op of each key
+}
+
+// txnOp is an update staged by a Txn.
+type txnOp[K comparable, V any] struct {
+	key    K
+	value  V
+	ttl    time.Duration
+	remove bool
+}
+
+// Txn invokes fn with a Txn staging updates of the cache, and once fn
+// returns, applies them in order while holding the cache's lock, such that
+// concurrent readers observe either none or all of them, such as of an object
+// and the items indexing it. If fn returns an error, or panics, the updates
+// are discarded, and the error is returned. Gets of the Txn observe its own
+// staged updates, and otherwise the items of the cache at the time of the
+// Get, which may be updated by others before the Txn is applied. Updates are
+// applied as their non-transactional equivalents, so that a staged item may
+// be evicted to make room for a later one.
+func (cache *Cache[K, V]) Txn(fn func(tx *Txn[K, V]) error) error {
+	tx := &Txn[K, V]{cache: cache, staged: make(map[K]int)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if len(tx.ops) == 0 {
+		return nil
+	}
+
+	cache.mutex.Lock()
+	defer cache.unlock()
+
+	for _, op := range tx.ops {
+		switch {
+		case op.remove:
+			cache.remove(op.key)
+		case op.ttl > 0:
+			cache.set(op.key, op.value, cache.clock.Now(), op.ttl)
+		default:
+			cache.set(op.key, op.value, cache.getTimestamp(), 0)
+		}
+	}
+
+	return nil
+}
+
+// Get returns the value staged for key by the Txn, or otherwise the value
+// stored at key, as with Cache.Get.
+func (tx *Txn[K, V]) Get(key K) (value V, found bool) {
+	if i, ok := tx.staged[key]; ok {
+		op := tx.ops[i]
+		return op.value, !op.remove
+	}
+	return tx.cache.Get(key)
+}
+
+// Set stages a Set of the key:value pair.
+func (tx *Txn[K, V]) Set(key K, value V) {
+	tx.stage(txnOp[K, V]{key: key, value: value})
+}
+
+// SetWithTTL stages a SetWithTTL of the key:value pair, expiring it once ttl
+// has elapsed.
+func (tx *Txn[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	tx.stage(txnOp[K, V]{key: key, value: value, ttl: ttl})
+}
+
+// Remove stages the removal of the item at key.
+func (tx *Txn[K, V]) Remove(key K) {
+	tx.stage(txnOp[K, V]{key: key, remove: true})
+}
+
+func (tx *Txn[K, V]) stage(op txnOp[K, V]) {
+	tx.staged[op.key] = len(tx.ops)
+	tx.ops = append(tx.ops, op)
+}
@@ -0,0 +1,94 @@
+package agecache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxn(t *testing.T) {
+	var removed []string
+
+	cache := New(Config[string, int]{
+		Capacity: 10,
+		Clock:    NewManualClock(time.Now()),
+		OnRemove: func(key string, value int) {
+			removed = append(removed, key)
+		},
+	})
+	cache.Set("foo", 1)
+	cache.Set("bar", 2)
+
+	err := cache.Txn(func(tx *Txn[string, int]) error {
+		value, ok := tx.Get("foo")
+		assert.True(t, ok)
+		tx.Set("foo", value+10)
+		tx.SetWithTTL("baz", 3, time.Minute)
+		tx.Remove("bar")
+
+		// Staged updates are visible to the Txn alone
+		value, ok = tx.Get("foo")
+		assert.True(t, ok)
+		assert.Equal(t, 11, value)
+		_, ok = tx.Get("bar")
+		assert.False(t, ok)
+		value, _ = cache.Get("foo")
+		assert.Equal(t, 1, value)
+		assert.True(t, cache.Has("bar"))
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"foo": 11, "baz": 3}, cache.GetMulti([]string{"foo", "bar", "baz"}))
+	assert.Equal(t, []string{"bar"}, removed)
+	ttl, _ := cache.TTL("baz")
+	assert.Equal(t, time.Minute, ttl)
+
+	// Discarded on error
+	err = cache.Txn(func(tx *Txn[string, int]) error {
+		tx.Remove("foo")
+		tx.Set("qux", 4)
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.True(t, cache.Has("foo"))
+	assert.False(t, cache.Has("qux"))
+
+	// Discarded on panic
+	assert.Panics(t, func() {
+		cache.Txn(func(tx *Txn[string, int]) error {
+			tx.Set("qux", 4)
+			panic("failed")
+		})
+	})
+	assert.False(t, cache.Has("qux"))
+}
+
+func TestTxnAtomic(t *testing.T) {
+	cache := New(Config[string, int]{Capacity: 10})
+	cache.Set("object", 0)
+	cache.Set("index", 0)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 1000; i++ {
+			cache.Txn(func(tx *Txn[string, int]) error {
+				tx.Set("object", i)
+				tx.Set("index", i)
+				return nil
+			})
+		}
+	}()
+
+	for done := false; !done; {
+		values := cache.GetMulti([]string{"object", "index"})
+		assert.Equal(t, values["object"], values["index"])
+		done = values["object"] == 1000
+	}
+	wg.Wait()
+}
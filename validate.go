@@ -0,0 +1,120 @@
+package agecache
+
+import "errors"
+
+// Errors reported by NewWithError. Each is wrapped in a *ConfigError naming
+// the invalid field.
+var (
+	// ErrInvalidCapacity reports a Capacity which is not positive
+	ErrInvalidCapacity = errors.New("invalid capacity")
+	// ErrInvalidAge reports a negative MaxAge or MinAge, or a MinAge greater
+	// than the MaxAge
+	ErrInvalidAge = errors.New("invalid age")
+	// ErrInvalidConfig reports any other invalid field of a Config
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+// ConfigError describes an invalid field of a Config. It wraps one of
+// ErrInvalidCapacity, ErrInvalidAge or ErrInvalidConfig, for use with
+// errors.Is.
+type ConfigError struct {
+	// Name of the invalid field, such as "MaxAge"
+	Field string
+	// Sentinel error classifying the field
+	Err error
+
+	message string
+}
+
+// Error returns a description of the invalid field.
+func (err *ConfigError) Error() string {
+	return err.message
+}
+
+// Unwrap returns the sentinel error classifying the field.
+func (err *ConfigError) Unwrap() error {
+	return err.Err
+}
+
+// validate returns a *ConfigError describing the first invalid field of the
+// config, if any.
+func validate[K comparable, V any](config Config[K, V]) error {
+	invalid := func(field string, sentinel error, message string) error {
+		return &ConfigError{Field: field, Err: sentinel, message: message}
+	}
+
+	switch {
+	case config.Capacity <= 0:
+		return invalid("Capacity", ErrInvalidCapacity, "Must supply a positive config.Capacity")
+	case config.MaxCost < 0:
+		return invalid("MaxCost", ErrInvalidConfig, "Must supply a zero or positive config.MaxCost")
+	case config.ProtectedRatio < 0 || config.ProtectedRatio > 1:
+		return invalid("ProtectedRatio", ErrInvalidConfig, "Must supply a config.ProtectedRatio between 0 and 1")
+	case config.OriginSampleRate < 0 || config.OriginSampleRate > 1:
+		return invalid("OriginSampleRate", ErrInvalidConfig, "Must supply a config.OriginSampleRate between 0 and 1")
+	case config.ThrashWindow < 0:
+		return invalid("ThrashWindow", ErrInvalidConfig, "Must supply a zero or positive config.ThrashWindow and config.ThrashThreshold")
+	case config.ThrashThreshold < 0:
+		return invalid("ThrashThreshold", ErrInvalidConfig, "Must supply a zero or positive config.ThrashWindow and config.ThrashThreshold")
+	case config.Hierarchical && !isStringKey[K]():
+		return invalid("Hierarchical", ErrInvalidConfig, "Must supply a string key type with config.Hierarchical")
+	case config.PersistInterval < 0:
+		return invalid("PersistInterval", ErrInvalidConfig, "Must supply a zero or positive config.PersistInterval")
+	case config.AdaptiveJitter < 0:
+		return invalid("AdaptiveJitter", ErrInvalidConfig, "Must supply a zero or positive config.AdaptiveJitter and config.JitterThreshold")
+	case config.JitterThreshold < 0:
+		return invalid("JitterThreshold", ErrInvalidConfig, "Must supply a zero or positive config.AdaptiveJitter and config.JitterThreshold")
+	case config.WheelTick < 0:
+		return invalid("WheelTick", ErrInvalidConfig, "Must supply a zero or positive config.WheelTick")
+	case config.CallbackWorkers < 0:
+		return invalid("CallbackWorkers", ErrInvalidConfig, "Must supply a zero or positive config.CallbackWorkers and config.CallbackQueue")
+	case config.CallbackQueue < 0:
+		return invalid("CallbackQueue", ErrInvalidConfig, "Must supply a zero or positive config.CallbackWorkers and config.CallbackQueue")
+	case config.EvictionWindow < 0:
+		return invalid("EvictionWindow", ErrInvalidConfig, "Must supply a zero or positive config.EvictionWindow")
+	case config.StaleWhileRevalidate < 0:
+		return invalid("StaleWhileRevalidate", ErrInvalidAge, "Must supply a zero or positive config.StaleWhileRevalidate")
+	case config.StaleWhileRevalidate > 0 && config.Revalidate == nil:
+		return invalid("Revalidate", ErrInvalidConfig, "Must supply a config.Revalidate with a config.StaleWhileRevalidate")
+	case config.RefreshAfter < 0:
+		return invalid("RefreshAfter", ErrInvalidAge, "Must supply a zero or positive config.RefreshAfter")
+	case config.RefreshAfter > 0 && config.Revalidate == nil:
+		return invalid("Revalidate", ErrInvalidConfig, "Must supply a config.Revalidate with a config.RefreshAfter")
+	case config.InsertionWindow < 0:
+		return invalid("InsertionWindow", ErrInvalidConfig, "Must supply a zero or positive config.InsertionWindow")
+	case config.NegativeTTL < 0:
+		return invalid("NegativeTTL", ErrInvalidAge, "Must supply a zero or positive config.NegativeTTL")
+	case config.CardinalityWindow < 0:
+		return invalid("CardinalityWindow", ErrInvalidConfig, "Must supply a zero or positive config.CardinalityWindow")
+	case config.MaxMemory < 0:
+		return invalid("MaxMemory", ErrInvalidConfig, "Must supply a zero or positive config.MaxMemory")
+	case config.MaxMemory > 0 && (config.MaxCost > 0 || config.Cost != nil):
+		return invalid("MaxMemory", ErrInvalidConfig, "Must not supply a config.MaxCost or config.Cost with a config.MaxMemory")
+	case config.Sizer != nil && config.MaxMemory == 0:
+		return invalid("Sizer", ErrInvalidConfig, "Must supply a config.MaxMemory with a config.Sizer")
+	case config.MinCapacity < 0:
+		return invalid("MinCapacity", ErrInvalidCapacity, "Must supply a zero or positive config.MinCapacity")
+	case config.MaxCapacity < 0:
+		return invalid("MaxCapacity", ErrInvalidCapacity, "Must supply a zero or positive config.MaxCapacity")
+	case config.MinCapacity > 0 && config.MaxCapacity == 0:
+		return invalid("MaxCapacity", ErrInvalidCapacity, "Must supply a config.MaxCapacity with a config.MinCapacity")
+	case config.MaxCapacity > 0 && (config.Capacity < config.MinCapacity || config.Capacity > config.MaxCapacity):
+		return invalid("Capacity", ErrInvalidCapacity, "Must supply a config.Capacity between config.MinCapacity and config.MaxCapacity")
+	case config.CapacityInterval < 0:
+		return invalid("CapacityInterval", ErrInvalidConfig, "Must supply a zero or positive config.CapacityInterval")
+	case config.MetricsInterval < 0:
+		return invalid("MetricsInterval", ErrInvalidConfig, "Must supply a zero or positive config.MetricsInterval")
+	case config.MaxAge < 0:
+		return invalid("MaxAge", ErrInvalidAge, "Must supply a zero or positive config.MaxAge")
+	case config.jitter > 0 && config.jitter >= config.MaxAge:
+		return invalid("MinAge", ErrInvalidAge, "Must supply a jitter less than config.MaxAge")
+	case config.MinAge < 0:
+		return invalid("MinAge", ErrInvalidAge, "Must supply a zero or positive config.MinAge")
+	case config.MinAge > 0 && config.MinAge > config.MaxAge:
+		return invalid("MinAge", ErrInvalidAge, "config.MinAge must be less than or equal to config.MaxAge")
+	case config.AdaptiveJitter > 0 && config.MaxAge > 0 && (config.AdaptiveJitter >= config.MinAge && config.MinAge > 0 || config.AdaptiveJitter >= config.MaxAge):
+		return invalid("AdaptiveJitter", ErrInvalidConfig, "config.AdaptiveJitter must be less than config.MinAge, or config.MaxAge without one, as items would otherwise be stored already expired")
+	}
+
+	return nil
+}
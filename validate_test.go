@@ -0,0 +1,49 @@
+package agecache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithError(t *testing.T) {
+	cache, err := NewWithError(Config[string, int]{Capacity: 10, MaxAge: time.Minute})
+	assert.NoError(t, err)
+	cache.Set("foo", 1)
+	assert.True(t, cache.Has("foo"))
+
+	tests := map[string]struct {
+		config   Config[string, int]
+		field    string
+		sentinel error
+	}{
+		"capacity": {Config[string, int]{}, "Capacity", ErrInvalidCapacity},
+		"max age":  {Config[string, int]{Capacity: 1, MaxAge: -1}, "MaxAge", ErrInvalidAge},
+		"min age":  {Config[string, int]{Capacity: 1, MaxAge: time.Second, MinAge: time.Minute}, "MinAge", ErrInvalidAge},
+		"jitter":   {Config[string, int]{Capacity: 1, MaxAge: time.Minute, AdaptiveJitter: time.Minute}, "AdaptiveJitter", ErrInvalidConfig},
+		"bounds":   {Config[string, int]{Capacity: 20, MaxCapacity: 10}, "Capacity", ErrInvalidCapacity},
+		"memory":   {Config[string, int]{Capacity: 1, MaxMemory: 10, MaxCost: 10}, "MaxMemory", ErrInvalidConfig},
+		"queue":    {Config[string, int]{Capacity: 1, CallbackQueue: -1}, "CallbackQueue", ErrInvalidConfig},
+		"ratio":    {Config[string, int]{Capacity: 1, ProtectedRatio: 2}, "ProtectedRatio", ErrInvalidConfig},
+	}
+
+	for name, test := range tests {
+		cache, err := NewWithError(test.config)
+		assert.Nil(t, cache, name)
+		assert.True(t, errors.Is(err, test.sentinel), name)
+
+		var configErr *ConfigError
+		if assert.True(t, errors.As(err, &configErr), name) {
+			assert.Equal(t, test.field, configErr.Field, name)
+		}
+
+		assert.PanicsWithValue(t, err.Error(), func() {
+			New(test.config)
+		}, name)
+	}
+
+	_, err = NewWithError(Config[int, int]{Capacity: 1, Hierarchical: true})
+	assert.EqualError(t, err, "Must supply a string key type with config.Hierarchical")
+}
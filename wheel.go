@@ -0,0 +1,177 @@
+package agecache
+
+import (
+	"container/list"
+	"time"
+)
+
+const (
+	// Number of bits of a tick indexing the slots of each level of the wheel
+	wheelBits = 6
+	// Number of slots per level
+	wheelSlots = 1 << wheelBits
+	// Number of levels. Deadlines beyond the span of all levels, roughly 16.7
+	// million ticks ahead, are held in an overflow list
+	wheelLevels = 4
+)
+
+// timingWheel implements TimingWheelQueue. Time is divided into ticks, and an
+// entry is placed by the first tick after its deadline. Each level of the
+// wheel divides the span of one slot of the level above it into wheelSlots
+// slots. An entry is placed at the lowest level whose current rotation
+// includes its tick. As the wheel advances, the slots of higher levels are
+// cascaded into lower levels, and the entries of the lowest level become due
+// once the wheel passes their slot.
+type timingWheel[K comparable, V any] struct {
+	tick     time.Duration
+	current  int64 // Tick up to which the wheel has advanced
+	levels   [wheelLevels][wheelSlots]*list.List
+	overflow *list.List
+	ready    *list.List // Entries whose deadline has passed
+	count    int
+}
+
+func newTimingWheel[K comparable, V any](tick time.Duration, now time.Time) *timingWheel[K, V] {
+	wheel := &timingWheel[K, V]{
+		tick:     tick,
+		overflow: list.New(),
+		ready:    list.New(),
+	}
+	wheel.current = wheel.ticks(now)
+	for level := range wheel.levels {
+		for slot := range wheel.levels[level] {
+			wheel.levels[level][slot] = list.New()
+		}
+	}
+	return wheel
+}
+
+func (wheel *timingWheel[K, V]) schedule(entry *cacheEntry[K, V]) {
+	if entry.slot != nil {
+		entry.slot.Remove(entry.timer)
+	} else {
+		wheel.count++
+	}
+	wheel.place(entry)
+}
+
+func (wheel *timingWheel[K, V]) unschedule(entry *cacheEntry[K, V]) {
+	if entry.slot != nil {
+		entry.slot.Remove(entry.timer)
+		entry.slot = nil
+		entry.timer = nil
+		wheel.count--
+	}
+}
+
+func (wheel *timingWheel[K, V]) next(now time.Time) *cacheEntry[K, V] {
+	wheel.advance(wheel.ticks(now))
+	if element := wheel.ready.Front(); element != nil {
+		return element.Value.(*cacheEntry[K, V])
+	}
+	return nil
+}
+
+func (wheel *timingWheel[K, V]) clear() {
+	wheel.each(func(entries *list.List) {
+		for element := entries.Front(); element != nil; element = element.Next() {
+			entry := element.Value.(*cacheEntry[K, V])
+			entry.slot = nil
+			entry.timer = nil
+		}
+		entries.Init()
+	})
+	wheel.count = 0
+}
+
+func (wheel *timingWheel[K, V]) len() int {
+	return wheel.count
+}
+
+// ticks returns the number of whole ticks elapsed at t.
+func (wheel *timingWheel[K, V]) ticks(t time.Time) int64 {
+	return t.UnixNano() / int64(wheel.tick)
+}
+
+// place adds the entry to the slot of the first tick after its deadline.
+func (wheel *timingWheel[K, V]) place(entry *cacheEntry[K, V]) {
+	deadline := wheel.ticks(entry.deadline) + 1
+
+	entries := wheel.overflow
+	if deadline <= wheel.current {
+		entries = wheel.ready
+	} else {
+		for level := 0; level < wheelLevels; level++ {
+			shift := uint(wheelBits * (level + 1))
+			if deadline>>shift == wheel.current>>shift {
+				entries = wheel.levels[level][(deadline>>(shift-wheelBits))&(wheelSlots-1)]
+				break
+			}
+		}
+	}
+
+	entry.slot = entries
+	entry.timer = entries.PushBack(entry)
+}
+
+// advance moves the wheel forward to the given tick. When the wheel is empty
+// but for ready entries, it jumps there directly.
+func (wheel *timingWheel[K, V]) advance(to int64) {
+	if wheel.count == wheel.ready.Len() && to > wheel.current {
+		wheel.current = to
+		return
+	}
+
+	for wheel.current < to {
+		wheel.current++
+
+		// Cascade from the highest level starting a new slot, so that entries
+		// may cascade through several levels at once
+		top := 0
+		for top < wheelLevels && wheel.current&(1<<uint(wheelBits*(top+1))-1) == 0 {
+			top++
+		}
+		if top == wheelLevels {
+			wheel.cascade(&wheel.overflow)
+		}
+		if top > wheelLevels-1 {
+			top = wheelLevels - 1
+		}
+		for level := top; level >= 1; level-- {
+			slot := (wheel.current >> uint(wheelBits*level)) & (wheelSlots - 1)
+			wheel.cascade(&wheel.levels[level][slot])
+		}
+
+		wheel.cascade(&wheel.levels[0][wheel.current&(wheelSlots-1)])
+
+		if wheel.count == wheel.ready.Len() {
+			wheel.current = to
+		}
+	}
+}
+
+// cascade places the entries of the slot again, relative to the current tick.
+// The slot is replaced by an empty list first, as entries still beyond the
+// span of the wheel are placed back into the overflow list.
+func (wheel *timingWheel[K, V]) cascade(slot **list.List) {
+	entries := *slot
+	if entries.Len() == 0 {
+		return
+	}
+	*slot = list.New()
+
+	for element := entries.Front(); element != nil; element = element.Next() {
+		wheel.place(element.Value.(*cacheEntry[K, V]))
+	}
+}
+
+// each invokes fn with every list of the wheel.
+func (wheel *timingWheel[K, V]) each(fn func(entries *list.List)) {
+	for level := range wheel.levels {
+		for slot := range wheel.levels[level] {
+			fn(wheel.levels[level][slot])
+		}
+	}
+	fn(wheel.overflow)
+	fn(wheel.ready)
+}
@@ -0,0 +1,162 @@
+package agecache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drain returns the keys of all entries due at now, unscheduling them.
+func drain(wheel *timingWheel[int, int], now time.Time) []int {
+	var keys []int
+	for entry := wheel.next(now); entry != nil; entry = wheel.next(now) {
+		wheel.unschedule(entry)
+		keys = append(keys, entry.key)
+	}
+	return keys
+}
+
+func TestTimingWheel(t *testing.T) {
+	start := time.Unix(0, 0)
+	wheel := newTimingWheel[int, int](time.Millisecond, start)
+
+	// Deadlines within the first, second and third levels, and beyond the
+	// span of all levels
+	offsets := []time.Duration{
+		5 * time.Millisecond,
+		100 * time.Millisecond,
+		10 * time.Second,
+		5 * time.Hour,
+	}
+	for i, offset := range offsets {
+		wheel.schedule(&cacheEntry[int, int]{key: i, deadline: start.Add(offset)})
+	}
+	assert.Equal(t, 4, wheel.len())
+
+	for i, offset := range offsets {
+		assert.Empty(t, drain(wheel, start.Add(offset)))
+		assert.Equal(t, []int{i}, drain(wheel, start.Add(offset+time.Millisecond)))
+	}
+	assert.Equal(t, 0, wheel.len())
+}
+
+func TestTimingWheelReschedule(t *testing.T) {
+	start := time.Unix(0, 0)
+	wheel := newTimingWheel[int, int](time.Millisecond, start)
+
+	entry := &cacheEntry[int, int]{key: 1, deadline: start.Add(10 * time.Millisecond)}
+	wheel.schedule(entry)
+	entry.deadline = start.Add(time.Second)
+	wheel.schedule(entry)
+	assert.Equal(t, 1, wheel.len())
+	assert.Empty(t, drain(wheel, start.Add(500*time.Millisecond)))
+
+	// Entries already due are ready at once
+	entry.deadline = start
+	wheel.schedule(entry)
+	assert.Equal(t, []int{1}, drain(wheel, start.Add(500*time.Millisecond)))
+
+	wheel.schedule(entry)
+	wheel.clear()
+	assert.Equal(t, 0, wheel.len())
+	assert.Nil(t, entry.slot)
+	assert.Nil(t, wheel.next(start.Add(time.Hour)))
+}
+
+func TestTimingWheelRandom(t *testing.T) {
+	start := time.Unix(0, 0)
+	tick := time.Millisecond
+	wheel := newTimingWheel[int, int](tick, start)
+	random := rand.New(rand.NewSource(1))
+
+	deadlines := make(map[int]time.Time)
+	for i := 0; i < 1000; i++ {
+		deadline := start.Add(time.Duration(random.Int63n(int64(time.Minute))))
+		deadlines[i] = deadline
+		wheel.schedule(&cacheEntry[int, int]{key: i, deadline: deadline})
+	}
+
+	now := start
+	for len(deadlines) > 0 {
+		now = now.Add(time.Duration(random.Int63n(int64(100 * time.Millisecond))))
+		for _, key := range drain(wheel, now) {
+			// Entries are due once their deadline passed, at most a tick late
+			// when observed on time
+			assert.True(t, now.After(deadlines[key]))
+			delete(deadlines, key)
+		}
+		for key, deadline := range deadlines {
+			assert.False(t, now.After(deadline.Add(tick)), "key %d is overdue", key)
+		}
+	}
+}
+
+func TestTimingWheelQueue(t *testing.T) {
+	var expired []string
+
+	cache := New(Config[string, int]{
+		Capacity:        10,
+		MaxAge:          time.Hour,
+		ExpirationQueue: TimingWheelQueue,
+		WheelTick:       time.Millisecond,
+		OnExpiration: func(key string, value int) {
+			expired = append(expired, key)
+		},
+	})
+
+	cache.SetWithTTL("a", 1, 10*time.Millisecond)
+	cache.SetWithTTL("b", 2, 50*time.Millisecond)
+	cache.Set("c", 3)
+
+	<-time.After(20 * time.Millisecond)
+	assert.Equal(t, 1, cache.RemoveExpired())
+	assert.Equal(t, []string{"a"}, expired)
+
+	cache.Remove("b")
+	cache.ScaleMaxAge(0.000001)
+	<-time.After(10 * time.Millisecond)
+	assert.Equal(t, 1, cache.RemoveExpired())
+	assert.Equal(t, 0, cache.expiry.len())
+}
+
+func TestTimingWheelOverflow(t *testing.T) {
+	// Just before the overflow list cascades, upon the span of all levels
+	span := int64(1) << (wheelBits * wheelLevels)
+	start := time.Unix(0, (span-10)*int64(time.Millisecond))
+	wheel := newTimingWheel[int, int](time.Millisecond, start)
+
+	for i := 0; i < 3; i++ {
+		wheel.schedule(&cacheEntry[int, int]{key: i, deadline: start.Add(30 * 24 * time.Hour)})
+	}
+	wheel.schedule(&cacheEntry[int, int]{key: 3, deadline: start.Add(time.Duration(span/2) * time.Millisecond)})
+
+	// Entries still beyond the span are placed back into the overflow list
+	assert.Empty(t, drain(wheel, start.Add(20*time.Millisecond)))
+	assert.Equal(t, 4, wheel.len())
+	assert.Equal(t, 3, wheel.overflow.Len())
+
+	assert.Equal(t, []int{3}, drain(wheel, start.Add(time.Duration(span/2+1)*time.Millisecond)))
+	assert.Equal(t, 3, wheel.len())
+}
+
+func TestTimingWheelQueueLongTTL(t *testing.T) {
+	span := int64(1) << (wheelBits * wheelLevels)
+	clock := NewManualClock(time.Unix(0, (span-10)*int64(time.Millisecond)))
+
+	cache := New(Config[string, int]{
+		Capacity:        10,
+		MaxAge:          30 * 24 * time.Hour,
+		ExpirationQueue: TimingWheelQueue,
+		WheelTick:       time.Millisecond,
+		Clock:           clock,
+	})
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	clock.Advance(20 * time.Millisecond)
+	assert.Equal(t, 0, cache.RemoveExpired())
+	assert.Equal(t, 2, cache.Len())
+}
@@ -0,0 +1,114 @@
+package agecache
+
+import "math/rand"
+
+// WorkloadConfig configures a Workload of synthetic cache traffic.
+type WorkloadConfig struct {
+	// Seed of the random source, such that workloads constructed with equal
+	// configs generate the same keys
+	Seed int64
+	// Number of distinct keys requested outside of scans. Keys range from 0 to
+	// Keys-1
+	Keys int
+	// Skew of the Zipf distribution of key popularity, which must be greater
+	// than 1. Larger values concentrate requests on fewer keys. Defaults to
+	// 1.1
+	Skew float64
+	// Optional number of requests after which the hot keys move to a random
+	// other region of the keyspace. Defaults to zero, keeping the same hot
+	// keys throughout
+	ShiftInterval int
+	// Optional fraction of requests, from 0 to 1, which begin a scan
+	ScanRatio float64
+	// For a positive ScanRatio, the number of sequential keys requested by
+	// each scan. Scans start at a random key, and may run past Keys-1 into
+	// keys which are never otherwise requested
+	ScanLength int
+}
+
+// Workload generates a deterministic sequence of cache keys mixing a Zipf
+// distributed working set, shifts of the hot keys, and sequential scans. It
+// can be replayed against caches constructed with different configs to
+// compare their hit ratios. A Workload is not safe for concurrent use.
+type Workload struct {
+	config   WorkloadConfig
+	rand     *rand.Rand
+	zipf     *rand.Zipf
+	offset   uint64
+	requests int
+	scan     uint64
+	scanning int // Keys remaining in the current scan
+}
+
+// NewWorkload constructs a Workload with the given WorkloadConfig. Panics
+// given an invalid configuration.
+func NewWorkload(config WorkloadConfig) *Workload {
+	if config.Keys <= 0 {
+		panic("Must supply a positive config.Keys")
+	}
+
+	if config.Skew == 0 {
+		config.Skew = 1.1
+	} else if config.Skew <= 1 {
+		panic("Must supply a config.Skew greater than 1")
+	}
+
+	if config.ShiftInterval < 0 {
+		panic("Must supply a zero or positive config.ShiftInterval")
+	}
+
+	if config.ScanRatio < 0 || config.ScanRatio > 1 {
+		panic("Must supply a config.ScanRatio between 0 and 1")
+	}
+
+	if config.ScanRatio > 0 && config.ScanLength <= 0 {
+		panic("Must supply a positive config.ScanLength with a config.ScanRatio")
+	}
+
+	r := rand.New(rand.NewSource(config.Seed))
+
+	return &Workload{
+		config: config,
+		rand:   r,
+		zipf:   rand.NewZipf(r, config.Skew, 1, uint64(config.Keys-1)),
+	}
+}
+
+// Next returns the next key requested by the workload.
+func (workload *Workload) Next() uint64 {
+	workload.requests++
+
+	if interval := workload.config.ShiftInterval; interval > 0 && workload.requests%interval == 0 {
+		workload.offset = uint64(workload.rand.Intn(workload.config.Keys))
+	}
+
+	if workload.scanning > 0 {
+		workload.scanning--
+		workload.scan++
+		return workload.scan
+	}
+
+	if workload.config.ScanRatio > 0 && workload.rand.Float64() < workload.config.ScanRatio {
+		workload.scanning = workload.config.ScanLength - 1
+		workload.scan = uint64(workload.rand.Intn(workload.config.Keys))
+		return workload.scan
+	}
+
+	return (workload.zipf.Uint64() + workload.offset) % uint64(workload.config.Keys)
+}
+
+// Replay requests n keys of the workload from the cache, storing each key
+// missing from the cache, and returns the Stats accumulated meanwhile. The
+// hit ratio of the returned Stats can be compared across cache configs.
+func (workload *Workload) Replay(cache *Cache[uint64, uint64], n int) Stats {
+	previous := cache.Stats()
+
+	for i := 0; i < n; i++ {
+		key := workload.Next()
+		if _, ok := cache.Get(key); !ok {
+			cache.Set(key, key)
+		}
+	}
+
+	return cache.Stats().Delta(previous)
+}
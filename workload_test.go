@@ -0,0 +1,112 @@
+package agecache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidWorkload(t *testing.T) {
+	assert.Panics(t, func() {
+		NewWorkload(WorkloadConfig{})
+	})
+	assert.Panics(t, func() {
+		NewWorkload(WorkloadConfig{Keys: 10, Skew: 1})
+	})
+	assert.Panics(t, func() {
+		NewWorkload(WorkloadConfig{Keys: 10, ScanRatio: 0.1})
+	})
+}
+
+func TestWorkloadSeed(t *testing.T) {
+	config := WorkloadConfig{Seed: 42, Keys: 1000, ShiftInterval: 10, ScanRatio: 0.1, ScanLength: 5}
+	a := NewWorkload(config)
+	b := NewWorkload(config)
+
+	config.Seed++
+	c := NewWorkload(config)
+
+	same, different := true, false
+	for i := 0; i < 1000; i++ {
+		key := a.Next()
+		same = same && key == b.Next()
+		different = different || key != c.Next()
+	}
+	assert.True(t, same)
+	assert.True(t, different)
+}
+
+func TestWorkloadSkew(t *testing.T) {
+	workload := NewWorkload(WorkloadConfig{Keys: 1000, Skew: 2})
+
+	counts := map[uint64]int{}
+	for i := 0; i < 10000; i++ {
+		counts[workload.Next()]++
+	}
+	assert.True(t, counts[0] > counts[1])
+	assert.True(t, counts[1] > counts[10])
+	assert.True(t, counts[0] > 5000)
+}
+
+func TestWorkloadShift(t *testing.T) {
+	workload := NewWorkload(WorkloadConfig{Keys: 1000, Skew: 3, ShiftInterval: 1000})
+
+	hottest := func() uint64 {
+		counts := map[uint64]int{}
+		for i := 0; i < 999; i++ {
+			counts[workload.Next()]++
+		}
+		workload.Next()
+
+		var hot uint64
+		for key, count := range counts {
+			if count > counts[hot] {
+				hot = key
+			}
+		}
+		return hot
+	}
+
+	assert.Equal(t, uint64(0), hottest())
+	assert.NotEqual(t, uint64(0), hottest())
+}
+
+func TestWorkloadScan(t *testing.T) {
+	workload := NewWorkload(WorkloadConfig{Keys: 100, ScanRatio: 1, ScanLength: 4})
+
+	start := workload.Next()
+	for i := uint64(1); i < 4; i++ {
+		assert.Equal(t, start+i, workload.Next())
+	}
+}
+
+func TestWorkloadReplay(t *testing.T) {
+	workload := NewWorkload(WorkloadConfig{Keys: 1000, Skew: 1.5})
+	cache := New(Config[uint64, uint64]{Capacity: 100})
+	cache.Set(1000, 1000)
+
+	stats := workload.Replay(cache, 10000)
+	assert.Equal(t, int64(10000), stats.Gets)
+	assert.Equal(t, stats.Misses, stats.Sets)
+	assert.True(t, stats.HitRatio() > 0.5)
+}
+
+func BenchmarkWorkload(b *testing.B) {
+	policies := map[string]EvictionPolicy{
+		"lru":  LRUEviction,
+		"lfu":  LFUEviction,
+		"arc":  ARCEviction,
+		"slru": SLRUEviction,
+	}
+
+	for name, policy := range policies {
+		b.Run(name, func(b *testing.B) {
+			workload := NewWorkload(WorkloadConfig{Keys: 100000, ShiftInterval: 100000, ScanRatio: 0.001, ScanLength: 1000})
+			cache := New(Config[uint64, uint64]{Capacity: 1000, Policy: policy})
+
+			b.ResetTimer()
+			stats := workload.Replay(cache, b.N)
+			b.ReportMetric(stats.HitRatio(), "hits/op")
+		})
+	}
+}